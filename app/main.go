@@ -1,47 +1,278 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
-	"github.com/go-playground/validator/v10"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/gorilla/mux"
-	_ "github.com/joho/godotenv/autoload"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 
 	"waizly/config"
 	"waizly/config/bcrypt"
+	"waizly/config/jwt"
+	"waizly/db/migration"
+	"waizly/helpers/cache"
+	"waizly/helpers/database"
+	"waizly/helpers/dialect"
+	"waizly/helpers/i18n"
+	"waizly/helpers/logger"
+	"waizly/helpers/metrics"
+	"waizly/helpers/middleware"
+	"waizly/helpers/replica"
+	"waizly/helpers/tracing"
+	"waizly/helpers/transaction"
+	"waizly/helpers/validation"
 	"waizly/internal/account"
+	"waizly/internal/audit"
 	"waizly/internal/constant"
+	"waizly/internal/health"
+	"waizly/internal/lifecycle"
+	"waizly/internal/migrator"
+	"waizly/internal/outbox"
+	"waizly/internal/routes"
+	"waizly/internal/webhook"
 )
 
 func main() {
-	cfg := config.New()
+	printConfig := flag.Bool("print-config", false, "print the effective configuration and exit")
 
-	db, err := sql.Open("mysql", cfg.Database.DSN)
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *printConfig {
+		err = cfg.Print(os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	level, _ := logger.ParseLevel(cfg.Log.Level)
+	appLogger := logger.New(os.Stderr, cfg.Log.Format, level)
+	logger.SetDefault(appLogger)
+	log.SetFlags(0)
+	log.SetOutput(appLogger.Writer(logger.LevelError))
+
+	jwt.Configure(cfg.Jwt.Secret, cfg.Jwt.PreviousSecrets)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.OTLPEndpoint,
+		Insecure:    cfg.Tracing.OTLPInsecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dbDialect := dialect.Dialect(cfg.Database.Driver)
+
+	db, err := database.Open(cfg.Database.Driver, cfg.Database.DSN, databaseOptions(cfg))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = database.Wait(context.Background(), db, cfg.Database.ConnectTimeout)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migrations, err := migrator.Load(migration.For(dbDialect))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	schema := migrator.New(db, dbDialect, migrations, constant.TableSchemaMigrations)
+
+	if cfg.Database.AutoMigrate {
+		err = schema.Up(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	checks := health.New(cfg.Health.CheckTimeout, cfg.Health.CacheTTL)
+	checks.Register(cfg.Database.Driver, health.Ping(db))
+	checks.Register("migration", health.Migration(schema, schema.Latest()))
+
+	appMetrics := metrics.New()
+	appMetrics.RegisterDB("waizly", db)
+
+	var replicaDBs []*sql.DB
+	for i, dsn := range cfg.Database.ReplicaDSNs {
+		// A replica that is down is kept out of rotation by its checks
+		// rather than holding up the start.
+		replicaDB, err := database.Open(cfg.Database.Driver, dsn, databaseOptions(cfg))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		appMetrics.RegisterDB(fmt.Sprintf("waizly_replica_%d", i), replicaDB)
+		replicaDBs = append(replicaDBs, replicaDB)
+	}
+
+	replicas := replica.NewPool(replicaDBs, dbDialect.ReplicaLag, replica.Options{
+		MaxLag:        cfg.Database.ReplicaMaxLag,
+		CheckInterval: cfg.Database.ReplicaCheckInterval,
+		Observe:       appMetrics.ObserveReplica,
+	})
+
+	validator := validation.New()
+	translations, err := i18n.New(validator, cfg.I18n.FallbackLocale, cfg.I18n.CatalogDir)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	validator := validator.New()
 	router := mux.NewRouter()
-	bcrypt := bcrypt.NewBcrypt(cfg.Bcrypt.HashCost)
-	accountRepo := account.NewAccountRepository(db, constant.TableAccount)
-	accountUseCase := account.NewAccountUseCase(accountRepo, bcrypt)
+	router.Use(middleware.RequestMetadata)
+	router.Use(replica.Middleware)
+	router.Use(translations.Middleware)
+	router.Use(middleware.Tracing)
+	router.Use(middleware.AccessLog)
+	router.Use(middleware.Metrics(appMetrics))
+	middleware.MetricsUnmatched(router, appMetrics)
+	adminAuth := middleware.BasicAuth(cfg.BasicAuth.Username, cfg.BasicAuth.Password)
+
+	bcrypt := bcrypt.NewInstrumentedBcrypt(bcrypt.NewBcrypt(cfg.Bcrypt.HashCost), appMetrics.HashDuration)
+	transactor := transaction.NewTransactor(db, transaction.WithRetry(cfg.Database.TxMaxAttempts, dbDialect.Retryable))
+	auditRepo := audit.NewAuditRepository(db, dbDialect, constant.TableAuditLog, constant.TableAuditLogHead)
+	auditUseCase := audit.NewAuditUseCase(auditRepo)
+	outboxRepo := outbox.NewOutboxRepository(db, dbDialect, constant.TableOutbox)
+	accountCache, closeCache, err := cache.Open(cacheOptions(cfg))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	queryLimits := []account.RepositoryOption{
+		account.WithQueryTimeout(cfg.Database.QueryTimeout),
+		account.WithSlowQueryLog(cfg.Database.SlowQueryThreshold),
+	}
+
+	accountRepo := account.NewInstrumentedRepository(account.NewRepository(db, dbDialect, constant.TableAccount, queryLimits...), appMetrics)
+	if replicas.Len() > 0 {
+		var replicaRepos []account.AccountRepository
+		for _, replicaDB := range replicaDBs {
+			replicaRepos = append(replicaRepos, account.NewInstrumentedRepository(account.NewRepository(replicaDB, dbDialect, constant.TableAccount, queryLimits...), appMetrics))
+		}
 
-	account.NewAccountHandler(router, validator, accountUseCase)
+		accountRepo = account.NewReplicatedRepository(accountRepo, replicaRepos, replicas, cfg.Database.ReplicaMaxLag)
+	}
+
+	if accountCache != nil {
+		accountRepo = account.NewCachedRepository(accountRepo, accountCache, cfg.Cache.TTL, appMetrics)
+	}
+	accountUseCase := account.NewInstrumentedUseCase(account.NewAccountUseCase(accountRepo, bcrypt, auditUseCase, outboxRepo, transactor), appMetrics)
+	accountUseCase = account.NewTracedUseCase(accountUseCase)
+
+	webhookRepo := webhook.NewWebhookRepository(db, dbDialect, constant.TableWebhookSubscription, constant.TableWebhookDelivery, constant.TableWebhookAttempt)
+	webhookUseCase := webhook.NewWebhookUseCase(webhookRepo)
+
+	eventBus := outbox.NewBus()
+	sinks := []outbox.Sink{eventBus, webhook.NewDispatcher(webhookRepo)}
+	for _, name := range cfg.Outbox.Sinks {
+		switch name {
+		case "stdout":
+			sinks = append(sinks, outbox.NewWriterSink(os.Stdout))
+		case "webhook":
+			sinks = append(sinks, outbox.NewHTTPSink(cfg.Outbox.WebhookURL, &http.Client{Timeout: 10 * time.Second}))
+		default:
+			log.Fatalf("unknown outbox sink %q", name)
+		}
+	}
+
+	outboxPolicy := outbox.Policy{
+		MaxAttempts: cfg.Outbox.MaxAttempts,
+		BackoffBase: cfg.Outbox.BackoffBase,
+		BackoffMax:  cfg.Outbox.BackoffMax,
+		Lease:       cfg.Outbox.Lease,
+	}
+
+	relay := outbox.NewRelay(outboxRepo, transactor, sinks, outboxPolicy, cfg.Outbox.PollInterval, cfg.Outbox.BatchSize)
+
+	webhookPolicy := webhook.Policy{
+		MaxAttempts:  cfg.Webhook.MaxAttempts,
+		BackoffBase:  cfg.Webhook.BackoffBase,
+		BackoffMax:   cfg.Webhook.BackoffMax,
+		DisableAfter: cfg.Webhook.DisableAfter,
+		Lease:        cfg.Webhook.Timeout,
+	}
+	webhookClient := &http.Client{Timeout: cfg.Webhook.Timeout}
+	webhookWorker := webhook.NewWorker(webhookRepo, transactor, webhookClient, webhookPolicy, cfg.Webhook.PollInterval, cfg.Webhook.BatchSize)
+
+	routes.Register(router, routes.Dependencies{
+		Health:         checks,
+		Metrics:        appMetrics.Handler(),
+		Validate:       validator,
+		Accounts:       accountUseCase,
+		AccountOptions: []account.HandlerOption{account.RequireIfMatch(cfg.Server.RequireIfMatch)},
+		Audit:          auditUseCase,
+		Webhooks:       webhookUseCase,
+		AdminAuth:      adminAuth,
+	})
 
 	server := &http.Server{
-		Addr:    fmt.Sprintf(":%s", cfg.App.Port),
-		Handler: router,
+		Addr:              fmt.Sprintf(":%s", cfg.App.Port),
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	app := lifecycle.New(server, cfg.Server.ShutdownTimeout)
+	app.SetDrainDelay(cfg.Server.ShutdownDelay)
+	app.OnShutdown(checks.Shutdown)
+	app.AddWorker("outbox relay", relay)
+	app.AddWorker("webhook worker", webhookWorker)
+	if replicas.Len() > 0 {
+		app.AddWorker("replica checks", replicas)
+	}
+	app.AddCloser("database", db)
+	for i, replicaDB := range replicaDBs {
+		app.AddCloser(fmt.Sprintf("replica %d", i), replicaDB)
+	}
+	app.AddCloser("cache", lifecycle.CloserFunc(closeCache))
+	app.AddCloser("tracer", lifecycle.CloserFunc(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		return shutdownTracing(ctx)
+	}))
+
+	err = app.Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
 	}
+}
 
-	port := os.Getenv("PORT")
+func databaseOptions(cfg *config.Config) database.Options {
+	return database.Options{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
+	}
+}
 
-	fmt.Println("SERVER ON")
-	fmt.Println("PORT :", port)
-	log.Fatal(server.ListenAndServe())
+func cacheOptions(cfg *config.Config) cache.Options {
+	return cache.Options{
+		Backend:       cfg.Cache.Backend,
+		Size:          cfg.Cache.Size,
+		RedisAddr:     cfg.Cache.RedisAddr,
+		RedisPassword: cfg.Cache.RedisPassword,
+		RedisDB:       cfg.Cache.RedisDB,
+		RedisTimeout:  cfg.Cache.RedisTimeout,
+		Prefix:        constant.CachePrefix,
+	}
 }
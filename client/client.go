@@ -0,0 +1,384 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"waizly/models"
+)
+
+// Client calls the account API of a waizly server. It keeps the token
+// returned by Login and sends it as a bearer token; when credentials were
+// given with WithCredentials it signs in again before the token expires or
+// after the server rejects it. A Client is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	retry      Retry
+
+	mu          sync.Mutex
+	token       string
+	credentials *models.LoginRequest
+}
+
+// Retry controls how failed requests are repeated. Requests are retried on
+// network errors and 5xx responses; a POST is only retried on 502, 503 and
+// 504, where the server most likely never processed it.
+type Retry struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+type Option func(c *Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func WithRetry(retry Retry) Option {
+	return func(c *Client) {
+		c.retry = retry
+	}
+}
+
+// WithToken starts the client with a token obtained elsewhere.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithCredentials lets the client sign in on its own, on the first
+// authenticated call and whenever the token needs refreshing.
+func WithCredentials(email, password string) Option {
+	return func(c *Client) {
+		c.credentials = &models.LoginRequest{Email: email, Password: password}
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		retry: Retry{
+			MaxAttempts: 3,
+			BaseDelay:   100 * time.Millisecond,
+			MaxDelay:    2 * time.Second,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Token returns the token currently used for authenticated calls.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.token
+}
+
+func (c *Client) Register(ctx context.Context, params models.RegisterRequest) (models.Account, error) {
+	var account models.Account
+
+	_, err := c.do(ctx, http.MethodPost, "/account/register", nil, params, "", &account)
+
+	return account, err
+}
+
+// Login signs in and keeps the returned token for later calls.
+func (c *Client) Login(ctx context.Context, params models.LoginRequest) (models.Account, error) {
+	var account models.Account
+
+	resp, err := c.do(ctx, http.MethodPost, "/account/login", nil, params, "", &account)
+	if err != nil {
+		return account, err
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "token" && cookie.Value != "" {
+			c.mu.Lock()
+			c.token = cookie.Value
+			c.mu.Unlock()
+
+			return account, nil
+		}
+	}
+
+	return account, errors.New("client: login response carried no token")
+}
+
+func (c *Client) Detail(ctx context.Context) (models.Account, error) {
+	var account models.Account
+
+	err := c.authorized(ctx, http.MethodGet, "/account/detail", nil, nil, &account)
+
+	return account, err
+}
+
+// Update stores params. When params.Version is set, as on an account
+// returned by Detail, the update only applies while the account is still at
+// that version and fails with exception.ErrPreconditionFailed otherwise.
+func (c *Client) Update(ctx context.Context, params models.Account) (models.Account, error) {
+	var account models.Account
+
+	err := c.authorized(ctx, http.MethodPatch, "/account/update", ifMatch(params.Version), params, &account)
+
+	return account, err
+}
+
+func (c *Client) Delete(ctx context.Context) error {
+	return c.DeleteVersion(ctx, 0)
+}
+
+// DeleteVersion deletes the account only while it is still at version, like
+// Update. A version of 0 deletes it whatever its version.
+func (c *Client) DeleteVersion(ctx context.Context, version int64) error {
+	return c.authorized(ctx, http.MethodDelete, "/account/delete", ifMatch(version), nil, nil)
+}
+
+// ifMatch returns the If-Match header naming version, or nil for 0.
+func ifMatch(version int64) http.Header {
+	if version == 0 {
+		return nil
+	}
+
+	return http.Header{"If-Match": {strconv.Quote(strconv.FormatInt(version, 10))}}
+}
+
+// authorized sends an authenticated request. With credentials configured an
+// expiring token is refreshed first, and a 401 is answered by signing in
+// once more and repeating the request.
+func (c *Client) authorized(ctx context.Context, method, path string, header http.Header, body, out interface{}) error {
+	token, err := c.currentToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(ctx, method, path, header, body, token, out)
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || !c.canLogin() {
+		return err
+	}
+
+	token, err = c.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(ctx, method, path, header, body, token, out)
+
+	return err
+}
+
+// refreshBefore is how long before its expiry a token is replaced.
+const refreshBefore = time.Minute
+
+func (c *Client) currentToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+
+	if c.canLogin() && (token == "" || expiresWithin(token, refreshBefore)) {
+		return c.login(ctx)
+	}
+
+	return token, nil
+}
+
+func (c *Client) canLogin() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.credentials != nil
+}
+
+func (c *Client) login(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	credentials := *c.credentials
+	c.mu.Unlock()
+
+	_, err := c.Login(ctx, credentials)
+	if err != nil {
+		return "", err
+	}
+
+	return c.Token(), nil
+}
+
+// expiresWithin reads the exp claim of a JWT without verifying it; the
+// server remains the judge of whether the token is valid.
+func expiresWithin(token string, d time.Duration) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+
+	err = json.Unmarshal(payload, &claims)
+	if err != nil || claims.ExpiresAt == 0 {
+		return false
+	}
+
+	return time.Until(time.Unix(claims.ExpiresAt, 0)) < d
+}
+
+type envelope struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// do sends one API call, retrying per c.retry, and decodes the data of a
+// successful response into out.
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body interface{}, token string, out interface{}) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	attempts := c.retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var (
+		resp *http.Response
+		data []byte
+		err  error
+	)
+
+	for attempt := 1; ; attempt++ {
+		resp, data, err = c.send(ctx, method, path, header, payload, token)
+		if attempt >= attempts || !retryable(method, resp, err) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.backoff(attempt)):
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp, newError(resp, data)
+	}
+
+	if out == nil {
+		return resp, nil
+	}
+
+	var env envelope
+	err = json.Unmarshal(data, &env)
+	if err != nil {
+		return resp, fmt.Errorf("client: decode %s %s: %w", method, path, err)
+	}
+
+	err = json.Unmarshal(env.Data, out)
+	if err != nil {
+		return resp, fmt.Errorf("client: decode %s %s: %w", method, path, err)
+	}
+
+	return resp, nil
+}
+
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, payload []byte, token string) (*http.Response, []byte, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	req.Header.Set("Accept", "application/problem+json, application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, data, nil
+}
+
+func retryable(method string, resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return method != http.MethodPost && resp.StatusCode >= http.StatusInternalServerError
+}
+
+// backoff doubles the delay after every attempt, capped at MaxDelay, with
+// full jitter so clients retrying together spread out.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retry.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > c.retry.MaxDelay {
+		delay = c.retry.MaxDelay
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
@@ -0,0 +1,215 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	newJWT "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"waizly/client"
+	"waizly/config/jwt"
+	"waizly/helpers/exception"
+	"waizly/helpers/middleware"
+	"waizly/helpers/response"
+	"waizly/helpers/validation"
+	"waizly/internal/account"
+	"waizly/internal/account/mocks"
+	"waizly/models"
+)
+
+var fast = client.WithRetry(client.Retry{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+func token(t *testing.T, id int64, expiresIn time.Duration) string {
+	jwt.Configure("0123456789abcdef", nil)
+
+	signed, err := jwt.Sign(&jwt.JWTclaim{
+		ID: id,
+		StandardClaims: newJWT.StandardClaims{
+			ExpiresAt: time.Now().Add(expiresIn).Unix(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+// serve runs the real account handlers in front of useCase. wrap may put a
+// middleware in front of the router.
+func serve(t *testing.T, useCase account.AccountUseCase, wrap func(http.Handler) http.Handler) *httptest.Server {
+	router := mux.NewRouter()
+	router.Use(middleware.RequestMetadata)
+	account.NewAccountHandler(router, validation.New(), useCase)
+
+	var handler http.Handler = router
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestClient(t *testing.T) {
+	profile := models.Account{ID: 7, Username: "jane", Email: "jane@example.com"}
+
+	t.Run("Register Login Detail", func(t *testing.T) {
+		useCase := new(mocks.AccountUseCase)
+		useCase.On("Register", mock.Anything, models.RegisterRequest{Username: "jane", Password: "secret", Email: "jane@example.com"}).
+			Return(response.Success(response.StatusCreated, profile))
+		useCase.On("Login", mock.Anything, models.LoginRequest{Email: "jane@example.com", Password: "secret"}).
+			Return(response.Success(response.StatusOK, profile), models.Token{Token: token(t, 7, time.Hour)})
+		useCase.On("Authenticate", mock.Anything, mock.AnythingOfType("jwt.JWTclaim")).Return(response.Success(response.StatusOK, profile))
+		useCase.On("DetailAccount", mock.Anything, int64(7)).Return(response.Success(response.StatusOK, profile))
+
+		c := client.New(serve(t, useCase, nil).URL)
+		ctx := context.TODO()
+
+		registered, err := c.Register(ctx, models.RegisterRequest{Username: "jane", Password: "secret", Email: "jane@example.com"})
+		assert.NoError(t, err)
+		assert.Equal(t, profile.ID, registered.ID)
+
+		_, err = c.Login(ctx, models.LoginRequest{Email: "jane@example.com", Password: "secret"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, c.Token())
+
+		detail, err := c.Detail(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, profile, detail)
+
+		useCase.AssertExpectations(t)
+	})
+
+	t.Run("Problem Details", func(t *testing.T) {
+		useCase := new(mocks.AccountUseCase)
+		useCase.On("Register", mock.Anything, mock.Anything).Return(response.Error(response.StatusConflicted, exception.ErrConflicted))
+
+		c := client.New(serve(t, useCase, nil).URL)
+
+		_, err := c.Register(context.TODO(), models.RegisterRequest{Username: "jane", Password: "secret", Email: "jane@example.com"})
+		assert.True(t, errors.Is(err, exception.ErrConflicted), "got %v", err)
+
+		_, err = c.Register(context.TODO(), models.RegisterRequest{Username: "jane", Password: "secret", Email: "not-an-email"})
+
+		var apiErr *client.Error
+		if assert.ErrorAs(t, err, &apiErr) {
+			assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+			assert.Equal(t, response.StatusBadRequest, apiErr.Code)
+			assert.Len(t, apiErr.Errors, 1)
+			assert.Equal(t, "email", apiErr.Errors[0].Field)
+			assert.NotEmpty(t, apiErr.RequestID)
+		}
+	})
+
+	t.Run("Retries Server Errors", func(t *testing.T) {
+		useCase := new(mocks.AccountUseCase)
+		useCase.On("Authenticate", mock.Anything, mock.Anything).Return(response.Success(response.StatusOK, profile))
+		useCase.On("DetailAccount", mock.Anything, int64(7)).Return(response.Success(response.StatusOK, profile))
+
+		var calls int32
+		server := serve(t, useCase, func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) < 3 {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+			})
+		})
+
+		c := client.New(server.URL, client.WithToken(token(t, 7, time.Hour)), fast)
+
+		_, err := c.Detail(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("Does Not Retry Failed Post", func(t *testing.T) {
+		var calls int32
+		server := serve(t, new(mocks.AccountUseCase), func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+		})
+
+		c := client.New(server.URL, fast)
+
+		_, err := c.Register(context.TODO(), models.RegisterRequest{Username: "jane", Password: "secret", Email: "jane@example.com"})
+
+		assert.True(t, errors.Is(err, exception.ErrInternalServer), "got %v", err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("Refreshes Expiring Token", func(t *testing.T) {
+		fresh := token(t, 7, time.Hour)
+
+		useCase := new(mocks.AccountUseCase)
+		useCase.On("Login", mock.Anything, mock.Anything).Return(response.Success(response.StatusOK, profile), models.Token{Token: fresh}).Once()
+		useCase.On("Authenticate", mock.Anything, mock.Anything).Return(response.Success(response.StatusOK, profile))
+		useCase.On("DetailAccount", mock.Anything, int64(7)).Return(response.Success(response.StatusOK, profile))
+
+		c := client.New(serve(t, useCase, nil).URL,
+			client.WithToken(token(t, 7, 30*time.Second)),
+			client.WithCredentials("jane@example.com", "secret"),
+		)
+
+		_, err := c.Detail(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, fresh, c.Token())
+		useCase.AssertExpectations(t)
+	})
+
+	t.Run("Signs In Again After Unauthorized", func(t *testing.T) {
+		fresh := token(t, 7, time.Hour)
+
+		useCase := new(mocks.AccountUseCase)
+		useCase.On("Login", mock.Anything, mock.Anything).Return(response.Success(response.StatusOK, profile), models.Token{Token: fresh}).Once()
+		useCase.On("Authenticate", mock.Anything, mock.MatchedBy(func(claims jwt.JWTclaim) bool { return claims.ID == 8 })).
+			Return(response.Error(response.StatusUnauthorized, exception.ErrUnauthorized))
+		useCase.On("Authenticate", mock.Anything, mock.Anything).Return(response.Success(response.StatusOK, profile))
+		useCase.On("DetailAccount", mock.Anything, int64(7)).Return(response.Success(response.StatusOK, profile))
+
+		c := client.New(serve(t, useCase, nil).URL,
+			client.WithToken(token(t, 8, time.Hour)),
+			client.WithCredentials("jane@example.com", "secret"),
+		)
+
+		_, err := c.Detail(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, fresh, c.Token())
+		useCase.AssertExpectations(t)
+	})
+
+	t.Run("Context Cancelled", func(t *testing.T) {
+		server := serve(t, new(mocks.AccountUseCase), func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})
+		})
+
+		c := client.New(server.URL, client.WithToken("token"), client.WithRetry(client.Retry{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}))
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := c.Detail(ctx)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
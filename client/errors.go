@@ -0,0 +1,83 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"waizly/helpers/exception"
+)
+
+// Error is a non-2xx answer from the server. The problem details fields are
+// filled when the server returned them.
+type Error struct {
+	StatusCode int
+	Code       string       `json:"code"`
+	Type       string       `json:"type"`
+	Title      string       `json:"title"`
+	Detail     string       `json:"detail"`
+	RequestID  string       `json:"request_id"`
+	Errors     []FieldError `json:"errors"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param"`
+	Message string `json:"message"`
+}
+
+func newError(resp *http.Response, data []byte) *Error {
+	e := &Error{StatusCode: resp.StatusCode}
+
+	// Both the problem document and the legacy envelope carry the status
+	// code, as "code" and "status" respectively.
+	var legacy struct {
+		Status string `json:"status"`
+	}
+
+	if json.Unmarshal(data, e) != nil || e.Code == "" {
+		if json.Unmarshal(data, &legacy) == nil {
+			e.Code = legacy.Status
+		}
+	}
+
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("X-Request-ID")
+	}
+
+	return e
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("waizly: %d %s", e.StatusCode, e.Code)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+
+	for _, fe := range e.Errors {
+		msg += "; " + fe.Message
+	}
+
+	return msg
+}
+
+var sentinels = map[int]error{
+	http.StatusBadRequest:          exception.ErrBadRequest,
+	http.StatusUnauthorized:        exception.ErrUnauthorized,
+	http.StatusForbidden:           exception.ErrForbidden,
+	http.StatusNotFound:            exception.ErrNotFound,
+	http.StatusConflict:            exception.ErrConflicted,
+	http.StatusInternalServerError: exception.ErrInternalServer,
+	http.StatusServiceUnavailable:  exception.ErrUnavailable,
+	http.StatusGatewayTimeout:      exception.ErrTimeout,
+
+	http.StatusPreconditionFailed:   exception.ErrPreconditionFailed,
+	http.StatusPreconditionRequired: exception.ErrPreconditionRequired,
+}
+
+// Is lets callers test errors with the server's sentinels, for example
+// errors.Is(err, exception.ErrNotFound).
+func (e *Error) Is(target error) bool {
+	return sentinels[e.StatusCode] == target
+}
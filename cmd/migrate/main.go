@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+
+	"waizly/config"
+	"waizly/db/migration"
+	"waizly/helpers/database"
+	"waizly/helpers/dialect"
+	"waizly/internal/constant"
+	"waizly/internal/migrator"
+)
+
+func main() {
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dbDialect := dialect.Dialect(cfg.Database.Driver)
+
+	db, err := database.Open(cfg.Database.Driver, cfg.Database.DSN, database.Options{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer db.Close()
+
+	err = database.Wait(context.Background(), db, cfg.Database.ConnectTimeout)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migrations, err := migrator.Load(migration.For(dbDialect))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	m := migrator.New(db, dbDialect, migrations, constant.TableSchemaMigrations)
+
+	err = migrator.Run(context.Background(), m, flag.Args(), os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+
+	"waizly/config"
+	"waizly/config/bcrypt"
+	"waizly/config/jwt"
+	"waizly/db/migration"
+	"waizly/helpers/cache"
+	"waizly/helpers/database"
+	"waizly/helpers/dialect"
+	"waizly/helpers/metadata"
+	"waizly/helpers/metrics"
+	"waizly/helpers/response"
+	"waizly/helpers/transaction"
+	"waizly/helpers/validation"
+	"waizly/internal/account"
+	"waizly/internal/audit"
+	"waizly/internal/constant"
+	"waizly/internal/migrator"
+	"waizly/internal/outbox"
+	"waizly/models"
+)
+
+const usage = `usage: waizlyctl <command>
+
+commands:
+  migrate <args>                          run a migrate command, see "waizlyctl migrate"
+  accounts create <username> <email>      create an account, the password is read from stdin
+  accounts list [-after id] [-limit n]    list accounts ordered by id
+  accounts disable <id>                   block login and revoke access for an account
+  accounts enable <id>                    lift a disable
+  accounts reset-password <id>            set a new password read from stdin and revoke sessions
+  roles assign <id> <user|admin>          change the role of an account
+  sessions revoke <id>                    invalidate every token issued so far
+  keys rotate                             print a new JWT_SECRET and JWT_PREVIOUS_SECRETS
+  config print                            print the effective configuration with secrets masked`
+
+var errUsage = errors.New(usage)
+
+type ctl struct {
+	cfg      *config.Config
+	db       *sql.DB
+	dialect  dialect.Dialect
+	validate *validator.Validate
+	accounts account.AccountUseCase
+	closers  []func() error
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	jwt.Configure(cfg.Jwt.Secret, cfg.Jwt.PreviousSecrets)
+
+	c := &ctl{
+		cfg:      cfg,
+		dialect:  dialect.Dialect(cfg.Database.Driver),
+		validate: validation.New(),
+	}
+
+	ctx := metadata.NewContext(context.Background(), metadata.Request{UserAgent: "waizlyctl"})
+
+	err = c.run(ctx, flag.Args())
+	c.close()
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func (c *ctl) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	if changesAccess(args) && c.cfg.Cache.Backend == "memory" {
+		return errors.New("CACHE_BACKEND=memory: every server caches accounts in its own memory and keeps serving the old account until CACHE_TTL, use the redis cache to change access from waizlyctl")
+	}
+
+	switch args[0] {
+	case "migrate", "accounts", "roles", "sessions":
+		err := c.connect(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch args[0] {
+	case "migrate":
+		return c.migrate(ctx, args[1:])
+	case "accounts":
+		return c.account(ctx, args[1:])
+	case "roles":
+		if len(args) != 4 || args[1] != "assign" {
+			return errUsage
+		}
+
+		id, err := parseID(args[2])
+		if err != nil {
+			return err
+		}
+
+		return output(c.accounts.AssignRole(ctx, id, args[3]))
+	case "sessions":
+		if len(args) != 3 || args[1] != "revoke" {
+			return errUsage
+		}
+
+		id, err := parseID(args[2])
+		if err != nil {
+			return err
+		}
+
+		return output(c.accounts.RevokeSessions(ctx, id))
+	case "keys":
+		if len(args) != 2 || args[1] != "rotate" {
+			return errUsage
+		}
+
+		return rotateKeys(c.cfg)
+	case "config":
+		if len(args) != 2 || args[1] != "print" {
+			return errUsage
+		}
+
+		return c.cfg.Print(os.Stdout)
+	default:
+		return errUsage
+	}
+}
+
+// connect opens the database, and the shared cache when there is one, for the
+// commands that change accounts or the schema.
+func (c *ctl) connect(ctx context.Context) error {
+	cfg := c.cfg
+
+	db, err := database.Open(cfg.Database.Driver, cfg.Database.DSN, database.Options{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.db = db
+	c.closers = append(c.closers, db.Close)
+
+	err = database.Wait(ctx, db, cfg.Database.ConnectTimeout)
+	if err != nil {
+		return err
+	}
+
+	transactor := transaction.NewTransactor(db, transaction.WithRetry(cfg.Database.TxMaxAttempts, c.dialect.Retryable))
+	auditUseCase := audit.NewAuditUseCase(audit.NewAuditRepository(db, c.dialect, constant.TableAuditLog, constant.TableAuditLogHead))
+	outboxRepo := outbox.NewOutboxRepository(db, c.dialect, constant.TableOutbox)
+	accountRepo := account.NewRepository(db, c.dialect, constant.TableAccount,
+		account.WithQueryTimeout(cfg.Database.QueryTimeout),
+		account.WithSlowQueryLog(cfg.Database.SlowQueryThreshold))
+
+	// Changes made here have to clear what the servers cached in Redis. A
+	// memory cache lives in each server out of reach, see changesAccess.
+	if cfg.Cache.Backend == "redis" {
+		accountCache, closeCache, err := cache.Open(cache.Options{
+			Backend:       cfg.Cache.Backend,
+			RedisAddr:     cfg.Cache.RedisAddr,
+			RedisPassword: cfg.Cache.RedisPassword,
+			RedisDB:       cfg.Cache.RedisDB,
+			RedisTimeout:  cfg.Cache.RedisTimeout,
+			Prefix:        constant.CachePrefix,
+		})
+		if err != nil {
+			return err
+		}
+
+		c.closers = append(c.closers, closeCache)
+		accountRepo = account.NewCachedRepository(accountRepo, accountCache, cfg.Cache.TTL, metrics.New())
+	}
+
+	c.accounts = account.NewAccountUseCase(accountRepo, bcrypt.NewBcrypt(cfg.Bcrypt.HashCost), auditUseCase, outboxRepo, transactor)
+
+	return nil
+}
+
+// changesAccess reports whether the command changes who may log in or what
+// they may do. The servers only see such a change at once when their cache is
+// shared, so these commands refuse to run against the memory cache.
+func changesAccess(args []string) bool {
+	switch args[0] {
+	case "roles", "sessions":
+		return true
+	case "accounts":
+		return len(args) > 1 && (args[1] == "disable" || args[1] == "enable" || args[1] == "reset-password")
+	default:
+		return false
+	}
+}
+
+// close releases what connect opened, the cache before the database.
+func (c *ctl) close() {
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		c.closers[i]()
+	}
+}
+
+func (c *ctl) migrate(ctx context.Context, args []string) error {
+	migrations, err := migrator.Load(migration.For(c.dialect))
+	if err != nil {
+		return err
+	}
+
+	m := migrator.New(c.db, c.dialect, migrations, constant.TableSchemaMigrations)
+
+	return migrator.Run(ctx, m, args, os.Stdout)
+}
+
+func (c *ctl) account(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) != 3 {
+			return errUsage
+		}
+
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+
+		params := models.RegisterRequest{
+			Username: args[1],
+			Email:    args[2],
+			Password: password,
+		}
+
+		err = c.validate.StructCtx(ctx, params)
+		if err != nil {
+			return err
+		}
+
+		return output(c.accounts.Register(ctx, params))
+	case "list":
+		flags := flag.NewFlagSet("accounts list", flag.ContinueOnError)
+		after := flags.Int64("after", 0, "only list accounts with an id greater than this")
+		limit := flags.Int("limit", 100, "maximum number of accounts to list")
+
+		err := flags.Parse(args[1:])
+		if err != nil {
+			return errUsage
+		}
+
+		return output(c.accounts.ListAccounts(ctx, *after, *limit))
+	case "disable", "enable", "reset-password":
+		if len(args) != 2 {
+			return errUsage
+		}
+
+		id, err := parseID(args[1])
+		if err != nil {
+			return err
+		}
+
+		if args[0] != "reset-password" {
+			return output(c.accounts.SetDisabled(ctx, id, args[0] == "disable"))
+		}
+
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+
+		return output(c.accounts.ResetPassword(ctx, id, password))
+	default:
+		return errUsage
+	}
+}
+
+// rotateKeys prints the environment for the next signing key. The current key
+// moves to the front of JWT_PREVIOUS_SECRETS so tokens it signed keep
+// verifying until they expire; drop it from the list after that. Without a
+// configured key there is nothing to keep accepting, so it refuses to run.
+func rotateKeys(cfg *config.Config) error {
+	if cfg.Jwt.Secret == "" {
+		return errors.New("JWT_SECRET is not set, there is no key to rotate out")
+	}
+
+	secret := make([]byte, 32)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		return err
+	}
+
+	previous := append([]string{cfg.Jwt.Secret}, cfg.Jwt.PreviousSecrets...)
+
+	fmt.Printf("JWT_SECRET=%s\n", base64.RawURLEncoding.EncodeToString(secret))
+	fmt.Printf("JWT_PREVIOUS_SECRETS=%s\n", strings.Join(previous, ","))
+
+	return nil
+}
+
+// readPassword takes the first line of stdin so passwords stay out of the
+// shell history and process list.
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "password: ")
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		return "", errors.New("password must not be empty")
+	}
+
+	return password, nil
+}
+
+func parseID(value string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid account id %q", value)
+	}
+
+	return id, nil
+}
+
+// output writes the use case response as JSON and returns its error so the
+// process exits non-zero when the command failed.
+func output(res response.Response) error {
+	err := printJSON(res)
+	if err != nil {
+		return err
+	}
+
+	return res.Err()
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
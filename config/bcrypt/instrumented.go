@@ -0,0 +1,30 @@
+package bcrypt
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type instrumentedBcrypt struct {
+	Bcrypt
+	duration prometheus.Observer
+}
+
+// NewInstrumentedBcrypt reports how long every HashPassword call takes to
+// duration.
+func NewInstrumentedBcrypt(bcrypt Bcrypt, duration prometheus.Observer) Bcrypt {
+	return &instrumentedBcrypt{
+		Bcrypt:   bcrypt,
+		duration: duration,
+	}
+}
+
+func (ib *instrumentedBcrypt) HashPassword(plain string) (string, error) {
+	start := time.Now()
+	defer func() {
+		ib.duration.Observe(time.Since(start).Seconds())
+	}()
+
+	return ib.Bcrypt.HashPassword(plain)
+}
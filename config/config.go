@@ -2,86 +2,216 @@ package config
 
 import (
 	"crypto/rsa"
-	"fmt"
-	"log"
-	"net/url"
-	"os"
-	"strconv"
+	"errors"
+	"flag"
+	"io/fs"
+	"time"
 
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
+
+	"waizly/helpers/dialect"
 )
 
 type Config struct {
 	App struct {
-		Port string
-	}
+		Port string `yaml:"port"`
+	} `yaml:"app"`
+	Server struct {
+		ReadTimeout       time.Duration `yaml:"read_timeout"`
+		ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+		WriteTimeout      time.Duration `yaml:"write_timeout"`
+		IdleTimeout       time.Duration `yaml:"idle_timeout"`
+		ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+		ShutdownDelay     time.Duration `yaml:"shutdown_delay"`
+		RequireIfMatch    bool          `yaml:"require_if_match"`
+	} `yaml:"server"`
+	Health struct {
+		CheckTimeout time.Duration `yaml:"check_timeout"`
+		CacheTTL     time.Duration `yaml:"cache_ttl"`
+	} `yaml:"health"`
+	Log struct {
+		Level  string `yaml:"level"`
+		Format string `yaml:"format"`
+	} `yaml:"log"`
+	I18n struct {
+		FallbackLocale string `yaml:"fallback_locale"`
+		CatalogDir     string `yaml:"catalog_dir"`
+	} `yaml:"i18n"`
+	Tracing struct {
+		Exporter     string  `yaml:"exporter"`
+		OTLPEndpoint string  `yaml:"otlp_endpoint"`
+		OTLPInsecure bool    `yaml:"otlp_insecure"`
+		SampleRatio  float64 `yaml:"sample_ratio"`
+	} `yaml:"tracing"`
 	Database struct {
-		DSN string
-	}
+		Driver        string `yaml:"driver"`
+		DSN           string `yaml:"dsn"`
+		Host          string `yaml:"host"`
+		Port          int    `yaml:"port"`
+		Username      string `yaml:"username"`
+		Password      string `yaml:"password"`
+		Name          string `yaml:"name"`
+		SSLMode       string `yaml:"sslmode"`
+		Location      string `yaml:"location"`
+		AutoMigrate   bool   `yaml:"auto_migrate"`
+		TxMaxAttempts int    `yaml:"tx_max_attempts"`
+
+		MaxOpenConns       int           `yaml:"max_open_conns"`
+		MaxIdleConns       int           `yaml:"max_idle_conns"`
+		ConnMaxLifetime    time.Duration `yaml:"conn_max_lifetime"`
+		ConnMaxIdleTime    time.Duration `yaml:"conn_max_idle_time"`
+		ConnectTimeout     time.Duration `yaml:"connect_timeout"`
+		QueryTimeout       time.Duration `yaml:"query_timeout"`
+		SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+
+		ReplicaDSNs          []string      `yaml:"replica_dsns"`
+		ReplicaMaxLag        time.Duration `yaml:"replica_max_lag"`
+		ReplicaCheckInterval time.Duration `yaml:"replica_check_interval"`
+	} `yaml:"database"`
+	Cache struct {
+		Backend       string        `yaml:"backend"`
+		TTL           time.Duration `yaml:"ttl"`
+		Size          int           `yaml:"size"`
+		RedisAddr     string        `yaml:"redis_addr"`
+		RedisPassword string        `yaml:"redis_password"`
+		RedisDB       int           `yaml:"redis_db"`
+		RedisTimeout  time.Duration `yaml:"redis_timeout"`
+	} `yaml:"cache"`
 	Bcrypt struct {
-		HashCost int
-	}
+		HashCost int `yaml:"hash_cost"`
+	} `yaml:"bcrypt"`
 	Jwt struct {
-		PrivateKey *rsa.PrivateKey
-		PublicKey  *rsa.PublicKey
-	}
+		PrivateKey      *rsa.PrivateKey `yaml:"-"`
+		PublicKey       *rsa.PublicKey  `yaml:"-"`
+		Secret          string          `yaml:"secret"`
+		PreviousSecrets []string        `yaml:"previous_secrets"`
+	} `yaml:"jwt"`
 	BasicAuth struct {
-		Username string
-		Password string
-	}
-}
-
-func New() *Config {
-	c := new(Config)
-	c.loadApp()
-	c.loadDatabase()
-	c.loadBcrypt()
-
-	return c
-}
-
-func (c *Config) loadApp() *Config {
-	port := os.Getenv("PORT")
-
-	c.App.Port = port
-
-	return c
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	} `yaml:"basic_auth"`
+	Outbox struct {
+		Sinks        []string      `yaml:"sinks"`
+		WebhookURL   string        `yaml:"webhook_url"`
+		PollInterval time.Duration `yaml:"poll_interval"`
+		BatchSize    int           `yaml:"batch_size"`
+		MaxAttempts  int           `yaml:"max_attempts"`
+		BackoffBase  time.Duration `yaml:"backoff_base"`
+		BackoffMax   time.Duration `yaml:"backoff_max"`
+		Lease        time.Duration `yaml:"lease"`
+	} `yaml:"outbox"`
+	Webhook struct {
+		MaxAttempts  int           `yaml:"max_attempts"`
+		BackoffBase  time.Duration `yaml:"backoff_base"`
+		BackoffMax   time.Duration `yaml:"backoff_max"`
+		DisableAfter int           `yaml:"disable_after"`
+		Timeout      time.Duration `yaml:"timeout"`
+		PollInterval time.Duration `yaml:"poll_interval"`
+		BatchSize    int           `yaml:"batch_size"`
+	} `yaml:"webhook"`
 }
 
-func (c *Config) loadDatabase() *Config {
+// Load builds the configuration from, in increasing order of precedence, the
+// built-in defaults, the YAML file named by -config or CONFIG_FILE, the
+// environment (including a .env file when present) and the command-line
+// flags registered on flags. Every problem found is reported in one error.
+// Arguments left after the flags are available through flags.Args().
+func Load(flags *flag.FlagSet, args []string) (*Config, error) {
 	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
 	}
 
-	// get Env Value
+	c := defaults()
 
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	username := os.Getenv("DB_USERNAME")
-	password := os.Getenv("DB_PASSWORD")
-	database := os.Getenv("DB_DATABASE_NAME")
-
-	connVal := url.Values{}
-	// connVal.Add("parseTime", "1")
-	connVal.Add("loc", "Asia/Jakarta")
-
-	dbConnection := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", username, password, host, port, database)
+	file := flags.String("config", getenv("CONFIG_FILE"), "path to a YAML configuration file")
+	settings := c.settings()
+	for _, s := range settings {
+		flags.Var(&rawValue{boolFlag: isBoolFlag(s.value)}, s.flag(), s.usage)
+	}
 
-	dsn := fmt.Sprintf("%s?%s", dbConnection, connVal.Encode())
+	err = flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
 
-	c.Database.DSN = dsn
+	if *file != "" {
+		err = c.loadFile(*file)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	c.Database.DSN = dbConnection
+	problems := c.loadEnv(settings)
+	problems = append(problems, c.loadFlags(flags, settings)...)
+	problems = append(problems, c.validate()...)
+	if len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
 
-	return c
+	return c, nil
 }
 
-func (c *Config) loadBcrypt() *Config {
-	// env value
-	hashCost := os.Getenv("BCRYPT_HASH_COST")
+func defaults() *Config {
+	c := new(Config)
 
-	c.Bcrypt.HashCost, _ = strconv.Atoi(hashCost)
+	c.App.Port = "8080"
+
+	c.Server.ReadTimeout = 15 * time.Second
+	c.Server.ReadHeaderTimeout = 5 * time.Second
+	c.Server.WriteTimeout = 30 * time.Second
+	c.Server.IdleTimeout = time.Minute
+	c.Server.ShutdownTimeout = 30 * time.Second
+
+	c.Health.CheckTimeout = 2 * time.Second
+	c.Health.CacheTTL = 2 * time.Second
+
+	c.Log.Level = "info"
+	c.Log.Format = "json"
+
+	c.I18n.FallbackLocale = "en"
+
+	c.Tracing.Exporter = "none"
+	c.Tracing.OTLPEndpoint = "localhost:4318"
+	c.Tracing.SampleRatio = 1
+
+	c.Database.Driver = string(dialect.MySQL)
+	c.Database.SSLMode = "disable"
+	c.Database.Location = "UTC"
+	c.Database.TxMaxAttempts = 3
+	c.Database.MaxOpenConns = 20
+	c.Database.MaxIdleConns = 10
+	c.Database.ConnMaxLifetime = 30 * time.Minute
+	c.Database.ConnMaxIdleTime = 5 * time.Minute
+	c.Database.ConnectTimeout = 30 * time.Second
+	c.Database.QueryTimeout = 5 * time.Second
+	c.Database.SlowQueryThreshold = 200 * time.Millisecond
+	c.Database.ReplicaMaxLag = 5 * time.Second
+	c.Database.ReplicaCheckInterval = 5 * time.Second
+
+	c.Cache.Backend = "none"
+	c.Cache.TTL = 30 * time.Second
+	c.Cache.Size = 10000
+	c.Cache.RedisAddr = "localhost:6379"
+	c.Cache.RedisTimeout = 100 * time.Millisecond
+
+	c.Bcrypt.HashCost = bcrypt.DefaultCost
+
+	c.Outbox.PollInterval = time.Second
+	c.Outbox.BatchSize = 100
+	c.Outbox.MaxAttempts = 10
+	c.Outbox.BackoffBase = time.Second
+	c.Outbox.BackoffMax = 5 * time.Minute
+	c.Outbox.Lease = time.Minute
+
+	c.Webhook.MaxAttempts = 8
+	c.Webhook.BackoffBase = 30 * time.Second
+	c.Webhook.BackoffMax = time.Hour
+	c.Webhook.DisableAfter = 20
+	c.Webhook.Timeout = 10 * time.Second
+	c.Webhook.PollInterval = 5 * time.Second
+	c.Webhook.BatchSize = 50
 
 	return c
 }
@@ -1,13 +1,80 @@
 package jwt
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
 	"github.com/dgrijalva/jwt-go"
 )
 
-var JWT_KEY = []byte("rahasia")
+// JWT_KEY signs new tokens. PreviousKeys are still accepted when verifying,
+// so tokens issued before a key rotation stay valid until they expire. There
+// is no built-in key: nothing is signed or verified until Configure ran.
+var (
+	JWT_KEY      []byte
+	PreviousKeys [][]byte
+)
+
+var errNoKey = errors.New("jwt signing key is not configured")
 
 type JWTclaim struct {
 	ID    int64
 	Email string
+	Role  string
 	jwt.StandardClaims
 }
+
+// Configure sets the signing key and the keys still accepted from earlier
+// rotations.
+func Configure(secret string, previous []string) {
+	JWT_KEY = []byte(secret)
+
+	PreviousKeys = nil
+	for _, key := range previous {
+		PreviousKeys = append(PreviousKeys, []byte(key))
+	}
+}
+
+// KeyID identifies a key in the token header without revealing it.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+func Sign(claims *JWTclaim) (string, error) {
+	if len(JWT_KEY) == 0 {
+		return "", errNoKey
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = KeyID(JWT_KEY)
+
+	return token.SignedString(JWT_KEY)
+}
+
+// KeyFunc resolves the verification key from the token's kid header. Tokens
+// without a kid predate rotation and are checked against the current key.
+func KeyFunc(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+	}
+
+	if len(JWT_KEY) == 0 {
+		return nil, errNoKey
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" || kid == KeyID(JWT_KEY) {
+		return JWT_KEY, nil
+	}
+
+	for _, key := range PreviousKeys {
+		if KeyID(key) == kid {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"regexp"
+
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v3"
+
+	"waizly/helpers/dialect"
+)
+
+const redacted = "******"
+
+// keywordPassword matches the password in a key=value PostgreSQL DSN.
+var keywordPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Redacted returns a copy of c that is safe to print: secrets are masked and
+// passwords are removed from the DSN and URLs.
+func (c *Config) Redacted() Config {
+	r := *c
+
+	r.Database.DSN = redactDSN(r.Database.Driver, r.Database.DSN)
+	r.Database.ReplicaDSNs = make([]string, len(c.Database.ReplicaDSNs))
+	for i, dsn := range c.Database.ReplicaDSNs {
+		r.Database.ReplicaDSNs[i] = redactDSN(r.Database.Driver, dsn)
+	}
+
+	r.Database.Password = redact(r.Database.Password)
+	r.Cache.RedisPassword = redact(r.Cache.RedisPassword)
+
+	r.Jwt.PrivateKey = nil
+	r.Jwt.PublicKey = nil
+	r.Jwt.Secret = redact(r.Jwt.Secret)
+	r.Jwt.PreviousSecrets = make([]string, len(c.Jwt.PreviousSecrets))
+	for i, secret := range c.Jwt.PreviousSecrets {
+		r.Jwt.PreviousSecrets[i] = redact(secret)
+	}
+
+	r.BasicAuth.Password = redact(r.BasicAuth.Password)
+
+	if u, err := url.Parse(r.Outbox.WebhookURL); err == nil && u.User != nil {
+		r.Outbox.WebhookURL = u.Redacted()
+	}
+
+	return r
+}
+
+// Print writes the redacted effective configuration as YAML, in the same
+// layout the -config file accepts.
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	err := encoder.Encode(c.Redacted())
+	if err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func redactDSN(driver, dsn string) string {
+	if driver != string(dialect.Postgres) {
+		if cfg, err := mysql.ParseDSN(dsn); err == nil && cfg.Passwd != "" {
+			cfg.Passwd = redacted
+			return cfg.FormatDSN()
+		}
+
+		return dsn
+	}
+
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+
+		return u.String()
+	}
+
+	return keywordPassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return redacted
+}
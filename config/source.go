@@ -0,0 +1,351 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setting binds one configuration field to its environment variable. The
+// command-line flag name is derived from the variable, DB_HOST becomes
+// -db-host.
+type setting struct {
+	env   string
+	usage string
+	value flag.Value
+}
+
+func (s setting) flag() string {
+	return strings.ReplaceAll(strings.ToLower(s.env), "_", "-")
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"PORT", "HTTP listen port", stringValue{&c.App.Port}},
+
+		{"SERVER_READ_TIMEOUT", "time allowed to read a whole request", durationValue{&c.Server.ReadTimeout}},
+		{"SERVER_READ_HEADER_TIMEOUT", "time allowed to read request headers", durationValue{&c.Server.ReadHeaderTimeout}},
+		{"SERVER_WRITE_TIMEOUT", "time allowed to write a response", durationValue{&c.Server.WriteTimeout}},
+		{"SERVER_IDLE_TIMEOUT", "how long idle keep-alive connections stay open", durationValue{&c.Server.IdleTimeout}},
+		{"SERVER_SHUTDOWN_TIMEOUT", "deadline for draining requests and stopping workers", durationValue{&c.Server.ShutdownTimeout}},
+		{"SERVER_SHUTDOWN_DELAY", "how long /readyz fails before the server stops accepting connections", durationValue{&c.Server.ShutdownDelay}},
+		{"SERVER_REQUIRE_IF_MATCH", "reject account updates and deletes sent without If-Match", boolValue{&c.Server.RequireIfMatch}},
+
+		{"HEALTH_CHECK_TIMEOUT", "timeout of each readiness check", durationValue{&c.Health.CheckTimeout}},
+		{"HEALTH_CACHE_TTL", "how long a readiness result is reused", durationValue{&c.Health.CacheTTL}},
+
+		{"LOG_LEVEL", "minimum level logged: debug, info, warn or error", stringValue{&c.Log.Level}},
+		{"LOG_FORMAT", "log line format: json or console", stringValue{&c.Log.Format}},
+
+		{"I18N_FALLBACK_LOCALE", "locale used when Accept-Language matches none: en or id", stringValue{&c.I18n.FallbackLocale}},
+		{"I18N_CATALOG_DIR", "directory of extra or overriding translation catalogs", stringValue{&c.I18n.CatalogDir}},
+
+		{"TRACING_EXPORTER", "where spans are sent: none, otlp or stdout", stringValue{&c.Tracing.Exporter}},
+		{"TRACING_OTLP_ENDPOINT", "host:port of the OTLP/HTTP collector", stringValue{&c.Tracing.OTLPEndpoint}},
+		{"TRACING_OTLP_INSECURE", "send spans to the collector over plain HTTP", boolValue{&c.Tracing.OTLPInsecure}},
+		{"TRACING_SAMPLE_RATIO", "fraction of new traces that are sampled, 0 to 1", floatValue{&c.Tracing.SampleRatio}},
+
+		{"DB_DRIVER", "database driver: mysql, postgres or sqlite", stringValue{&c.Database.Driver}},
+		{"DB_DSN", "driver-specific DSN of the primary, overrides the other DB_ settings", stringValue{&c.Database.DSN}},
+		{"DB_HOST", "database host", stringValue{&c.Database.Host}},
+		{"DB_PORT", "database port, 3306 for mysql and 5432 for postgres when unset", intValue{&c.Database.Port}},
+		{"DB_USERNAME", "database user", stringValue{&c.Database.Username}},
+		{"DB_PASSWORD", "database password", stringValue{&c.Database.Password}},
+		{"DB_DATABASE_NAME", "database name, or the database file for sqlite", stringValue{&c.Database.Name}},
+		{"DB_SSLMODE", "postgres sslmode: disable, require, verify-ca or verify-full", stringValue{&c.Database.SSLMode}},
+		{"DB_LOCATION", "time zone used to read and write timestamp columns", stringValue{&c.Database.Location}},
+		{"DB_AUTO_MIGRATE", "apply pending migrations on start", boolValue{&c.Database.AutoMigrate}},
+		{"DB_TX_MAX_ATTEMPTS", "runs of a transaction that hit a deadlock or serialization failure, 1 disables retries", intValue{&c.Database.TxMaxAttempts}},
+		{"DB_MAX_OPEN_CONNS", "connections a pool opens at most, 0 for no limit", intValue{&c.Database.MaxOpenConns}},
+		{"DB_MAX_IDLE_CONNS", "idle connections a pool keeps, 0 keeps none", intValue{&c.Database.MaxIdleConns}},
+		{"DB_CONN_MAX_LIFETIME", "age after which a connection is closed, 0 keeps it forever", durationValue{&c.Database.ConnMaxLifetime}},
+		{"DB_CONN_MAX_IDLE_TIME", "idle time after which a connection is closed, 0 keeps it forever", durationValue{&c.Database.ConnMaxIdleTime}},
+		{"DB_CONNECT_TIMEOUT", "how long to retry reaching the primary on start before giving up", durationValue{&c.Database.ConnectTimeout}},
+		{"DB_QUERY_TIMEOUT", "time an account query may take before it is cancelled, 0 disables", durationValue{&c.Database.QueryTimeout}},
+		{"DB_SLOW_QUERY_THRESHOLD", "duration from which account queries are logged as slow, 0 disables", durationValue{&c.Database.SlowQueryThreshold}},
+		{"DB_REPLICA_DSNS", "comma separated DSNs of read replicas for account lookups", listValue{&c.Database.ReplicaDSNs}},
+		{"DB_REPLICA_MAX_LAG", "replication lag beyond which a replica stops serving reads", durationValue{&c.Database.ReplicaMaxLag}},
+		{"DB_REPLICA_CHECK_INTERVAL", "time between two replica health checks", durationValue{&c.Database.ReplicaCheckInterval}},
+
+		{"CACHE_BACKEND", "account cache in front of the database: none, memory or redis", stringValue{&c.Cache.Backend}},
+		{"CACHE_TTL", "how long a cached account is served", durationValue{&c.Cache.TTL}},
+		{"CACHE_SIZE", "accounts kept by the memory cache", intValue{&c.Cache.Size}},
+		{"CACHE_REDIS_ADDR", "host:port of the Redis-compatible server", stringValue{&c.Cache.RedisAddr}},
+		{"CACHE_REDIS_PASSWORD", "Redis password", stringValue{&c.Cache.RedisPassword}},
+		{"CACHE_REDIS_DB", "Redis database number", intValue{&c.Cache.RedisDB}},
+		{"CACHE_REDIS_TIMEOUT", "timeout of a single Redis command", durationValue{&c.Cache.RedisTimeout}},
+
+		{"BCRYPT_HASH_COST", "bcrypt cost for password hashes", intValue{&c.Bcrypt.HashCost}},
+
+		{"JWT_SECRET", "key that signs new tokens", stringValue{&c.Jwt.Secret}},
+		{"JWT_PREVIOUS_SECRETS", "comma separated keys still accepted for verification", listValue{&c.Jwt.PreviousSecrets}},
+
+		{"BASIC_AUTH_USERNAME", "user for the /admin endpoints", stringValue{&c.BasicAuth.Username}},
+		{"BASIC_AUTH_PASSWORD", "password for the /admin endpoints", stringValue{&c.BasicAuth.Password}},
+
+		{"OUTBOX_SINKS", "comma separated extra outbox sinks: stdout, webhook", listValue{&c.Outbox.Sinks}},
+		{"OUTBOX_WEBHOOK_URL", "URL for the webhook outbox sink", stringValue{&c.Outbox.WebhookURL}},
+		{"OUTBOX_POLL_INTERVAL", "outbox relay poll interval", durationValue{&c.Outbox.PollInterval}},
+		{"OUTBOX_BATCH_SIZE", "events relayed per poll", intValue{&c.Outbox.BatchSize}},
+		{"OUTBOX_MAX_ATTEMPTS", "delivery attempts before an outbox event is dead-lettered", intValue{&c.Outbox.MaxAttempts}},
+		{"OUTBOX_BACKOFF_BASE", "wait before the first retry of an outbox event, doubled on every failure", durationValue{&c.Outbox.BackoffBase}},
+		{"OUTBOX_BACKOFF_MAX", "longest wait between two attempts of an outbox event", durationValue{&c.Outbox.BackoffMax}},
+		{"OUTBOX_LEASE", "how long a relay owns a claimed batch before other relays may take it over", durationValue{&c.Outbox.Lease}},
+
+		{"WEBHOOK_MAX_ATTEMPTS", "delivery attempts before giving up", intValue{&c.Webhook.MaxAttempts}},
+		{"WEBHOOK_BACKOFF_BASE", "delay before the first retry", durationValue{&c.Webhook.BackoffBase}},
+		{"WEBHOOK_BACKOFF_MAX", "longest delay between retries", durationValue{&c.Webhook.BackoffMax}},
+		{"WEBHOOK_DISABLE_AFTER", "consecutive failures that disable a subscription", intValue{&c.Webhook.DisableAfter}},
+		{"WEBHOOK_TIMEOUT", "timeout of a single delivery", durationValue{&c.Webhook.Timeout}},
+		{"WEBHOOK_POLL_INTERVAL", "delivery worker poll interval", durationValue{&c.Webhook.PollInterval}},
+		{"WEBHOOK_BATCH_SIZE", "deliveries sent per poll", intValue{&c.Webhook.BatchSize}},
+	}
+}
+
+// loadFile overlays the YAML file at path. Unknown keys are rejected so a
+// typo does not silently fall back to the default.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadEnv applies every setting found in the environment. KEY_FILE takes
+// precedence over KEY and names a file holding the value, which is how
+// container secrets are usually mounted.
+func (c *Config) loadEnv(settings []setting) []string {
+	var problems []string
+
+	for _, s := range settings {
+		value, source, err := lookupEnv(s.env)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		if source == "" {
+			continue
+		}
+
+		err = s.value.Set(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", source, err))
+		}
+	}
+
+	return problems
+}
+
+// rawValue keeps a flag as given until loadFlags applies it on top of the
+// file and the environment. A bool setting can be given as a bare -flag.
+type rawValue struct {
+	value    string
+	boolFlag bool
+}
+
+func (v *rawValue) String() string {
+	if v == nil {
+		return ""
+	}
+
+	return v.value
+}
+
+func (v *rawValue) Set(s string) error {
+	v.value = s
+	return nil
+}
+
+func (v *rawValue) IsBoolFlag() bool { return v.boolFlag }
+
+func isBoolFlag(value flag.Value) bool {
+	b, ok := value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func (c *Config) loadFlags(flags *flag.FlagSet, settings []setting) []string {
+	var problems []string
+
+	byName := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byName[s.flag()] = s
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		s, ok := byName[f.Name]
+		if !ok {
+			return
+		}
+
+		err := s.value.Set(f.Value.String())
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("-%s: %v", f.Name, err))
+		}
+	})
+
+	return problems
+}
+
+// lookupEnv returns the value of key and the name of the variable it came
+// from, or an empty source when neither key nor key_FILE is set.
+func lookupEnv(key string) (value, source string, err error) {
+	if path := getenv(key + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("%s_FILE: %w", key, err)
+		}
+
+		return strings.TrimRight(string(data), "\r\n"), key + "_FILE", nil
+	}
+
+	if value := getenv(key); value != "" {
+		return value, key, nil
+	}
+
+	return "", "", nil
+}
+
+// getenv treats a variable that is set but blank, as in .env.example, the
+// same as an unset one.
+func getenv(key string) string {
+	return strings.TrimSpace(os.Getenv(key))
+}
+
+type stringValue struct{ p *string }
+
+func (v stringValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+
+	return *v.p
+}
+
+func (v stringValue) Set(s string) error {
+	*v.p = s
+	return nil
+}
+
+type intValue struct{ p *int }
+
+func (v intValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+
+	return strconv.Itoa(*v.p)
+}
+
+func (v intValue) Set(s string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", s)
+	}
+
+	*v.p = n
+	return nil
+}
+
+type boolValue struct{ p *bool }
+
+func (v boolValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+
+	return strconv.FormatBool(*v.p)
+}
+
+// IsBoolFlag lets -flag stand for -flag=true.
+func (boolValue) IsBoolFlag() bool { return true }
+
+func (v boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("%q is not a boolean", s)
+	}
+
+	*v.p = b
+	return nil
+}
+
+type floatValue struct{ p *float64 }
+
+func (v floatValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(*v.p, 'g', -1, 64)
+}
+
+func (v floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", s)
+	}
+
+	*v.p = f
+	return nil
+}
+
+type durationValue struct{ p *time.Duration }
+
+func (v durationValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+
+	return v.p.String()
+}
+
+func (v durationValue) Set(s string) error {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("%q is not a duration such as 30s or 5m", s)
+	}
+
+	*v.p = d
+	return nil
+}
+
+type listValue struct{ p *[]string }
+
+func (v listValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+
+	return strings.Join(*v.p, ",")
+}
+
+func (v listValue) Set(s string) error {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	*v.p = list
+	return nil
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+
+	"waizly/helpers/dialect"
+	"waizly/helpers/i18n"
+	"waizly/helpers/logger"
+)
+
+// Error lists every problem found while loading the configuration, so a
+// broken deployment is fixed in one pass instead of one restart per field.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+var (
+	defaultPorts = map[dialect.Dialect]int{
+		dialect.MySQL:    3306,
+		dialect.Postgres: 5432,
+	}
+
+	sslModes = []string{"disable", "require", "verify-ca", "verify-full"}
+)
+
+// validate checks every field and, when they are all valid, fills in the
+// values derived from them such as Database.DSN.
+func (c *Config) validate() []string {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(c.App.Port)
+	check(err == nil && port > 0 && port < 65536, "PORT must be a port number, got %q", c.App.Port)
+
+	check(c.Server.ReadTimeout > 0, "SERVER_READ_TIMEOUT must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "SERVER_READ_HEADER_TIMEOUT must be positive")
+	check(c.Server.WriteTimeout > 0, "SERVER_WRITE_TIMEOUT must be positive")
+	check(c.Server.IdleTimeout > 0, "SERVER_IDLE_TIMEOUT must be positive")
+	check(c.Server.ShutdownTimeout > 0, "SERVER_SHUTDOWN_TIMEOUT must be positive")
+	check(c.Server.ShutdownDelay >= 0, "SERVER_SHUTDOWN_DELAY must not be negative")
+
+	check(c.Health.CheckTimeout > 0, "HEALTH_CHECK_TIMEOUT must be positive")
+	check(c.Health.CacheTTL >= 0, "HEALTH_CACHE_TTL must not be negative")
+
+	_, err = logger.ParseLevel(c.Log.Level)
+	check(err == nil, "LOG_LEVEL must be debug, info, warn or error, got %q", c.Log.Level)
+	check(c.Log.Format == logger.FormatJSON || c.Log.Format == logger.FormatConsole,
+		"LOG_FORMAT must be json or console, got %q", c.Log.Format)
+
+	supported := i18n.Supported()
+	check(contains(supported, c.I18n.FallbackLocale), "I18N_FALLBACK_LOCALE must be one of %s, got %q",
+		strings.Join(supported, ", "), c.I18n.FallbackLocale)
+	if c.I18n.CatalogDir != "" {
+		info, err := os.Stat(c.I18n.CatalogDir)
+		check(err == nil && info.IsDir(), "I18N_CATALOG_DIR %q is not a directory", c.I18n.CatalogDir)
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		check(c.Tracing.OTLPEndpoint != "", "TRACING_OTLP_ENDPOINT is required for the otlp exporter")
+	default:
+		check(false, "TRACING_EXPORTER must be none, otlp or stdout, got %q", c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+
+	location, err := time.LoadLocation(c.Database.Location)
+	check(err == nil, "DB_LOCATION %q is not a known time zone", c.Database.Location)
+
+	driver, err := dialect.Parse(c.Database.Driver)
+	check(err == nil, "DB_DRIVER must be mysql, postgres or sqlite, got %q", c.Database.Driver)
+
+	check(c.Database.TxMaxAttempts > 0, "DB_TX_MAX_ATTEMPTS must be positive")
+	check(c.Database.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS")
+	check(c.Database.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "DB_CONN_MAX_IDLE_TIME must not be negative")
+	check(c.Database.ConnectTimeout > 0, "DB_CONNECT_TIMEOUT must be positive")
+	check(c.Database.QueryTimeout >= 0, "DB_QUERY_TIMEOUT must not be negative")
+	check(c.Database.SlowQueryThreshold >= 0, "DB_SLOW_QUERY_THRESHOLD must not be negative")
+
+	if c.Database.Port == 0 {
+		c.Database.Port = defaultPorts[driver]
+	}
+
+	if driver == dialect.Postgres {
+		check(contains(sslModes, c.Database.SSLMode), "DB_SSLMODE must be one of %s, got %q", strings.Join(sslModes, ", "), c.Database.SSLMode)
+	}
+
+	if len(c.Database.ReplicaDSNs) > 0 {
+		check(driver != dialect.SQLite, "DB_REPLICA_DSNS is not supported for sqlite")
+		check(c.Database.ReplicaMaxLag > 0, "DB_REPLICA_MAX_LAG must be positive")
+		check(c.Database.ReplicaCheckInterval > 0, "DB_REPLICA_CHECK_INTERVAL must be positive")
+	}
+
+	for i, dsn := range c.Database.ReplicaDSNs {
+		switch driver {
+		case dialect.MySQL:
+			parsed, err := mysql.ParseDSN(dsn)
+			check(err == nil, "DB_REPLICA_DSNS entry %d is invalid: %v", i+1, err)
+			check(err != nil || parsed.ParseTime, "DB_REPLICA_DSNS entry %d must set parseTime=true", i+1)
+		case dialect.Postgres:
+			_, err := pq.NewConnector(dsn)
+			check(err == nil, "DB_REPLICA_DSNS entry %d is invalid: %v", i+1, err)
+		}
+	}
+
+	if c.Database.DSN != "" {
+		switch driver {
+		case dialect.MySQL:
+			_, err = mysql.ParseDSN(c.Database.DSN)
+			check(err == nil, "DB_DSN is invalid: %v", err)
+		case dialect.Postgres:
+			_, err = pq.NewConnector(c.Database.DSN)
+			check(err == nil, "DB_DSN is invalid: %v", err)
+		}
+	} else if driver == dialect.SQLite {
+		check(c.Database.Name != "", "DB_DATABASE_NAME must be the database file for sqlite unless DB_DSN is set")
+	} else {
+		check(c.Database.Host != "", "DB_HOST is required unless DB_DSN is set")
+		check(c.Database.Username != "", "DB_USERNAME is required unless DB_DSN is set")
+		check(c.Database.Name != "", "DB_DATABASE_NAME is required unless DB_DSN is set")
+		check(c.Database.Port > 0 && c.Database.Port < 65536, "DB_PORT must be a port number, got %d", c.Database.Port)
+	}
+
+	switch c.Cache.Backend {
+	case "none":
+	case "memory":
+		check(c.Cache.Size > 0, "CACHE_SIZE must be positive")
+	case "redis":
+		check(c.Cache.RedisAddr != "", "CACHE_REDIS_ADDR is required for the redis cache")
+		check(c.Cache.RedisDB >= 0, "CACHE_REDIS_DB must not be negative")
+		check(c.Cache.RedisTimeout > 0, "CACHE_REDIS_TIMEOUT must be positive")
+	default:
+		check(false, "CACHE_BACKEND must be none, memory or redis, got %q", c.Cache.Backend)
+	}
+	check(c.Cache.TTL > 0, "CACHE_TTL must be positive")
+
+	check(c.Bcrypt.HashCost >= bcrypt.MinCost && c.Bcrypt.HashCost <= bcrypt.MaxCost,
+		"BCRYPT_HASH_COST must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, c.Bcrypt.HashCost)
+
+	check(len(c.Jwt.Secret) >= 16, "JWT_SECRET is required and must be at least 16 characters")
+	for _, secret := range c.Jwt.PreviousSecrets {
+		check(!strings.Contains(secret, ","), "JWT_PREVIOUS_SECRETS entries must not contain commas")
+	}
+
+	check((c.BasicAuth.Username == "") == (c.BasicAuth.Password == ""),
+		"BASIC_AUTH_USERNAME and BASIC_AUTH_PASSWORD must be set together")
+
+	for _, sink := range c.Outbox.Sinks {
+		switch sink {
+		case "stdout":
+		case "webhook":
+			check(isURL(c.Outbox.WebhookURL), "OUTBOX_WEBHOOK_URL must be an http(s) URL when the webhook sink is enabled")
+		default:
+			check(false, "OUTBOX_SINKS: unknown sink %q", sink)
+		}
+	}
+	check(c.Outbox.PollInterval > 0, "OUTBOX_POLL_INTERVAL must be positive")
+	check(c.Outbox.BatchSize > 0, "OUTBOX_BATCH_SIZE must be positive")
+	check(c.Outbox.MaxAttempts > 0, "OUTBOX_MAX_ATTEMPTS must be positive")
+	check(c.Outbox.BackoffBase > 0, "OUTBOX_BACKOFF_BASE must be positive")
+	check(c.Outbox.BackoffMax >= c.Outbox.BackoffBase, "OUTBOX_BACKOFF_MAX must not be shorter than OUTBOX_BACKOFF_BASE")
+	check(c.Outbox.Lease > 0, "OUTBOX_LEASE must be positive")
+
+	check(c.Webhook.MaxAttempts > 0, "WEBHOOK_MAX_ATTEMPTS must be positive")
+	check(c.Webhook.BackoffBase > 0, "WEBHOOK_BACKOFF_BASE must be positive")
+	check(c.Webhook.BackoffMax >= c.Webhook.BackoffBase, "WEBHOOK_BACKOFF_MAX must not be shorter than WEBHOOK_BACKOFF_BASE")
+	check(c.Webhook.DisableAfter > 0, "WEBHOOK_DISABLE_AFTER must be positive")
+	check(c.Webhook.Timeout > 0, "WEBHOOK_TIMEOUT must be positive")
+	check(c.Webhook.PollInterval > 0, "WEBHOOK_POLL_INTERVAL must be positive")
+	check(c.Webhook.BatchSize > 0, "WEBHOOK_BATCH_SIZE must be positive")
+
+	if len(problems) > 0 {
+		return problems
+	}
+
+	if c.Database.DSN == "" && driver == dialect.SQLite {
+		// Immediate transactions take the write lock up front, so two
+		// writers wait on busy_timeout instead of failing to upgrade.
+		c.Database.DSN = "file:" + c.Database.Name + "?" + url.Values{
+			"_pragma":      {"foreign_keys(1)", "busy_timeout(5000)"},
+			"_time_format": {"sqlite"},
+			"_txlock":      {"immediate"},
+		}.Encode()
+	}
+
+	if c.Database.DSN == "" && driver == dialect.Postgres {
+		dsn := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(c.Database.Username, c.Database.Password),
+			Host:   net.JoinHostPort(c.Database.Host, strconv.Itoa(c.Database.Port)),
+			Path:   "/" + c.Database.Name,
+			RawQuery: url.Values{
+				"sslmode":  {c.Database.SSLMode},
+				"timezone": {location.String()},
+			}.Encode(),
+		}
+
+		c.Database.DSN = dsn.String()
+	}
+
+	if c.Database.DSN == "" {
+		dsn := mysql.NewConfig()
+		dsn.User = c.Database.Username
+		dsn.Passwd = c.Database.Password
+		dsn.Net = "tcp"
+		dsn.Addr = net.JoinHostPort(c.Database.Host, strconv.Itoa(c.Database.Port))
+		dsn.DBName = c.Database.Name
+		dsn.ParseTime = true
+		dsn.Loc = location
+
+		c.Database.DSN = dsn.FormatDSN()
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -1 +1 @@
-DROP TABLE IF EXISTS accounts;
\ No newline at end of file
+DROP TABLE IF EXISTS `account`;
@@ -1,4 +1,4 @@
-CREATE TABLE `waizly`.`account` (
+CREATE TABLE `account` (
   `ID` INT NOT NULL AUTO_INCREMENT,
   `username` VARCHAR(255) NULL,
   `password` VARCHAR(255) NULL,
@@ -6,4 +6,4 @@ CREATE TABLE `waizly`.`account` (
   `created_at` DATETIME NULL DEFAULT (now()),
   `update_at` DATETIME NULL DEFAULT (now()),
   PRIMARY KEY (`ID`)
-);
\ No newline at end of file
+);
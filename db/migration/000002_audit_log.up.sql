@@ -0,0 +1,33 @@
+CREATE TABLE `audit_log` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `actor_id` BIGINT NOT NULL DEFAULT 0,
+  `subject_id` BIGINT NOT NULL DEFAULT 0,
+  `action` VARCHAR(64) NOT NULL,
+  `outcome` VARCHAR(16) NOT NULL,
+  `detail` VARCHAR(512) NOT NULL DEFAULT '',
+  `ip` VARCHAR(45) NOT NULL DEFAULT '',
+  `user_agent` VARCHAR(512) NOT NULL DEFAULT '',
+  `request_id` VARCHAR(128) NOT NULL DEFAULT '',
+  `occurred_at` DATETIME(6) NOT NULL,
+  `prev_hash` CHAR(64) NOT NULL,
+  `hash` CHAR(64) NOT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_audit_log_actor` (`actor_id`),
+  INDEX `idx_audit_log_subject` (`subject_id`),
+  INDEX `idx_audit_log_action` (`action`, `occurred_at`),
+  INDEX `idx_audit_log_request` (`request_id`)
+);
+
+CREATE TABLE `audit_log_head` (
+  `id` TINYINT NOT NULL,
+  `hash` CHAR(64) NOT NULL,
+  PRIMARY KEY (`id`)
+);
+
+INSERT INTO `audit_log_head` (`id`, `hash`) VALUES (1, '');
+
+CREATE TRIGGER `audit_log_no_update` BEFORE UPDATE ON `audit_log`
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+
+CREATE TRIGGER `audit_log_no_delete` BEFORE DELETE ON `audit_log`
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
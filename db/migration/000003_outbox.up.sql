@@ -0,0 +1,12 @@
+CREATE TABLE `outbox` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `event_type` VARCHAR(64) NOT NULL,
+  `aggregate_id` BIGINT NOT NULL,
+  `payload` JSON NOT NULL,
+  `occurred_at` DATETIME(6) NOT NULL,
+  `published_at` DATETIME(6) NULL,
+  `attempts` INT NOT NULL DEFAULT 0,
+  `last_error` VARCHAR(1024) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_outbox_pending` (`published_at`, `id`)
+);
@@ -0,0 +1,45 @@
+CREATE TABLE `webhook_subscription` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `url` VARCHAR(2048) NOT NULL,
+  `event_type` VARCHAR(64) NOT NULL,
+  `secret` VARCHAR(255) NOT NULL,
+  `active` TINYINT(1) NOT NULL DEFAULT 1,
+  `failure_count` INT NOT NULL DEFAULT 0,
+  `disabled_at` DATETIME NULL,
+  `created_at` DATETIME NOT NULL,
+  `update_at` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_webhook_subscription_event` (`active`, `event_type`)
+);
+
+CREATE TABLE `webhook_delivery` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `subscription_id` BIGINT NOT NULL,
+  `event_id` BIGINT NOT NULL,
+  `event_type` VARCHAR(64) NOT NULL,
+  `payload` JSON NOT NULL,
+  `status` VARCHAR(16) NOT NULL,
+  `attempts` INT NOT NULL DEFAULT 0,
+  `next_attempt_at` DATETIME NOT NULL,
+  `last_status_code` INT NOT NULL DEFAULT 0,
+  `last_error` VARCHAR(1024) NOT NULL DEFAULT '',
+  `created_at` DATETIME NOT NULL,
+  `update_at` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `uq_webhook_delivery_event` (`subscription_id`, `event_id`),
+  INDEX `idx_webhook_delivery_due` (`status`, `next_attempt_at`),
+  CONSTRAINT `fk_webhook_delivery_subscription` FOREIGN KEY (`subscription_id`) REFERENCES `webhook_subscription` (`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE `webhook_attempt` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `delivery_id` BIGINT NOT NULL,
+  `status_code` INT NOT NULL DEFAULT 0,
+  `response_body` TEXT NOT NULL,
+  `error` VARCHAR(1024) NOT NULL DEFAULT '',
+  `duration_ms` BIGINT NOT NULL DEFAULT 0,
+  `attempted_at` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_webhook_attempt_delivery` (`delivery_id`),
+  CONSTRAINT `fk_webhook_attempt_delivery` FOREIGN KEY (`delivery_id`) REFERENCES `webhook_delivery` (`id`) ON DELETE CASCADE
+);
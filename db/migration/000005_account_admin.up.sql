@@ -0,0 +1,4 @@
+ALTER TABLE `account`
+  ADD COLUMN `role` VARCHAR(32) NOT NULL DEFAULT 'user',
+  ADD COLUMN `disabled_at` DATETIME NULL,
+  ADD COLUMN `sessions_revoked_at` DATETIME NULL;
@@ -0,0 +1,19 @@
+-- Rows that stop 000006 from adding NOT NULL and the unique indexes. Fix or
+-- merge them by hand, then run the migration again.
+SELECT a.id, a.username, a.email,
+  CASE
+    WHEN a.username IS NULL THEN 'username is NULL'
+    WHEN a.email IS NULL THEN 'email is NULL'
+    WHEN EXISTS (SELECT 1 FROM `account` b WHERE b.id <> a.id
+      AND CONVERT(b.username USING utf8mb4) COLLATE utf8mb4_unicode_ci = CONVERT(a.username USING utf8mb4) COLLATE utf8mb4_unicode_ci)
+      THEN 'duplicate username'
+    ELSE 'duplicate email'
+  END AS problem
+FROM `account` a
+WHERE a.username IS NULL
+  OR a.email IS NULL
+  OR EXISTS (SELECT 1 FROM `account` b WHERE b.id <> a.id
+    AND CONVERT(b.username USING utf8mb4) COLLATE utf8mb4_unicode_ci = CONVERT(a.username USING utf8mb4) COLLATE utf8mb4_unicode_ci)
+  OR EXISTS (SELECT 1 FROM `account` b WHERE b.id <> a.id
+    AND CONVERT(b.email USING utf8mb4) COLLATE utf8mb4_unicode_ci = CONVERT(a.email USING utf8mb4) COLLATE utf8mb4_unicode_ci)
+ORDER BY a.id
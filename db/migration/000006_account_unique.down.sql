@@ -0,0 +1,5 @@
+ALTER TABLE `account`
+  DROP INDEX `uq_account_email`,
+  DROP INDEX `uq_account_username`,
+  MODIFY `email` VARCHAR(255) NULL,
+  MODIFY `username` VARCHAR(255) NULL;
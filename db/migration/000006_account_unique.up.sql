@@ -0,0 +1,5 @@
+ALTER TABLE `account`
+  MODIFY `username` VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL,
+  MODIFY `email` VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL,
+  ADD UNIQUE INDEX `uq_account_username` (`username`),
+  ADD UNIQUE INDEX `uq_account_email` (`email`);
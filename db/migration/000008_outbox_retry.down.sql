@@ -0,0 +1,3 @@
+ALTER TABLE `outbox`
+  DROP COLUMN `next_attempt_at`,
+  DROP COLUMN `dead_at`;
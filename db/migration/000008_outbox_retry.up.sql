@@ -0,0 +1,3 @@
+ALTER TABLE `outbox`
+  ADD COLUMN `next_attempt_at` DATETIME(6) NULL,
+  ADD COLUMN `dead_at` DATETIME(6) NULL;
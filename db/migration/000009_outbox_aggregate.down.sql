@@ -0,0 +1,2 @@
+ALTER TABLE `outbox`
+  DROP INDEX `idx_outbox_aggregate`;
@@ -0,0 +1,2 @@
+ALTER TABLE `outbox`
+  ADD INDEX `idx_outbox_aggregate` (`aggregate_id`, `id`);
@@ -0,0 +1,30 @@
+// Package migration embeds the SQL migrations so the binaries can apply them
+// without the source tree.
+package migration
+
+import (
+	"embed"
+	"io/fs"
+
+	"waizly/helpers/dialect"
+)
+
+// FS holds the MySQL migrations.
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed postgres/*.sql sqlite/*.sql
+var dialectFS embed.FS
+
+// For returns the migrations written for d. Every set shares version numbers
+// and names so status output reads the same on any database.
+func For(d dialect.Dialect) fs.FS {
+	if d == dialect.MySQL {
+		return FS
+	}
+
+	sub, _ := fs.Sub(dialectFS, string(d))
+
+	return sub
+}
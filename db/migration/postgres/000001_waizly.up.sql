@@ -0,0 +1,9 @@
+CREATE TABLE account (
+  id BIGSERIAL NOT NULL,
+  username VARCHAR(255) NULL,
+  password VARCHAR(255) NULL,
+  email VARCHAR(255) NULL,
+  created_at TIMESTAMPTZ NULL DEFAULT now(),
+  update_at TIMESTAMPTZ NULL DEFAULT now(),
+  PRIMARY KEY (id)
+);
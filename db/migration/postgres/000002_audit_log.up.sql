@@ -0,0 +1,40 @@
+CREATE TABLE audit_log (
+  id BIGSERIAL NOT NULL,
+  actor_id BIGINT NOT NULL DEFAULT 0,
+  subject_id BIGINT NOT NULL DEFAULT 0,
+  action VARCHAR(64) NOT NULL,
+  outcome VARCHAR(16) NOT NULL,
+  detail VARCHAR(512) NOT NULL DEFAULT '',
+  ip VARCHAR(45) NOT NULL DEFAULT '',
+  user_agent VARCHAR(512) NOT NULL DEFAULT '',
+  request_id VARCHAR(128) NOT NULL DEFAULT '',
+  occurred_at TIMESTAMPTZ NOT NULL,
+  prev_hash CHAR(64) NOT NULL,
+  hash CHAR(64) NOT NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_audit_log_actor ON audit_log (actor_id);
+CREATE INDEX idx_audit_log_subject ON audit_log (subject_id);
+CREATE INDEX idx_audit_log_action ON audit_log (action, occurred_at);
+CREATE INDEX idx_audit_log_request ON audit_log (request_id);
+
+CREATE TABLE audit_log_head (
+  id SMALLINT NOT NULL,
+  hash CHAR(64) NOT NULL,
+  PRIMARY KEY (id)
+);
+
+INSERT INTO audit_log_head (id, hash) VALUES (1, '');
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only' USING ERRCODE = '45000';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
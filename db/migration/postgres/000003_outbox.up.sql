@@ -0,0 +1,13 @@
+CREATE TABLE outbox (
+  id BIGSERIAL NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  aggregate_id BIGINT NOT NULL,
+  payload JSONB NOT NULL,
+  occurred_at TIMESTAMPTZ NOT NULL,
+  published_at TIMESTAMPTZ NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  last_error VARCHAR(1024) NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_outbox_pending ON outbox (published_at, id);
@@ -0,0 +1,48 @@
+CREATE TABLE webhook_subscription (
+  id BIGSERIAL NOT NULL,
+  url VARCHAR(2048) NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  secret VARCHAR(255) NOT NULL,
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  failure_count INT NOT NULL DEFAULT 0,
+  disabled_at TIMESTAMPTZ NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  update_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_webhook_subscription_event ON webhook_subscription (active, event_type);
+
+CREATE TABLE webhook_delivery (
+  id BIGSERIAL NOT NULL,
+  subscription_id BIGINT NOT NULL,
+  event_id BIGINT NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  payload JSONB NOT NULL,
+  status VARCHAR(16) NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL,
+  last_status_code INT NOT NULL DEFAULT 0,
+  last_error VARCHAR(1024) NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL,
+  update_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (id),
+  CONSTRAINT uq_webhook_delivery_event UNIQUE (subscription_id, event_id),
+  CONSTRAINT fk_webhook_delivery_subscription FOREIGN KEY (subscription_id) REFERENCES webhook_subscription (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_delivery_due ON webhook_delivery (status, next_attempt_at);
+
+CREATE TABLE webhook_attempt (
+  id BIGSERIAL NOT NULL,
+  delivery_id BIGINT NOT NULL,
+  status_code INT NOT NULL DEFAULT 0,
+  response_body TEXT NOT NULL,
+  error VARCHAR(1024) NOT NULL DEFAULT '',
+  duration_ms BIGINT NOT NULL DEFAULT 0,
+  attempted_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (id),
+  CONSTRAINT fk_webhook_attempt_delivery FOREIGN KEY (delivery_id) REFERENCES webhook_delivery (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_attempt_delivery ON webhook_attempt (delivery_id);
@@ -0,0 +1,4 @@
+ALTER TABLE account
+  DROP COLUMN sessions_revoked_at,
+  DROP COLUMN disabled_at,
+  DROP COLUMN role;
@@ -0,0 +1,4 @@
+ALTER TABLE account
+  ADD COLUMN role VARCHAR(32) NOT NULL DEFAULT 'user',
+  ADD COLUMN disabled_at TIMESTAMPTZ NULL,
+  ADD COLUMN sessions_revoked_at TIMESTAMPTZ NULL;
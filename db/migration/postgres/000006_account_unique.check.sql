@@ -0,0 +1,16 @@
+-- Rows that stop 000006 from adding NOT NULL and the unique indexes. Fix or
+-- merge them by hand, then run the migration again.
+SELECT a.id, a.username, a.email,
+  CASE
+    WHEN a.username IS NULL THEN 'username is NULL'
+    WHEN a.email IS NULL THEN 'email is NULL'
+    WHEN EXISTS (SELECT 1 FROM account b WHERE b.id <> a.id AND lower(b.username) = lower(a.username))
+      THEN 'duplicate username'
+    ELSE 'duplicate email'
+  END AS problem
+FROM account a
+WHERE a.username IS NULL
+  OR a.email IS NULL
+  OR EXISTS (SELECT 1 FROM account b WHERE b.id <> a.id AND lower(b.username) = lower(a.username))
+  OR EXISTS (SELECT 1 FROM account b WHERE b.id <> a.id AND lower(b.email) = lower(a.email))
+ORDER BY a.id
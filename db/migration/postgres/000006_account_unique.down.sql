@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS uq_account_email;
+DROP INDEX IF EXISTS uq_account_username;
+
+ALTER TABLE account
+  ALTER COLUMN email DROP NOT NULL,
+  ALTER COLUMN username DROP NOT NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE account
+  ALTER COLUMN username SET NOT NULL,
+  ALTER COLUMN email SET NOT NULL;
+
+CREATE UNIQUE INDEX uq_account_username ON account (lower(username));
+CREATE UNIQUE INDEX uq_account_email ON account (lower(email));
@@ -0,0 +1 @@
+ALTER TABLE account ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
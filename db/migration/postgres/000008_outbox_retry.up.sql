@@ -0,0 +1,3 @@
+ALTER TABLE outbox
+  ADD COLUMN next_attempt_at TIMESTAMPTZ NULL,
+  ADD COLUMN dead_at TIMESTAMPTZ NULL;
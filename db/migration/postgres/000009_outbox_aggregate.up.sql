@@ -0,0 +1 @@
+CREATE INDEX idx_outbox_aggregate ON outbox (aggregate_id, id);
@@ -0,0 +1,8 @@
+CREATE TABLE account (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  username VARCHAR(255) NULL,
+  password VARCHAR(255) NULL,
+  email VARCHAR(255) NULL,
+  created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+  update_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP
+);
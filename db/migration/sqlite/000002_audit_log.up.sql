@@ -0,0 +1,36 @@
+CREATE TABLE audit_log (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  actor_id BIGINT NOT NULL DEFAULT 0,
+  subject_id BIGINT NOT NULL DEFAULT 0,
+  action VARCHAR(64) NOT NULL,
+  outcome VARCHAR(16) NOT NULL,
+  detail VARCHAR(512) NOT NULL DEFAULT '',
+  ip VARCHAR(45) NOT NULL DEFAULT '',
+  user_agent VARCHAR(512) NOT NULL DEFAULT '',
+  request_id VARCHAR(128) NOT NULL DEFAULT '',
+  occurred_at DATETIME NOT NULL,
+  prev_hash CHAR(64) NOT NULL,
+  hash CHAR(64) NOT NULL
+);
+
+CREATE INDEX idx_audit_log_actor ON audit_log (actor_id);
+CREATE INDEX idx_audit_log_subject ON audit_log (subject_id);
+CREATE INDEX idx_audit_log_action ON audit_log (action, occurred_at);
+CREATE INDEX idx_audit_log_request ON audit_log (request_id);
+
+CREATE TABLE audit_log_head (
+  id TINYINT NOT NULL PRIMARY KEY,
+  hash CHAR(64) NOT NULL
+);
+
+INSERT INTO audit_log_head (id, hash) VALUES (1, '');
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+  SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+  SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
@@ -0,0 +1,12 @@
+CREATE TABLE outbox (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  event_type VARCHAR(64) NOT NULL,
+  aggregate_id BIGINT NOT NULL,
+  payload TEXT NOT NULL,
+  occurred_at DATETIME NOT NULL,
+  published_at DATETIME NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  last_error VARCHAR(1024) NULL
+);
+
+CREATE INDEX idx_outbox_pending ON outbox (published_at, id);
@@ -0,0 +1,43 @@
+CREATE TABLE webhook_subscription (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  url VARCHAR(2048) NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  secret VARCHAR(255) NOT NULL,
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  failure_count INT NOT NULL DEFAULT 0,
+  disabled_at DATETIME NULL,
+  created_at DATETIME NOT NULL,
+  update_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_webhook_subscription_event ON webhook_subscription (active, event_type);
+
+CREATE TABLE webhook_delivery (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  subscription_id BIGINT NOT NULL REFERENCES webhook_subscription (id) ON DELETE CASCADE,
+  event_id BIGINT NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  payload TEXT NOT NULL,
+  status VARCHAR(16) NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at DATETIME NOT NULL,
+  last_status_code INT NOT NULL DEFAULT 0,
+  last_error VARCHAR(1024) NOT NULL DEFAULT '',
+  created_at DATETIME NOT NULL,
+  update_at DATETIME NOT NULL,
+  UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX idx_webhook_delivery_due ON webhook_delivery (status, next_attempt_at);
+
+CREATE TABLE webhook_attempt (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  delivery_id BIGINT NOT NULL REFERENCES webhook_delivery (id) ON DELETE CASCADE,
+  status_code INT NOT NULL DEFAULT 0,
+  response_body TEXT NOT NULL,
+  error VARCHAR(1024) NOT NULL DEFAULT '',
+  duration_ms BIGINT NOT NULL DEFAULT 0,
+  attempted_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_webhook_attempt_delivery ON webhook_attempt (delivery_id);
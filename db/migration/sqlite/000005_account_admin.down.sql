@@ -0,0 +1,3 @@
+ALTER TABLE account DROP COLUMN sessions_revoked_at;
+ALTER TABLE account DROP COLUMN disabled_at;
+ALTER TABLE account DROP COLUMN role;
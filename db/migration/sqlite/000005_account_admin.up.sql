@@ -0,0 +1,3 @@
+ALTER TABLE account ADD COLUMN role VARCHAR(32) NOT NULL DEFAULT 'user';
+ALTER TABLE account ADD COLUMN disabled_at DATETIME NULL;
+ALTER TABLE account ADD COLUMN sessions_revoked_at DATETIME NULL;
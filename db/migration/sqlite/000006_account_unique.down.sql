@@ -0,0 +1,18 @@
+CREATE TABLE account_old (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  username VARCHAR(255) NULL,
+  password VARCHAR(255) NULL,
+  email VARCHAR(255) NULL,
+  created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+  update_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+  role VARCHAR(32) NOT NULL DEFAULT 'user',
+  disabled_at DATETIME NULL,
+  sessions_revoked_at DATETIME NULL
+);
+
+INSERT INTO account_old (id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at)
+SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at FROM account;
+
+DROP TABLE account;
+
+ALTER TABLE account_old RENAME TO account;
@@ -0,0 +1,20 @@
+CREATE TABLE account_new (
+  id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  username VARCHAR(255) NOT NULL COLLATE NOCASE,
+  password VARCHAR(255) NULL,
+  email VARCHAR(255) NOT NULL COLLATE NOCASE,
+  created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+  update_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+  role VARCHAR(32) NOT NULL DEFAULT 'user',
+  disabled_at DATETIME NULL,
+  sessions_revoked_at DATETIME NULL,
+  CONSTRAINT uq_account_username UNIQUE (username),
+  CONSTRAINT uq_account_email UNIQUE (email)
+);
+
+INSERT INTO account_new (id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at)
+SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at FROM account;
+
+DROP TABLE account;
+
+ALTER TABLE account_new RENAME TO account;
@@ -0,0 +1 @@
+ALTER TABLE account ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+ALTER TABLE outbox ADD COLUMN next_attempt_at DATETIME NULL;
+
+ALTER TABLE outbox ADD COLUMN dead_at DATETIME NULL;
@@ -0,0 +1,59 @@
+// Package cache keeps short-lived copies of values that are expensive to
+// load, either in process memory or in a server speaking the Redis protocol.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache maps keys to opaque values that expire after a TTL. Get reports a
+// missing or expired key with ok set to false; an error means the cache
+// itself could not be used and the caller should fall back to the source.
+type Cache interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Options selects and configures the Cache returned by Open.
+type Options struct {
+	// Backend is none, memory or redis.
+	Backend       string
+	Size          int
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+	RedisTimeout  time.Duration
+	// Prefix is put in front of every Redis key.
+	Prefix string
+}
+
+// Open returns the Cache named by opts.Backend, or nil for none, along with
+// a function that releases its connections.
+func Open(opts Options) (Cache, func() error, error) {
+	noop := func() error { return nil }
+
+	switch opts.Backend {
+	case "", "none":
+		return nil, noop, nil
+	case "memory":
+		return NewLRU(opts.Size), noop, nil
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:         opts.RedisAddr,
+			Password:     opts.RedisPassword,
+			DB:           opts.RedisDB,
+			DialTimeout:  opts.RedisTimeout,
+			ReadTimeout:  opts.RedisTimeout,
+			WriteTimeout: opts.RedisTimeout,
+		})
+
+		return NewRedis(client, opts.Prefix), client.Close, nil
+	default:
+		return nil, noop, fmt.Errorf("unknown cache backend %q", opts.Backend)
+	}
+}
@@ -0,0 +1,105 @@
+package cache_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/cache"
+)
+
+// run checks the behaviour every Cache shares. advance lets the entries of c
+// age by d.
+func run(t *testing.T, c cache.Cache, advance func(d time.Duration)) {
+	ctx := context.Background()
+
+	t.Run("Get Set Delete", func(t *testing.T) {
+		_, ok, err := c.Get(ctx, "missing")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		value := []byte("jane")
+		assert.NoError(t, c.Set(ctx, "a", value, time.Minute))
+		value[0] = 'J'
+
+		got, ok, err := c.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, []byte("jane"), got, "the cache keeps its own copy")
+
+		assert.NoError(t, c.Set(ctx, "a", []byte("john"), time.Minute))
+		got, _, _ = c.Get(ctx, "a")
+		assert.Equal(t, []byte("john"), got)
+
+		assert.NoError(t, c.Delete(ctx, "a"))
+		assert.NoError(t, c.Delete(ctx, "a"), "deleting a missing key is not an error")
+
+		_, ok, err = c.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Expires", func(t *testing.T) {
+		assert.NoError(t, c.Set(ctx, "short", []byte("x"), 20*time.Millisecond))
+		assert.NoError(t, c.Set(ctx, "long", []byte("y"), time.Minute))
+
+		advance(50 * time.Millisecond)
+
+		_, ok, _ := c.Get(ctx, "short")
+		assert.False(t, ok)
+
+		_, ok, _ = c.Get(ctx, "long")
+		assert.True(t, ok)
+	})
+}
+
+func TestLRU(t *testing.T) {
+	run(t, cache.NewLRU(100), time.Sleep)
+
+	t.Run("Evicts Least Recently Used", func(t *testing.T) {
+		ctx := context.Background()
+		c := cache.NewLRU(3)
+
+		for i := 1; i <= 3; i++ {
+			c.Set(ctx, strconv.Itoa(i), []byte{byte(i)}, time.Minute)
+		}
+
+		// Reading 1 makes 2 the least recently used entry.
+		_, ok, _ := c.Get(ctx, "1")
+		assert.True(t, ok)
+
+		c.Set(ctx, "4", []byte{4}, time.Minute)
+
+		for key, want := range map[string]bool{"1": true, "2": false, "3": true, "4": true} {
+			_, ok, _ := c.Get(ctx, key)
+			assert.Equal(t, want, ok, "key %s", key)
+		}
+	})
+}
+
+func TestRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	c := cache.NewRedis(client, "test:")
+	run(t, c, server.FastForward)
+
+	t.Run("Prefix", func(t *testing.T) {
+		assert.NoError(t, c.Set(context.Background(), "a", []byte("x"), time.Minute))
+		assert.True(t, server.Exists("test:a"))
+	})
+
+	t.Run("Server Down", func(t *testing.T) {
+		server.Close()
+
+		_, ok, err := c.Get(context.Background(), "a")
+		assert.Error(t, err)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	lruCache struct {
+		mu      sync.Mutex
+		size    int
+		entries map[string]*list.Element
+		// order holds the entries from the most to the least recently used.
+		order *list.List
+	}
+
+	lruEntry struct {
+		key       string
+		value     []byte
+		expiresAt time.Time
+	}
+)
+
+// NewLRU returns a Cache held in memory that keeps at most size entries,
+// evicting the least recently used one to make room. Values are copied in
+// and out, so callers may reuse their buffers.
+func NewLRU(size int) Cache {
+	return &lruCache{
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+func (c *lruCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		c.remove(element)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(element)
+
+	return clone(entry.value), true, nil
+}
+
+func (c *lruCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if c.size <= 0 || ttl <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{key: key, value: clone(value), expiresAt: time.Now().Add(ttl)}
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	for c.order.Len() >= c.size {
+		c.remove(c.order.Back())
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+
+	return nil
+}
+
+func (c *lruCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	return nil
+}
+
+func (c *lruCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry).key)
+}
+
+func clone(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
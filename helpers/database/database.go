@@ -0,0 +1,78 @@
+// Package database opens connection pools and waits for the database behind
+// them to accept connections.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"waizly/helpers/logger"
+)
+
+const (
+	firstRetry = 100 * time.Millisecond
+	maxRetry   = 5 * time.Second
+)
+
+// Options sizes a connection pool. Zero values keep the database/sql
+// defaults, except MaxIdleConns where zero keeps no idle connection.
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// Open returns a pool for dsn configured with opts. Like sql.Open it does
+// not connect; call Wait to find out whether the database is reachable.
+func Open(driver, dsn string, opts Options) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	return db, nil
+}
+
+// Wait pings db until it answers, doubling the pause between attempts, and
+// gives up after timeout so a wrong host or password stops the process at
+// start instead of failing the first request.
+func Wait(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	wait := firstRetry
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		// Jitter keeps instances started together from retrying in step.
+		pause := wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+		deadline, _ := ctx.Deadline()
+		if time.Until(deadline) < pause {
+			return fmt.Errorf("database not reachable after %d attempts: %w", attempt, err)
+		}
+
+		logger.Ctx(ctx).Warn("database not reachable", "attempt", attempt, "retry_in", pause.String(), "error", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not reachable after %d attempts: %w", attempt, err)
+		case <-time.After(pause):
+		}
+
+		if wait *= 2; wait > maxRetry {
+			wait = maxRetry
+		}
+	}
+}
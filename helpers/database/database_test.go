@@ -0,0 +1,48 @@
+package database_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+
+	"waizly/helpers/database"
+	"waizly/helpers/logger"
+)
+
+func TestOpen(t *testing.T) {
+	db, err := database.Open("sqlite", ":memory:", database.Options{
+		MaxOpenConns:    4,
+		MaxIdleConns:    2,
+		ConnMaxLifetime: time.Minute,
+		ConnMaxIdleTime: time.Second,
+	})
+	assert.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, 4, db.Stats().MaxOpenConnections)
+	assert.NoError(t, database.Wait(context.Background(), db, time.Second))
+}
+
+func TestWait(t *testing.T) {
+	var out bytes.Buffer
+	logger.SetDefault(logger.New(&out, logger.FormatJSON, logger.LevelInfo))
+	defer logger.SetDefault(logger.New(&bytes.Buffer{}, logger.FormatJSON, logger.LevelInfo))
+
+	missing := filepath.Join(t.TempDir(), "missing", "waizly.db")
+	db, err := database.Open("sqlite", "file:"+missing+"?mode=rw", database.Options{})
+	assert.NoError(t, err)
+	defer db.Close()
+
+	started := time.Now()
+	err = database.Wait(context.Background(), db, 500*time.Millisecond)
+
+	assert.ErrorContains(t, err, "database not reachable")
+	assert.Less(t, time.Since(started), time.Second)
+	assert.GreaterOrEqual(t, strings.Count(out.String(), `"msg":"database not reachable"`), 2, "every failed attempt is logged")
+}
@@ -0,0 +1,226 @@
+package dialect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"waizly/helpers/transaction"
+)
+
+// Dialect names a supported SQL database and smooths over the few places
+// where the repositories' SQL differs between them. Queries are written
+// with ? placeholders and MySQL syntax, then adapted by the dialect.
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+func Parse(name string) (Dialect, error) {
+	switch d := Dialect(name); d {
+	case MySQL, Postgres, SQLite:
+		return d, nil
+	default:
+		return "", fmt.Errorf("unsupported database driver %q", name)
+	}
+}
+
+// Rebind rewrites ? placeholders to the dialect's syntax. Question marks
+// inside quoted literals are left alone.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	var quote byte
+	n := 0
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+// InsertIgnore turns an INSERT INTO statement into one that skips rows
+// violating a unique key instead of failing.
+func (d Dialect) InsertIgnore(query string) string {
+	switch d {
+	case Postgres:
+		return query + " ON CONFLICT DO NOTHING"
+	case SQLite:
+		return strings.Replace(query, "INSERT INTO", "INSERT OR IGNORE INTO", 1)
+	default:
+		return strings.Replace(query, "INSERT INTO", "INSERT IGNORE INTO", 1)
+	}
+}
+
+// Lock drops a trailing FOR UPDATE clause where row locks are not
+// supported. SQLite allows one writer at a time, so the transaction that
+// runs the query is already exclusive once it writes.
+func (d Dialect) Lock(query string) string {
+	if d != SQLite {
+		return query
+	}
+
+	if i := strings.LastIndex(query, " FOR UPDATE"); i >= 0 {
+		return query[:i]
+	}
+
+	return query
+}
+
+// InsertID runs an INSERT and returns the generated id column, using
+// RETURNING where LastInsertId is not supported.
+func (d Dialect) InsertID(ctx context.Context, conn transaction.DBTX, query string, args ...interface{}) (int64, error) {
+	if d == Postgres {
+		var id int64
+		err := conn.QueryRowContext(ctx, d.Rebind(query)+" RETURNING id", args...).Scan(&id)
+
+		return id, err
+	}
+
+	result, err := conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// Retryable reports whether err aborted a transaction that may succeed when
+// run again: a deadlock or serialization failure, or for SQLite a write lock
+// still held when busy_timeout ran out.
+func (d Dialect) Retryable(err error) bool {
+	switch d {
+	case Postgres:
+		var pqErr *pq.Error
+		return errors.As(err, &pqErr) && (pqErr.Code == "40001" || pqErr.Code == "40P01")
+	case SQLite:
+		var sqliteErr *sqlite.Error
+		return errors.As(err, &sqliteErr) && sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY
+	default:
+		var mysqlErr *mysql.MySQLError
+		return errors.As(err, &mysqlErr) && mysqlErr.Number == 1213
+	}
+}
+
+// ErrNotReplicating is returned by ReplicaLag for a server that is not
+// applying changes from a primary, or has stopped doing so.
+var ErrNotReplicating = errors.New("server is not replicating")
+
+// ReplicaLag reports how far the replica behind db trails its primary.
+func (d Dialect) ReplicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	switch d {
+	case Postgres:
+		// Comparing the received and replayed positions keeps an idle
+		// primary from looking like lag.
+		var standby bool
+		var seconds float64
+		err := db.QueryRowContext(ctx, `SELECT pg_is_in_recovery(),
+			CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END`).Scan(&standby, &seconds)
+		if err != nil {
+			return 0, err
+		}
+
+		if !standby {
+			return 0, ErrNotReplicating
+		}
+
+		return time.Duration(seconds * float64(time.Second)), nil
+	case SQLite:
+		return 0, ErrNotReplicating
+	default:
+		return mysqlReplicaLag(ctx, db)
+	}
+}
+
+// mysqlReplicaLag reads Seconds_Behind_Source, called Seconds_Behind_Master
+// before MySQL 8.0.22 together with the SHOW SLAVE STATUS statement.
+func mysqlReplicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1064 {
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+
+		return 0, ErrNotReplicating
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	err = rows.Scan(dest...)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+
+		// NULL while the replication threads are stopped.
+		if !values[i].Valid {
+			return 0, ErrNotReplicating
+		}
+
+		seconds, err := strconv.ParseInt(values[i].String, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", column, err)
+		}
+
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return 0, errors.New("replica status has no Seconds_Behind_Source column")
+}
@@ -0,0 +1,136 @@
+package dialect_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/dialect"
+	"waizly/helpers/exception"
+	"waizly/internal/mock"
+)
+
+func TestParse(t *testing.T) {
+	d, err := dialect.Parse("postgres")
+
+	assert.NoError(t, err)
+	assert.Equal(t, dialect.Postgres, d)
+
+	_, err = dialect.Parse("oracle")
+
+	assert.Error(t, err)
+}
+
+func TestRebind(t *testing.T) {
+	query := `SELECT id FROM t WHERE a = ? AND b IN (?, '?') AND c = "?"`
+
+	assert.Equal(t, query, dialect.MySQL.Rebind(query))
+	assert.Equal(t, `SELECT id FROM t WHERE a = $1 AND b IN ($2, '?') AND c = "?"`, dialect.Postgres.Rebind(query))
+}
+
+func TestInsertIgnore(t *testing.T) {
+	query := "INSERT INTO t (a) VALUES (?)"
+
+	assert.Equal(t, "INSERT IGNORE INTO t (a) VALUES (?)", dialect.MySQL.InsertIgnore(query))
+	assert.Equal(t, "INSERT INTO t (a) VALUES (?) ON CONFLICT DO NOTHING", dialect.Postgres.InsertIgnore(query))
+	assert.Equal(t, "INSERT OR IGNORE INTO t (a) VALUES (?)", dialect.SQLite.InsertIgnore(query))
+}
+
+func TestLock(t *testing.T) {
+	query := "SELECT id FROM t ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED"
+
+	assert.Equal(t, query, dialect.MySQL.Lock(query))
+	assert.Equal(t, "SELECT id FROM t ORDER BY id LIMIT ?", dialect.SQLite.Lock(query))
+}
+
+func TestRetryable(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+	assert.True(t, dialect.MySQL.Retryable(deadlock))
+	assert.True(t, dialect.MySQL.Retryable(exception.Wrap("AccountRepository.Update", exception.ErrInternalServer, deadlock)), "the cause must be found through wrapping")
+	assert.False(t, dialect.MySQL.Retryable(&mysql.MySQLError{Number: 1062}))
+	assert.False(t, dialect.MySQL.Retryable(errors.New("deadlock")))
+
+	assert.True(t, dialect.Postgres.Retryable(&pq.Error{Code: "40001"}))
+	assert.True(t, dialect.Postgres.Retryable(fmt.Errorf("commit: %w", &pq.Error{Code: "40P01"})))
+	assert.False(t, dialect.Postgres.Retryable(&pq.Error{Code: "23505"}))
+
+	t.Run("SQLite Busy", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "busy.db")
+		open := func() *sql.DB {
+			db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(0)&_txlock=immediate")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+
+			return db
+		}
+
+		holder, err := open().BeginTx(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer holder.Rollback()
+
+		_, err = open().BeginTx(context.Background(), nil)
+
+		assert.True(t, dialect.SQLite.Retryable(err), "got %v", err)
+		assert.False(t, dialect.SQLite.Retryable(errors.New("database is locked")))
+	})
+}
+
+func TestReplicaLag(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("MySQL", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		defer db.Close()
+
+		mock.ExpectQuery("SHOW REPLICA STATUS").WillReturnRows(
+			sqlmock.NewRows([]string{"Replica_IO_State", "Seconds_Behind_Source"}).AddRow("Waiting for source", "3"))
+		mock.ExpectQuery("SHOW REPLICA STATUS").WillReturnError(&mysql.MySQLError{Number: 1064})
+		mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(
+			sqlmock.NewRows([]string{"Slave_IO_State", "Seconds_Behind_Master"}).AddRow("", nil))
+		mock.ExpectQuery("SHOW REPLICA STATUS").WillReturnRows(sqlmock.NewRows([]string{"Seconds_Behind_Source"}))
+
+		lag, err := dialect.MySQL.ReplicaLag(ctx, db)
+		assert.NoError(t, err)
+		assert.Equal(t, 3*time.Second, lag)
+
+		_, err = dialect.MySQL.ReplicaLag(ctx, db)
+		assert.ErrorIs(t, err, dialect.ErrNotReplicating, "stopped replication")
+
+		_, err = dialect.MySQL.ReplicaLag(ctx, db)
+		assert.ErrorIs(t, err, dialect.ErrNotReplicating, "not a replica")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		defer db.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_is_in_recovery()")).WillReturnRows(sqlmock.NewRows([]string{"standby", "lag"}).AddRow(true, 1.5))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_is_in_recovery()")).WillReturnRows(sqlmock.NewRows([]string{"standby", "lag"}).AddRow(false, 0))
+
+		lag, err := dialect.Postgres.ReplicaLag(ctx, db)
+		assert.NoError(t, err)
+		assert.Equal(t, 1500*time.Millisecond, lag)
+
+		_, err = dialect.Postgres.ReplicaLag(ctx, db)
+		assert.ErrorIs(t, err, dialect.ErrNotReplicating)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
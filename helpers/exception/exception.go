@@ -8,6 +8,16 @@ var (
 	ErrNotFound       = fmt.Errorf("not found error")
 	ErrBadRequest     = fmt.Errorf("bad request")
 	ErrUnauthorized   = fmt.Errorf("unauthorized")
+	ErrForbidden      = fmt.Errorf("forbidden")
 	ErrNotPremium     = fmt.Errorf("not premium user")
 	ErrParams         = fmt.Errorf("error get params")
+	ErrTimeout        = fmt.Errorf("timeout")
+	ErrUnavailable    = fmt.Errorf("service unavailable")
+
+	ErrPreconditionFailed   = fmt.Errorf("precondition failed")
+	ErrPreconditionRequired = fmt.Errorf("precondition required")
+
+	// ErrVersionMismatch is the cause of an ErrConflicted write that found
+	// the row at another version than the caller read.
+	ErrVersionMismatch = fmt.Errorf("version mismatch")
 )
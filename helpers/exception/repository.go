@@ -0,0 +1,63 @@
+package exception
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+)
+
+// RepositoryError is a failed repository call. Kind is one of ErrNotFound,
+// ErrConflicted, ErrTimeout, ErrUnavailable or ErrInternalServer and is what
+// errors.Is matches, while Err keeps the driver error for logs and
+// errors.As.
+type RepositoryError struct {
+	Op   string
+	Kind error
+	Err  error
+}
+
+// Wrap returns err as a RepositoryError of the given kind raised by op. err
+// may be nil, for example for an update that matched no row.
+func Wrap(op string, kind, err error) error {
+	return &RepositoryError{Op: op, Kind: kind, Err: err}
+}
+
+// Database wraps an error returned by database/sql for op, classified by
+// its cause: sql.ErrNoRows is ErrNotFound, an expired deadline ErrTimeout,
+// a broken or refused connection ErrUnavailable and anything else
+// ErrInternalServer. Repositories check driver specific codes first.
+func Database(op string, err error) error {
+	var netErr net.Error
+	network := errors.As(err, &netErr)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return Wrap(op, ErrNotFound, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(op, ErrTimeout, err)
+	case network && netErr.Timeout():
+		return Wrap(op, ErrTimeout, err)
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), network:
+		return Wrap(op, ErrUnavailable, err)
+	default:
+		return Wrap(op, ErrInternalServer, err)
+	}
+}
+
+func (e *RepositoryError) Error() string {
+	if e.Err == nil {
+		return e.Op + ": " + e.Kind.Error()
+	}
+
+	return e.Op + ": " + e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *RepositoryError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RepositoryError) Is(target error) bool {
+	return target == e.Kind
+}
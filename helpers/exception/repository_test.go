@@ -0,0 +1,65 @@
+package exception_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/exception"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestDatabase(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"No Rows", sql.ErrNoRows, exception.ErrNotFound},
+		{"Deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), exception.ErrTimeout},
+		{"Network Timeout", &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, exception.ErrTimeout},
+		{"Connection Refused", refused, exception.ErrUnavailable},
+		{"Bad Connection", driver.ErrBadConn, exception.ErrUnavailable},
+		{"Connection Done", sql.ErrConnDone, exception.ErrUnavailable},
+		{"Other", errors.New("syntax error"), exception.ErrInternalServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := exception.Database("AccountRepository.FindByID", tt.err)
+
+			assert.ErrorIs(t, err, tt.kind)
+			assert.ErrorIs(t, err, tt.err, "the cause must stay reachable")
+		})
+	}
+}
+
+func TestRepositoryError(t *testing.T) {
+	cause := errors.New("connection reset")
+	err := exception.Wrap("AccountRepository.Update", exception.ErrUnavailable, cause)
+
+	assert.Equal(t, "AccountRepository.Update: service unavailable: connection reset", err.Error())
+	assert.False(t, errors.Is(err, exception.ErrNotFound))
+
+	var repoErr *exception.RepositoryError
+	if assert.ErrorAs(t, fmt.Errorf("update: %w", err), &repoErr) {
+		assert.Equal(t, "AccountRepository.Update", repoErr.Op)
+		assert.Equal(t, exception.ErrUnavailable, repoErr.Kind)
+	}
+
+	assert.Equal(t, "AccountRepository.Delete: not found error", exception.Wrap("AccountRepository.Delete", exception.ErrNotFound, nil).Error())
+}
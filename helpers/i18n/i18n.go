@@ -0,0 +1,225 @@
+package i18n
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	idTranslations "github.com/go-playground/validator/v10/translations/id"
+)
+
+//go:embed catalog/*.json
+var catalogs embed.FS
+
+type registerFunc func(v *validator.Validate, trans ut.Translator) error
+
+var supported = []struct {
+	locale    locales.Translator
+	validator registerFunc
+}{
+	{en.New(), enTranslations.RegisterDefaultTranslations},
+	{id.New(), idTranslations.RegisterDefaultTranslations},
+}
+
+// Supported lists the locales that have a built-in catalog.
+func Supported() []string {
+	names := make([]string, 0, len(supported))
+	for _, s := range supported {
+		names = append(names, s.locale.Locale())
+	}
+
+	return names
+}
+
+// Bundle holds the message catalogs of every supported locale.
+type Bundle struct {
+	universal *ut.UniversalTranslator
+	fallback  ut.Translator
+}
+
+// New loads the built-in catalogs and then every *.json file under dir, in
+// the universal-translator format; entries must set "override": true to
+// replace a built-in message. When validate is not nil its error messages
+// are registered for every locale as well.
+func New(validate *validator.Validate, fallback, dir string) (*Bundle, error) {
+	var fallbackLocale locales.Translator
+	for _, s := range supported {
+		if s.locale.Locale() == fallback {
+			fallbackLocale = s.locale
+		}
+	}
+
+	if fallbackLocale == nil {
+		return nil, fmt.Errorf("unsupported fallback locale %q", fallback)
+	}
+
+	universal := ut.New(fallbackLocale)
+	for _, s := range supported {
+		err := universal.AddTranslator(s.locale, true)
+		if err != nil {
+			return nil, err
+		}
+
+		if validate != nil {
+			trans, _ := universal.GetTranslator(s.locale.Locale())
+
+			err = s.validator(validate, trans)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	entries, err := catalogs.ReadDir("catalog")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		data, err := catalogs.ReadFile("catalog/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		err = universal.ImportByReader(ut.FormatJSON, bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", entry.Name(), err)
+		}
+	}
+
+	if dir != "" {
+		err = universal.Import(ut.FormatJSON, dir)
+		if err != nil {
+			return nil, fmt.Errorf("catalog dir %s: %w", dir, err)
+		}
+	}
+
+	return &Bundle{
+		universal: universal,
+		fallback:  universal.GetFallback(),
+	}, nil
+}
+
+// Localizer returns the translator best matching an Accept-Language header,
+// or the fallback locale when none of the requested languages is supported.
+func (b *Bundle) Localizer(acceptLanguage string) *Localizer {
+	trans, _ := b.universal.FindTranslator(preferred(acceptLanguage)...)
+
+	return &Localizer{trans: trans, fallback: b.fallback}
+}
+
+// Middleware picks the locale of each request from Accept-Language and
+// stores its Localizer in the request context.
+func (b *Bundle) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		localizer := b.Localizer(r.Header.Get("Accept-Language"))
+
+		w.Header().Set("Content-Language", localizer.Locale())
+		w.Header().Add("Vary", "Accept-Language")
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), localizer)))
+	})
+}
+
+// preferred returns the language tags of an Accept-Language header, most
+// preferred first. Each region-specific tag is followed by its base
+// language, so id-ID also matches id.
+func preferred(header string) []string {
+	type tag struct {
+		name string
+		q    float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" || name == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				parsed, err := strconv.ParseFloat(param[2:], 64)
+				if err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		if q > 0 {
+			tags = append(tags, tag{name, q})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	var names []string
+	for _, t := range tags {
+		name := strings.ReplaceAll(t.name, "-", "_")
+		names = append(names, name)
+		if i := strings.Index(name, "_"); i > 0 {
+			names = append(names, name[:i])
+		}
+	}
+
+	return names
+}
+
+// Localizer translates messages into one locale.
+type Localizer struct {
+	trans    ut.Translator
+	fallback ut.Translator
+}
+
+func (l *Localizer) Locale() string {
+	return l.trans.Locale()
+}
+
+// Translator exposes the underlying translator, for validator.FieldError.
+func (l *Localizer) Translator() ut.Translator {
+	return l.trans
+}
+
+// T translates key with params, falling back to the fallback locale and then
+// to def when the key is missing from both catalogs.
+func (l *Localizer) T(key, def string, params ...string) string {
+	text, err := l.trans.T(key, params...)
+	if err == nil {
+		return text
+	}
+
+	text, err = l.fallback.T(key, params...)
+	if err == nil {
+		return text
+	}
+
+	return def
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, localizer *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, localizer)
+}
+
+// FromContext returns the Localizer stored by Middleware, or nil.
+func FromContext(ctx context.Context) *Localizer {
+	localizer, _ := ctx.Value(contextKey{}).(*Localizer)
+	return localizer
+}
@@ -0,0 +1,85 @@
+package i18n_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/i18n"
+	"waizly/helpers/validation"
+)
+
+func TestLocalizer(t *testing.T) {
+	bundle, err := i18n.New(nil, "en", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		locale         string
+	}{
+		{name: "Exact", acceptLanguage: "id", locale: "id"},
+		{name: "Region", acceptLanguage: "id-ID,en;q=0.8", locale: "id"},
+		{name: "Quality", acceptLanguage: "id;q=0.3, en;q=0.9", locale: "en"},
+		{name: "Unsupported", acceptLanguage: "fr-FR", locale: "en"},
+		{name: "Missing", acceptLanguage: "", locale: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.locale, bundle.Localizer(tt.acceptLanguage).Locale())
+		})
+	}
+
+	t.Run("Translate", func(t *testing.T) {
+		assert.Equal(t, "Data tidak ditemukan", bundle.Localizer("id").T("problem.not-found", ""))
+		assert.Equal(t, "default", bundle.Localizer("id").T("missing.key", "default"))
+	})
+}
+
+func TestCatalogDir(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "custom.json"), []byte(`[
+		{"locale": "id", "key": "problem.not-found", "trans": "Tidak ada", "override": true},
+		{"locale": "id", "key": "greeting", "trans": "Halo {0}"}
+	]`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := i18n.New(nil, "id", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Tidak ada", bundle.Localizer("id").T("problem.not-found", ""))
+	assert.Equal(t, "Halo Budi", bundle.Localizer("en").T("greeting", "", "Budi"), "missing keys fall back to the fallback locale")
+	assert.Equal(t, "id", bundle.Localizer("fr").Locale())
+}
+
+func TestMiddleware(t *testing.T) {
+	bundle, err := i18n.New(validation.New(), "en", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var locale string
+	handler := bundle.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale = i18n.FromContext(r.Context()).Locale()
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "id-ID")
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, r)
+
+	assert.Equal(t, "id", locale)
+	assert.Equal(t, "id", recorder.Header().Get("Content-Language"))
+}
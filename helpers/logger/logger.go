@@ -0,0 +1,245 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"waizly/helpers/metadata"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+type (
+	// Logger writes one line per entry with a message and key/value fields.
+	// Loggers derived with With share the output of their parent.
+	Logger struct {
+		out    *output
+		fields []interface{}
+	}
+
+	output struct {
+		mu     sync.Mutex
+		w      io.Writer
+		format string
+		level  Level
+	}
+)
+
+// New returns a logger writing entries at level or above to w, either as JSON
+// objects or as human readable console lines.
+func New(w io.Writer, format string, level Level) *Logger {
+	return &Logger{
+		out: &output{w: w, format: format, level: level},
+	}
+}
+
+var std atomic.Pointer[Logger]
+
+func init() {
+	std.Store(New(os.Stderr, FormatConsole, LevelInfo))
+}
+
+// Default returns the process-wide logger.
+func Default() *Logger {
+	return std.Load()
+}
+
+func SetDefault(l *Logger) {
+	std.Store(l)
+}
+
+// Ctx returns the default logger carrying the request ID and trace ID found
+// in ctx, so lines from handlers, use cases and repositories of the same
+// request can be correlated.
+func Ctx(ctx context.Context) *Logger {
+	l := Default()
+
+	var fields []interface{}
+	if id := metadata.FromContext(ctx).RequestID; id != "" {
+		fields = append(fields, "request_id", id)
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.HasTraceID() {
+		fields = append(fields, "trace_id", span.TraceID().String())
+	}
+
+	if len(fields) == 0 {
+		return l
+	}
+
+	return l.With(fields...)
+}
+
+// With returns a logger that adds kv to every entry.
+func (l *Logger) With(kv ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(kv))
+	fields = append(fields, l.fields...)
+	fields = append(fields, kv...)
+
+	return &Logger{out: l.out, fields: fields}
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.out.level
+}
+
+func (l *Logger) Debug(msg string, kv ...interface{}) { l.log(LevelDebug, msg, kv) }
+func (l *Logger) Info(msg string, kv ...interface{})  { l.log(LevelInfo, msg, kv) }
+func (l *Logger) Warn(msg string, kv ...interface{})  { l.log(LevelWarn, msg, kv) }
+func (l *Logger) Error(msg string, kv ...interface{}) { l.log(LevelError, msg, kv) }
+
+// Writer adapts the logger for the standard library log package; every line
+// written becomes an entry at level.
+func (l *Logger) Writer(level Level) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		l.log(level, strings.TrimRight(string(p), "\n"), nil)
+		return len(p), nil
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func (l *Logger) log(level Level, msg string, kv []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	fields := make([]interface{}, 0, len(l.fields)+len(kv))
+	fields = append(fields, l.fields...)
+	fields = append(fields, kv...)
+
+	var buf bytes.Buffer
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	msg = redactString(msg)
+
+	if l.out.format == FormatConsole {
+		fmt.Fprintf(&buf, "%s %-5s %s", now, strings.ToUpper(level.String()), msg)
+		eachField(fields, func(key string, value interface{}) {
+			buf.WriteByte(' ')
+			buf.WriteString(key)
+			buf.WriteByte('=')
+			buf.WriteString(consoleValue(value))
+		})
+	} else {
+		buf.WriteString(`{"time":`)
+		writeJSON(&buf, now)
+		buf.WriteString(`,"level":`)
+		writeJSON(&buf, level.String())
+		buf.WriteString(`,"msg":`)
+		writeJSON(&buf, msg)
+		eachField(fields, func(key string, value interface{}) {
+			buf.WriteByte(',')
+			writeJSON(&buf, key)
+			buf.WriteByte(':')
+			writeJSON(&buf, value)
+		})
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('\n')
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+
+	l.out.w.Write(buf.Bytes())
+}
+
+// eachField walks kv as key/value pairs, redacting sensitive values. A
+// trailing key without a value is logged under "!BADKEY".
+func eachField(kv []interface{}, fn func(key string, value interface{})) {
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 == len(kv) {
+			fn("!BADKEY", normalize(kv[i]))
+			return
+		}
+
+		key := fmt.Sprint(kv[i])
+		fn(key, redact(key, normalize(kv[i+1])))
+	}
+}
+
+// normalize turns values without a useful JSON form into strings.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+func writeJSON(buf *bytes.Buffer, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+
+	buf.Write(data)
+}
+
+func consoleValue(value interface{}) string {
+	s := fmt.Sprint(value)
+	if strings.ContainsAny(s, " \t\n\"=") || s == "" {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
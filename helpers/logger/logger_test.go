@@ -0,0 +1,103 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/logger"
+	"waizly/helpers/metadata"
+)
+
+func decode(t *testing.T, out *bytes.Buffer) map[string]interface{} {
+	var line map[string]interface{}
+
+	err := json.Unmarshal(out.Bytes(), &line)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return line
+}
+
+func TestLogger(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) {
+		var out bytes.Buffer
+
+		logger.New(&out, logger.FormatJSON, logger.LevelInfo).
+			With("component", "test").
+			Error("query failed", "error", errors.New("bad connection"), "rows", 3)
+
+		line := decode(t, &out)
+		assert.Equal(t, "error", line["level"])
+		assert.Equal(t, "query failed", line["msg"])
+		assert.Equal(t, "test", line["component"])
+		assert.Equal(t, "bad connection", line["error"])
+		assert.Equal(t, float64(3), line["rows"])
+	})
+
+	t.Run("Console", func(t *testing.T) {
+		var out bytes.Buffer
+
+		logger.New(&out, logger.FormatConsole, logger.LevelInfo).Info("listening", "addr", ":8080", "note", "two words")
+
+		assert.Contains(t, out.String(), "INFO  listening addr=:8080 note=\"two words\"")
+	})
+
+	t.Run("Level", func(t *testing.T) {
+		var out bytes.Buffer
+
+		log := logger.New(&out, logger.FormatJSON, logger.LevelWarn)
+		log.Debug("hidden")
+		log.Info("hidden")
+		log.Warn("shown")
+
+		assert.Equal(t, 1, strings.Count(out.String(), "\n"))
+		assert.Contains(t, out.String(), "shown")
+	})
+
+	t.Run("Redaction", func(t *testing.T) {
+		var out bytes.Buffer
+
+		logger.New(&out, logger.FormatJSON, logger.LevelInfo).Info("login for jane@example.com",
+			"password", "hunter22",
+			"access_token", "eyJhbGciOi",
+			"email", "jane@example.com",
+			"error", errors.New("Duplicate entry 'jane@example.com' for key 'email'"),
+		)
+
+		assert.NotContains(t, out.String(), "hunter22")
+		assert.NotContains(t, out.String(), "eyJhbGciOi")
+		assert.NotContains(t, out.String(), "jane@example.com")
+
+		line := decode(t, &out)
+		assert.Equal(t, "login for j***@example.com", line["msg"])
+		assert.Equal(t, "j***@example.com", line["email"])
+	})
+}
+
+func TestCtx(t *testing.T) {
+	var out bytes.Buffer
+	logger.SetDefault(logger.New(&out, logger.FormatJSON, logger.LevelInfo))
+
+	ctx := metadata.NewContext(context.TODO(), metadata.Request{RequestID: "req-42"})
+	logger.Ctx(ctx).Info("handled")
+
+	assert.Equal(t, "req-42", decode(t, &out)["request_id"])
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := logger.ParseLevel("WARN")
+
+	assert.NoError(t, err)
+	assert.Equal(t, logger.LevelWarn, level)
+
+	_, err = logger.ParseLevel("verbose")
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+)
+
+const redacted = "******"
+
+var (
+	// secretKeys are field name fragments whose values are never logged.
+	secretKeys = []string{"password", "passwd", "secret", "token", "authorization", "cookie"}
+
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+)
+
+func redact(key string, value interface{}) interface{} {
+	lower := strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(lower, secret) {
+			if value == nil || value == "" {
+				return value
+			}
+
+			return redacted
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		return redactString(s)
+	}
+
+	return value
+}
+
+// redactString masks every email address in s, keeping the first letter and
+// the domain so support can still tell accounts apart.
+func redactString(s string) string {
+	return emailPattern.ReplaceAllStringFunc(s, MaskEmail)
+}
+
+// MaskEmail turns jane@example.com into j***@example.com.
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return redacted
+	}
+
+	return email[:1] + "***" + email[at:]
+}
@@ -0,0 +1,21 @@
+package metadata
+
+import "context"
+
+type contextKey struct{}
+
+// Request describes where a call into the use cases came from.
+type Request struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+func NewContext(ctx context.Context, req Request) context.Context {
+	return context.WithValue(ctx, contextKey{}, req)
+}
+
+func FromContext(ctx context.Context) Request {
+	req, _ := ctx.Value(contextKey{}).(Request)
+	return req
+}
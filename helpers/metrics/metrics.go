@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"waizly/helpers/exception"
+)
+
+const namespace = "waizly"
+
+// Metrics holds every collector the service exports. Each instance owns its
+// registry, so tests can create one without clashing with the global
+// default registry.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	HTTPRequests       *prometheus.CounterVec
+	HTTPDuration       *prometheus.HistogramVec
+	Logins             *prometheus.CounterVec
+	Registrations      *prometheus.CounterVec
+	HashDuration       prometheus.Histogram
+	RepositoryDuration *prometheus.HistogramVec
+	CacheRequests      *prometheus.CounterVec
+	ReplicaUp          *prometheus.GaugeVec
+	ReplicaLag         *prometheus.GaugeVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route template, method and status code.",
+		}, []string{"route", "method", "status"}),
+
+		HTTPDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route template, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+
+		Logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "account_logins_total",
+			Help:      "Login attempts by outcome and failure reason.",
+		}, []string{"outcome", "reason"}),
+
+		Registrations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "account_registrations_total",
+			Help:      "Registration attempts by outcome and failure reason.",
+		}, []string{"outcome", "reason"}),
+
+		// bcrypt is deliberately slow, the buckets cover the usual cost range.
+		HashDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "bcrypt_hash_duration_seconds",
+			Help:      "Time spent hashing passwords with bcrypt.",
+			Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2, 4},
+		}),
+
+		RepositoryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_duration_seconds",
+			Help:      "Repository call latency by repository, method and outcome.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"repository", "method", "outcome"}),
+
+		CacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_requests_total",
+			Help:      "Cache lookups by cache and result: hit, miss or error.",
+		}, []string{"cache", "result"}),
+
+		ReplicaUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "db_replica_up",
+			Help:      "1 while a read replica passes its checks and serves reads.",
+		}, []string{"replica"}),
+
+		ReplicaLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "db_replica_lag_seconds",
+			Help:      "Replication lag measured by the last check of a read replica.",
+		}, []string{"replica"}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.HTTPRequests,
+		m.HTTPDuration,
+		m.Logins,
+		m.Registrations,
+		m.HashDuration,
+		m.RepositoryDuration,
+		m.CacheRequests,
+		m.ReplicaUp,
+		m.ReplicaLag,
+	)
+
+	return m
+}
+
+// RegisterDB exports the connection pool statistics of db, such as open and
+// in-use connections and the wait count, labelled with name.
+func (m *Metrics) RegisterDB(name string, db *sql.DB) {
+	m.Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveReplica records the outcome of a health check of read replica
+// number replica. It fits replica.Options.Observe.
+func (m *Metrics) ObserveReplica(replica int, healthy bool, lag time.Duration) {
+	up := 0.0
+	if healthy {
+		up = 1
+	}
+
+	label := strconv.Itoa(replica)
+	m.ReplicaUp.WithLabelValues(label).Set(up)
+	m.ReplicaLag.WithLabelValues(label).Set(lag.Seconds())
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// Outcome maps an error to the outcome label used across the metrics. A
+// missing row is an expected answer rather than a failure of the store.
+func Outcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, exception.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, exception.ErrTimeout):
+		return "timeout"
+	case errors.Is(err, exception.ErrUnavailable):
+		return "unavailable"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"waizly/helpers/logger"
+	"waizly/helpers/metadata"
+)
+
+// AccessLog writes one line per request with its route, status, size and
+// latency. The query string is left out since it may carry tokens. It must
+// run after RequestMetadata so the line carries the request ID.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		log := logger.Ctx(r.Context())
+		fields := []interface{}{
+			"method", r.Method,
+			"route", route,
+			"path", r.URL.Path,
+			"status", recorder.status,
+			"bytes", recorder.bytes,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"ip", metadata.FromContext(r.Context()).IP,
+			"user_agent", r.UserAgent(),
+		}
+
+		switch {
+		case recorder.status >= http.StatusInternalServerError:
+			log.Error("request", fields...)
+		case recorder.status >= http.StatusBadRequest:
+			log.Warn("request", fields...)
+		default:
+			log.Info("request", fields...)
+		}
+	})
+}
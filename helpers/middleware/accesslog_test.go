@@ -0,0 +1,89 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/logger"
+	"waizly/helpers/metadata"
+	"waizly/helpers/middleware"
+)
+
+func TestRequestMetadata(t *testing.T) {
+	serve := func(requestID string) (string, string) {
+		var seen string
+
+		handler := middleware.RequestMetadata(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = metadata.FromContext(r.Context()).RequestID
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if requestID != "" {
+			r.Header.Set(middleware.HeaderRequestID, requestID)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+
+		return seen, recorder.Header().Get(middleware.HeaderRequestID)
+	}
+
+	t.Run("Accepts Caller ID", func(t *testing.T) {
+		seen, echoed := serve("req-42")
+
+		assert.Equal(t, "req-42", seen)
+		assert.Equal(t, "req-42", echoed)
+	})
+
+	t.Run("Generates Missing ID", func(t *testing.T) {
+		seen, echoed := serve("")
+
+		assert.Len(t, seen, 32)
+		assert.Equal(t, seen, echoed)
+	})
+
+	t.Run("Replaces Malformed ID", func(t *testing.T) {
+		seen, _ := serve("bad id\n{\"level\":\"error\"}")
+
+		assert.Len(t, seen, 32)
+	})
+}
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	logger.SetDefault(logger.New(&out, logger.FormatJSON, logger.LevelInfo))
+	defer logger.SetDefault(logger.New(&bytes.Buffer{}, logger.FormatJSON, logger.LevelInfo))
+
+	router := mux.NewRouter()
+	router.Use(middleware.RequestMetadata)
+	router.Use(middleware.AccessLog)
+	router.HandleFunc("/account/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("missing"))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/account/42?token=secret", nil)
+	r.Header.Set(middleware.HeaderRequestID, "req-42")
+
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	var line map[string]interface{}
+	err := json.Unmarshal(out.Bytes(), &line)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "warn", line["level"])
+	assert.Equal(t, "req-42", line["request_id"])
+	assert.Equal(t, "/account/{id}", line["route"])
+	assert.Equal(t, float64(http.StatusNotFound), line["status"])
+	assert.Equal(t, float64(len("missing")), line["bytes"])
+	assert.False(t, strings.Contains(out.String(), "secret"))
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/response"
+)
+
+// BasicAuth guards admin routes. Empty credentials reject every request so a
+// missing configuration never leaves the routes open.
+func BasicAuth(username, password string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+
+			if !ok || username == "" || password == "" ||
+				subtle.ConstantTimeCompare([]byte(user), []byte(username)) != 1 ||
+				subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+				response.Error(response.StatusUnauthorized, exception.ErrUnauthorized).JSON(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"waizly/helpers/metrics"
+)
+
+// Metrics records the count and latency of every request, labelled with the
+// route template rather than the raw path so IDs in URLs do not explode the
+// number of series. It must be added with router.Use so the route is known.
+// mux skips router.Use for requests no route matches, MetricsUnmatched counts
+// those.
+func Metrics(m *metrics.Metrics) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(recorder, r)
+
+			route := "unmatched"
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					route = template
+				}
+			}
+
+			status := strconv.Itoa(recorder.status)
+			m.HTTPRequests.WithLabelValues(route, r.Method, status).Inc()
+			m.HTTPDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// MetricsUnmatched counts the 404 and 405 answers of router under the
+// "unmatched" route.
+func MetricsUnmatched(router *mux.Router, m *metrics.Metrics) {
+	count := Metrics(m)
+
+	router.NotFoundHandler = count(http.NotFoundHandler())
+	router.MethodNotAllowedHandler = count(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+
+	return n, err
+}
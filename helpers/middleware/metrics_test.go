@@ -0,0 +1,36 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/metrics"
+	"waizly/helpers/middleware"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+
+	router := mux.NewRouter()
+	router.Use(middleware.Metrics(m))
+	middleware.MetricsUnmatched(router, m)
+	router.HandleFunc("/accounts/{id}", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+
+	for _, request := range []struct{ method, path string }{
+		{http.MethodGet, "/accounts/1"},
+		{http.MethodGet, "/accounts/2"},
+		{http.MethodGet, "/unknown"},
+		{http.MethodPost, "/accounts/1"},
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(request.method, request.path, nil))
+	}
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.HTTPRequests.WithLabelValues("/accounts/{id}", http.MethodGet, "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.HTTPRequests.WithLabelValues("unmatched", http.MethodGet, "404")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.HTTPRequests.WithLabelValues("unmatched", http.MethodPost, "405")))
+}
@@ -0,0 +1,181 @@
+// Package replica picks the read replica a query may run on, taking
+// replicas out of rotation while they are unreachable or lag too far behind
+// the primary.
+package replica
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"waizly/helpers/logger"
+	"waizly/helpers/transaction"
+)
+
+type sessionKey struct{}
+
+type (
+	// LagFunc reports how far the replica behind db trails the primary.
+	LagFunc func(ctx context.Context, db *sql.DB) (time.Duration, error)
+
+	// Options tunes a Pool.
+	Options struct {
+		// MaxLag is the lag beyond which a replica stops serving reads.
+		MaxLag time.Duration
+		// CheckInterval is the time between two health checks.
+		CheckInterval time.Duration
+		// Observe, when set, is called with the outcome of every check.
+		Observe func(replica int, healthy bool, lag time.Duration)
+	}
+
+	Pool struct {
+		replicas []*member
+		lag      LagFunc
+		opts     Options
+		next     atomic.Uint64
+	}
+
+	member struct {
+		db      *sql.DB
+		healthy atomic.Bool
+	}
+
+	// session records whether the request it belongs to wrote to the
+	// primary.
+	session struct {
+		written atomic.Bool
+	}
+)
+
+// NewPool returns a Pool over replicas. A replica serves reads only once a
+// check found it healthy, so until Check or Run has run every read goes to
+// the primary.
+func NewPool(replicas []*sql.DB, lag LagFunc, opts Options) *Pool {
+	p := &Pool{
+		lag:  lag,
+		opts: opts,
+	}
+
+	for _, db := range replicas {
+		p.replicas = append(p.replicas, &member{db: db})
+	}
+
+	return p
+}
+
+// Len returns the number of replicas, healthy or not.
+func (p *Pool) Len() int {
+	return len(p.replicas)
+}
+
+// Pick returns the index of the replica a read in ctx should use, taking the
+// healthy replicas in turn. It returns false when the read belongs on the
+// primary: inside a transaction, after the request wrote, or when no replica
+// is healthy.
+func (p *Pool) Pick(ctx context.Context) (int, bool) {
+	if len(p.replicas) == 0 || transaction.Active(ctx) || Written(ctx) {
+		return 0, false
+	}
+
+	healthy := make([]int, 0, len(p.replicas))
+	for i, replica := range p.replicas {
+		if replica.healthy.Load() {
+			healthy = append(healthy, i)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return 0, false
+	}
+
+	return healthy[(p.next.Add(1)-1)%uint64(len(healthy))], true
+}
+
+// Eject takes a replica out of rotation after a read on it failed. The next
+// successful check brings it back.
+func (p *Pool) Eject(ctx context.Context, replica int, err error) {
+	if p.replicas[replica].healthy.CompareAndSwap(true, false) {
+		logger.Ctx(ctx).Warn("replica ejected", "replica", replica, "error", err)
+	}
+}
+
+// Run checks the replicas every CheckInterval until ctx is cancelled.
+func (p *Pool) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.opts.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		p.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check measures the lag of every replica once and updates which of them
+// serve reads.
+func (p *Pool) Check(ctx context.Context) {
+	for i, replica := range p.replicas {
+		checkCtx, cancel := context.WithTimeout(ctx, p.timeout())
+		lag, err := p.lag(checkCtx, replica.db)
+		cancel()
+
+		healthy := err == nil && lag <= p.opts.MaxLag
+		was := replica.healthy.Swap(healthy)
+
+		switch {
+		case healthy && !was:
+			logger.Ctx(ctx).Info("replica in rotation", "replica", i, "lag", lag.String())
+		case !healthy && was && err != nil:
+			logger.Ctx(ctx).Warn("replica ejected", "replica", i, "error", err)
+		case !healthy && was:
+			logger.Ctx(ctx).Warn("replica ejected", "replica", i, "lag", lag.String(), "max_lag", p.opts.MaxLag.String())
+		}
+
+		if p.opts.Observe != nil {
+			p.opts.Observe(i, healthy, lag)
+		}
+	}
+}
+
+// timeout bounds a single check so one hanging replica does not hold up the
+// others past the next tick.
+func (p *Pool) timeout() time.Duration {
+	if p.opts.CheckInterval > 0 && len(p.replicas) > 0 {
+		return p.opts.CheckInterval / time.Duration(len(p.replicas))
+	}
+
+	return 5 * time.Second
+}
+
+// NewSession returns a context in which reads go to the primary once
+// MarkWritten was called, so a request reads its own writes.
+func NewSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, new(session))
+}
+
+// MarkWritten records that the session in ctx wrote to the primary. Without
+// a session it does nothing.
+func MarkWritten(ctx context.Context) {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		s.written.Store(true)
+	}
+}
+
+// Written reports whether the session in ctx wrote to the primary.
+func Written(ctx context.Context) bool {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	return ok && s.written.Load()
+}
+
+// Middleware starts a session for every request.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(NewSession(r.Context())))
+	})
+}
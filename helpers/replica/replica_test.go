@@ -0,0 +1,149 @@
+package replica_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+
+	"waizly/helpers/replica"
+	"waizly/helpers/transaction"
+)
+
+// fakeLag answers a LagFunc from a table that tests can change.
+type fakeLag struct {
+	mu   sync.Mutex
+	lags map[*sql.DB]time.Duration
+	errs map[*sql.DB]error
+}
+
+func (f *fakeLag) set(db *sql.DB, lag time.Duration, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lags[db], f.errs[db] = lag, err
+}
+
+func (f *fakeLag) lag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.lags[db], f.errs[db]
+}
+
+func open(t *testing.T, n int) []*sql.DB {
+	var dbs []*sql.DB
+	for i := 0; i < n; i++ {
+		db, err := sql.Open("sqlite", ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() { db.Close() })
+		dbs = append(dbs, db)
+	}
+
+	return dbs
+}
+
+func picks(ctx context.Context, pool *replica.Pool, n int) []int {
+	var picked []int
+	for i := 0; i < n; i++ {
+		index, ok := pool.Pick(ctx)
+		if !ok {
+			index = -1
+		}
+		picked = append(picked, index)
+	}
+
+	return picked
+}
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Round Robin Over Healthy Replicas", func(t *testing.T) {
+		dbs := open(t, 3)
+		lag := &fakeLag{lags: map[*sql.DB]time.Duration{}, errs: map[*sql.DB]error{}}
+		lag.set(dbs[1], 10*time.Second, nil)
+
+		observed := map[int]bool{}
+		pool := replica.NewPool(dbs, lag.lag, replica.Options{
+			MaxLag:        time.Second,
+			CheckInterval: time.Second,
+			Observe:       func(replica int, healthy bool, lag time.Duration) { observed[replica] = healthy },
+		})
+
+		assert.Equal(t, []int{-1}, picks(ctx, pool, 1), "nothing is read from a replica before the first check")
+
+		pool.Check(ctx)
+
+		assert.Equal(t, map[int]bool{0: true, 1: false, 2: true}, observed)
+		assert.ElementsMatch(t, []int{0, 2, 0, 2}, picks(ctx, pool, 4))
+
+		lag.set(dbs[1], 0, nil)
+		lag.set(dbs[2], 0, errors.New("connection refused"))
+		pool.Check(ctx)
+
+		assert.ElementsMatch(t, []int{0, 1, 0, 1}, picks(ctx, pool, 4))
+	})
+
+	t.Run("Eject", func(t *testing.T) {
+		dbs := open(t, 2)
+		lag := &fakeLag{lags: map[*sql.DB]time.Duration{}, errs: map[*sql.DB]error{}}
+		pool := replica.NewPool(dbs, lag.lag, replica.Options{MaxLag: time.Second, CheckInterval: time.Second})
+		pool.Check(ctx)
+
+		pool.Eject(ctx, 0, errors.New("bad connection"))
+		assert.Equal(t, []int{1, 1, 1}, picks(ctx, pool, 3))
+
+		pool.Eject(ctx, 1, errors.New("bad connection"))
+		assert.Equal(t, []int{-1}, picks(ctx, pool, 1))
+
+		pool.Check(ctx)
+		assert.ElementsMatch(t, []int{0, 1}, picks(ctx, pool, 2), "a successful check brings replicas back")
+	})
+
+	t.Run("Primary Reads", func(t *testing.T) {
+		dbs := open(t, 1)
+		lag := &fakeLag{lags: map[*sql.DB]time.Duration{}, errs: map[*sql.DB]error{}}
+		pool := replica.NewPool(dbs, lag.lag, replica.Options{MaxLag: time.Second, CheckInterval: time.Second})
+		pool.Check(ctx)
+
+		session := replica.NewSession(ctx)
+		assert.Equal(t, []int{0}, picks(session, pool, 1))
+
+		replica.MarkWritten(session)
+		assert.True(t, replica.Written(session))
+		assert.Equal(t, []int{-1}, picks(session, pool, 1), "a request reads its own writes from the primary")
+		assert.Equal(t, []int{0}, picks(ctx, pool, 1), "other requests still use the replica")
+
+		replica.MarkWritten(ctx)
+		assert.False(t, replica.Written(ctx), "without a session nothing is recorded")
+
+		err := transaction.NewTransactor(dbs[0]).WithinTransaction(ctx, func(ctx context.Context) error {
+			assert.Equal(t, []int{-1}, picks(ctx, pool, 1), "a transaction reads from the primary")
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Middleware", func(t *testing.T) {
+		var written bool
+		handler := replica.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			replica.MarkWritten(r.Context())
+			written = replica.Written(r.Context())
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.True(t, written)
+	})
+}
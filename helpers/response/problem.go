@@ -0,0 +1,192 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/i18n"
+	"waizly/helpers/metadata"
+)
+
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeProblem = "application/problem+json"
+)
+
+// Problem is an RFC 7807 problem details document. Code carries the legacy
+// status string so clients can switch formats without losing information.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes one field that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+type problemType struct {
+	slug  string
+	title string
+}
+
+// problemTypes maps the exception sentinels to a stable type URI and title.
+// Errors not listed here are described by their HTTP status alone.
+var problemTypes = map[error]problemType{
+	exception.ErrConflicted:     {"conflict", "Resource already exists"},
+	exception.ErrInternalServer: {"internal", "Internal server error"},
+	exception.ErrNotFound:       {"not-found", "Resource not found"},
+	exception.ErrBadRequest:     {"bad-request", "Bad request"},
+	exception.ErrUnauthorized:   {"unauthorized", "Authentication required"},
+	exception.ErrForbidden:      {"forbidden", "Not allowed"},
+	exception.ErrNotPremium:     {"not-premium", "Premium account required"},
+	exception.ErrParams:         {"invalid-params", "Invalid request parameters"},
+	exception.ErrTimeout:        {"timeout", "Request timed out"},
+	exception.ErrUnavailable:    {"unavailable", "Service temporarily unavailable"},
+
+	exception.ErrPreconditionFailed:   {"precondition-failed", "Resource has changed"},
+	exception.ErrPreconditionRequired: {"precondition-required", "If-Match header required"},
+}
+
+// NewProblem describes err, returned with the given legacy status, for the
+// request r. Details of server errors are never exposed. Titles and messages
+// are translated when the request carries an i18n.Localizer.
+func NewProblem(r *http.Request, status string, err error) Problem {
+	localizer := i18n.FromContext(r.Context())
+	translate := func(key, def string) string {
+		if localizer == nil {
+			return def
+		}
+
+		return localizer.T(key, def)
+	}
+
+	code := statusCode(status)
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     translate(fmt.Sprintf("http.%d", code), http.StatusText(code)),
+		Status:    code,
+		Instance:  r.URL.Path,
+		Code:      status,
+		RequestID: metadata.FromContext(r.Context()).RequestID,
+	}
+
+	var validationErrors validator.ValidationErrors
+
+	switch {
+	case errors.As(err, &validationErrors):
+		problem.Type = "/problems/validation"
+		problem.Title = translate("problem.validation", "Validation failed")
+		problem.Detail = translate("problem.validation.detail", "one or more fields are invalid")
+		for _, fe := range validationErrors {
+			problem.Errors = append(problem.Errors, newFieldError(fe, localizer))
+		}
+	case err != nil:
+		var detail string
+		if code < http.StatusInternalServerError {
+			detail = err.Error()
+		}
+
+		for sentinel, t := range problemTypes {
+			if errors.Is(err, sentinel) {
+				problem.Type = "/problems/" + t.slug
+				problem.Title = translate("problem."+t.slug, t.title)
+				if detail != "" {
+					detail = translate("error."+t.slug, detail)
+				}
+				break
+			}
+		}
+
+		problem.Detail = detail
+	}
+
+	return problem
+}
+
+func newFieldError(fe validator.FieldError, localizer *i18n.Localizer) FieldError {
+	field := fe.Namespace()
+	if i := strings.Index(field, "."); i >= 0 {
+		field = field[i+1:]
+	}
+
+	if localizer != nil {
+		return FieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: fe.Translate(localizer.Translator()),
+		}
+	}
+
+	var message string
+	switch fe.Tag() {
+	case "required":
+		message = "is required"
+	case "email":
+		message = "must be a valid email address"
+	case "url":
+		message = "must be a valid URL"
+	case "min", "gte":
+		message = fmt.Sprintf("must be at least %s", fe.Param())
+	case "max", "lte":
+		message = fmt.Sprintf("must be at most %s", fe.Param())
+	case "len":
+		message = fmt.Sprintf("must have length %s", fe.Param())
+	case "oneof":
+		message = fmt.Sprintf("must be one of %s", fe.Param())
+	default:
+		message = fmt.Sprintf("failed the %q rule", fe.Tag())
+	}
+
+	return FieldError{
+		Field:   field,
+		Rule:    fe.Tag(),
+		Param:   fe.Param(),
+		Message: field + " " + message,
+	}
+}
+
+// AcceptsProblem reports whether the client listed application/problem+json
+// in its Accept header. Clients that did not ask keep the legacy envelope.
+func AcceptsProblem(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil || mediaType != ContentTypeProblem {
+				continue
+			}
+
+			if q := params["q"]; q == "0" || q == "0.0" || q == "0.00" || q == "0.000" {
+				continue
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p Problem) write(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(p.Status)
+
+	return json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,107 @@
+package response_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/i18n"
+	"waizly/helpers/response"
+	"waizly/helpers/validation"
+)
+
+func TestRender(t *testing.T) {
+	render := func(res response.Response, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/account/detail", nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+
+		recorder := httptest.NewRecorder()
+		res.Render(recorder, r)
+
+		return recorder
+	}
+
+	t.Run("Legacy Envelope By Default", func(t *testing.T) {
+		recorder := render(response.Error(response.StatusNotFound, exception.ErrNotFound), "application/json")
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, response.ContentTypeJSON, recorder.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"status":"NOT_FOUND","data":null}`, recorder.Body.String())
+	})
+
+	t.Run("Problem Details", func(t *testing.T) {
+		recorder := render(response.Error(response.StatusNotFound, exception.ErrNotFound), "application/problem+json, application/json;q=0.5")
+
+		problem := response.Problem{}
+		if err := json.NewDecoder(recorder.Body).Decode(&problem); err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, response.ContentTypeProblem, recorder.Header().Get("Content-Type"))
+		assert.Equal(t, response.Problem{
+			Type:     "/problems/not-found",
+			Title:    "Resource not found",
+			Status:   http.StatusNotFound,
+			Detail:   exception.ErrNotFound.Error(),
+			Instance: "/account/detail",
+			Code:     response.StatusNotFound,
+		}, problem)
+	})
+
+	t.Run("Hides Server Error Detail", func(t *testing.T) {
+		recorder := render(response.Error(response.StatusInternalServerError, errors.New("dial tcp 10.0.0.5:3306: refused")), response.ContentTypeProblem)
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.NotContains(t, recorder.Body.String(), "10.0.0.5")
+	})
+
+	t.Run("Success Ignores Accept", func(t *testing.T) {
+		recorder := render(response.Success(response.StatusOK, "ok"), response.ContentTypeProblem)
+
+		assert.Equal(t, response.ContentTypeJSON, recorder.Header().Get("Content-Type"))
+	})
+
+	t.Run("Refused Problem", func(t *testing.T) {
+		recorder := render(response.Error(response.StatusNotFound, exception.ErrNotFound), "application/problem+json;q=0")
+
+		assert.Equal(t, response.ContentTypeJSON, recorder.Header().Get("Content-Type"))
+	})
+}
+
+func TestRenderLocalized(t *testing.T) {
+	validate := validation.New()
+	bundle, err := i18n.New(validate, "en", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type register struct {
+		Email string `json:"email" validate:"required"`
+	}
+
+	err = validate.Struct(register{})
+
+	r := httptest.NewRequest(http.MethodPost, "/account/register", nil)
+	r.Header.Set("Accept", response.ContentTypeProblem)
+	r = r.WithContext(i18n.NewContext(r.Context(), bundle.Localizer("id")))
+	recorder := httptest.NewRecorder()
+
+	response.Error(response.StatusBadRequest, err).Render(recorder, r)
+
+	problem := response.Problem{}
+	if err := json.NewDecoder(recorder.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Validasi gagal", problem.Title)
+	assert.Equal(t, []response.FieldError{
+		{Field: "email", Rule: "required", Message: "email wajib diisi"},
+	}, problem.Errors)
+}
@@ -2,12 +2,16 @@ package response
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"waizly/helpers/exception"
 )
 
 type Response interface {
 	Err() (err error)
 	JSON(w http.ResponseWriter) (err error)
+	Render(w http.ResponseWriter, r *http.Request) (err error)
 }
 
 type ResponseImpl struct {
@@ -32,7 +36,36 @@ func Error(status string, err error) (resp Response) {
 	}
 }
 
-func (r *ResponseImpl) getStatusCode(status string) (statusCode int) {
+// FromError answers with the status matching the kind of err, such as a
+// exception.RepositoryError. Only the sentinel is kept, so driver details
+// never reach the client.
+func FromError(err error) (resp Response) {
+	switch {
+	case errors.Is(err, exception.ErrNotFound):
+		return Error(StatusNotFound, exception.ErrNotFound)
+	case errors.Is(err, exception.ErrConflicted):
+		return Error(StatusConflicted, exception.ErrConflicted)
+	case errors.Is(err, exception.ErrTimeout):
+		return Error(StatusGatewayTimeout, exception.ErrTimeout)
+	case errors.Is(err, exception.ErrUnavailable):
+		return Error(StatusServiceUnavailable, exception.ErrUnavailable)
+	default:
+		return Error(StatusInternalServerError, exception.ErrInternalServer)
+	}
+}
+
+// StatusOf returns the status of res, or an empty string when res is not a
+// *ResponseImpl.
+func StatusOf(res Response) string {
+	impl, ok := res.(*ResponseImpl)
+	if !ok {
+		return ""
+	}
+
+	return impl.Status
+}
+
+func statusCode(status string) int {
 	switch status {
 	case StatusOK:
 		return http.StatusOK
@@ -52,6 +85,14 @@ func (r *ResponseImpl) getStatusCode(status string) (statusCode int) {
 		return http.StatusUnprocessableEntity
 	case StatusInternalServerError:
 		return http.StatusInternalServerError
+	case StatusServiceUnavailable:
+		return http.StatusServiceUnavailable
+	case StatusGatewayTimeout:
+		return http.StatusGatewayTimeout
+	case StatusPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case StatusPreconditionRequired:
+		return http.StatusPreconditionRequired
 	default:
 		return http.StatusInternalServerError
 	}
@@ -62,9 +103,20 @@ func (r *ResponseImpl) Err() (err error) {
 }
 
 func (r *ResponseImpl) JSON(w http.ResponseWriter) error {
-	statusCode := r.getStatusCode(r.Status)
-	w.Header().Set("Content-Type", "application/json")
+	statusCode := statusCode(r.Status)
+	w.Header().Set("Content-Type", ContentTypeJSON)
 	w.WriteHeader(statusCode)
 
 	return json.NewEncoder(w).Encode(r)
 }
+
+// Render writes an error as problem details when the client accepts
+// application/problem+json and falls back to JSON otherwise. Successful
+// responses always use the JSON envelope.
+func (r *ResponseImpl) Render(w http.ResponseWriter, req *http.Request) error {
+	if statusCode(r.Status) < http.StatusBadRequest || !AcceptsProblem(req) {
+		return r.JSON(w)
+	}
+
+	return NewProblem(req, r.Status, r.err).write(w)
+}
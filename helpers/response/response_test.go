@@ -0,0 +1,49 @@
+package response_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/response"
+)
+
+func TestFromError(t *testing.T) {
+	cause := errors.New("dial tcp 10.0.0.1:3306: connect: connection refused")
+
+	tests := []struct {
+		name   string
+		err    error
+		status string
+		code   int
+		want   error
+	}{
+		{"Not Found", exception.Wrap("op", exception.ErrNotFound, nil), response.StatusNotFound, http.StatusNotFound, exception.ErrNotFound},
+		{"Conflict", exception.Wrap("op", exception.ErrConflicted, cause), response.StatusConflicted, http.StatusConflict, exception.ErrConflicted},
+		{"Timeout", exception.Wrap("op", exception.ErrTimeout, cause), response.StatusGatewayTimeout, http.StatusGatewayTimeout, exception.ErrTimeout},
+		{"Unavailable", exception.Wrap("op", exception.ErrUnavailable, cause), response.StatusServiceUnavailable, http.StatusServiceUnavailable, exception.ErrUnavailable},
+		{"Bare Sentinel", exception.ErrNotFound, response.StatusNotFound, http.StatusNotFound, exception.ErrNotFound},
+		{"Unknown", cause, response.StatusInternalServerError, http.StatusInternalServerError, exception.ErrInternalServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := response.FromError(tt.err)
+
+			assert.Equal(t, tt.status, response.StatusOf(res))
+			assert.Equal(t, tt.want, res.Err(), "only the sentinel may reach the client")
+
+			r := httptest.NewRequest(http.MethodGet, "/account/detail", nil)
+			r.Header.Set("Accept", "application/problem+json")
+			recorder := httptest.NewRecorder()
+			res.Render(recorder, r)
+
+			assert.Equal(t, tt.code, recorder.Code)
+			assert.NotContains(t, recorder.Body.String(), "10.0.0.1")
+		})
+	}
+}
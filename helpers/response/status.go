@@ -1,14 +1,18 @@
 package response
 
 const (
-	StatusOK                  = "OK"
-	StatusCreated             = "CREATED"
-	StatusBadRequest          = "BAD_REQUEST"
-	StatusUnauthorized        = "UNAUTHORIZED"
-	StatusForbiddend          = "FORBIDDEN"
-	StatusNotFound            = "NOT_FOUND"
-	StatusConflicted          = "CONFLICTED"
-	StatusUnprocessableEntity = "UNPROCESSABLE_ENTITY"
-	StatusInternalServerError = "INTERNAL_SERVER_ERROR"
-	StatusUnprocessableParams = "UNPROCESSABLE_PARAMS"
+	StatusOK                   = "OK"
+	StatusCreated              = "CREATED"
+	StatusBadRequest           = "BAD_REQUEST"
+	StatusUnauthorized         = "UNAUTHORIZED"
+	StatusForbiddend           = "FORBIDDEN"
+	StatusNotFound             = "NOT_FOUND"
+	StatusConflicted           = "CONFLICTED"
+	StatusUnprocessableEntity  = "UNPROCESSABLE_ENTITY"
+	StatusInternalServerError  = "INTERNAL_SERVER_ERROR"
+	StatusUnprocessableParams  = "UNPROCESSABLE_PARAMS"
+	StatusServiceUnavailable   = "SERVICE_UNAVAILABLE"
+	StatusGatewayTimeout       = "GATEWAY_TIMEOUT"
+	StatusPreconditionFailed   = "PRECONDITION_FAILED"
+	StatusPreconditionRequired = "PRECONDITION_REQUIRED"
 )
@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+
+	serviceName = "waizly"
+)
+
+// Options selects where spans go. Endpoint is host:port of an OTLP/HTTP
+// collector and is only used by the otlp exporter.
+type Options struct {
+	Exporter    string
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The returned function flushes buffered spans and must be called
+// on shutdown. With ExporterNone spans are still created, so trace IDs
+// propagate to downstream services, but nothing is exported.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch opts.Exporter {
+	case ExporterNone, "":
+	case ExporterOTLP:
+		clientOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.Endpoint)}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err = otlptracehttp.New(ctx, clientOpts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{sdktrace.WithSampler(
+		sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio)),
+	)}
+	if exporter != nil {
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := NewProvider(providerOpts...)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns a tracer provider describing this service. Tests pass
+// sdktrace.WithSyncer(tracetest.NewInMemoryExporter()) to inspect spans.
+func NewProvider(opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))
+
+	return sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{sdktrace.WithResource(res)}, opts...)...)
+}
+
+// End records err on span, marks the span as failed and ends it. It takes a
+// pointer so it can be deferred with a named error result.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+
+	span.End()
+}
+
+// StartSQL starts a client span for one SQL statement against a MySQL
+// table.
+func StartSQL(ctx context.Context, tracer trace.Tracer, name, table, query string) (context.Context, trace.Span) {
+	return startSQL(ctx, tracer, semconv.DBSystemMySQL, name, table, query)
+}
+
+// StartPostgresSQL is StartSQL for a PostgreSQL table.
+func StartPostgresSQL(ctx context.Context, tracer trace.Tracer, name, table, query string) (context.Context, trace.Span) {
+	return startSQL(ctx, tracer, semconv.DBSystemPostgreSQL, name, table, query)
+}
+
+// StartSQLiteSQL is StartSQL for an SQLite table.
+func StartSQLiteSQL(ctx context.Context, tracer trace.Tracer, name, table, query string) (context.Context, trace.Span) {
+	return startSQL(ctx, tracer, semconv.DBSystemSqlite, name, table, query)
+}
+
+func startSQL(ctx context.Context, tracer trace.Tracer, system attribute.KeyValue, name, table, query string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			system,
+			semconv.DBSQLTableKey.String(table),
+			semconv.DBStatementKey.String(query),
+		),
+	)
+}
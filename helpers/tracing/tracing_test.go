@@ -0,0 +1,31 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+
+	"waizly/helpers/tracing"
+)
+
+func TestSetup(t *testing.T) {
+	t.Run("No Exporter Still Traces", func(t *testing.T) {
+		shutdown, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterNone, SampleRatio: 1})
+		assert.NoError(t, err)
+		defer shutdown(context.Background())
+
+		_, span := otel.Tracer("test").Start(context.Background(), "request")
+		defer span.End()
+
+		assert.True(t, span.SpanContext().TraceID().IsValid())
+		assert.True(t, span.SpanContext().IsSampled())
+	})
+
+	t.Run("Unknown Exporter", func(t *testing.T) {
+		_, err := tracing.Setup(context.Background(), tracing.Options{Exporter: "jaeger"})
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,239 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"waizly/helpers/logger"
+)
+
+type txKey struct{}
+
+// txState is what WithinTransaction stores in the context: the transaction,
+// how many savepoints deep the current call runs, the functions waiting for
+// the outermost transaction to commit and why it was aborted, if it was.
+type txState struct {
+	tx          *sql.Tx
+	depth       int
+	afterCommit *[]func()
+	aborted     *error
+}
+
+type (
+	// DBTX is the subset of *sql.DB and *sql.Tx used by repositories.
+	DBTX interface {
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+		PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+		QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+		QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	}
+
+	Transactor interface {
+		WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	}
+
+	// Option configures a Transactor.
+	Option func(*transactorImpl)
+
+	transactorImpl struct {
+		db          *sql.DB
+		maxAttempts int
+		retryable   func(err error) bool
+		backoff     time.Duration
+	}
+)
+
+// WithRetry runs a transaction up to maxAttempts times while it fails with
+// an error retryable reports as transient, such as a deadlock or a
+// serialization failure. Only the outermost transaction is retried, so fn
+// must be safe to run again from the start.
+func WithRetry(maxAttempts int, retryable func(err error) bool) Option {
+	return func(t *transactorImpl) {
+		t.maxAttempts = maxAttempts
+		t.retryable = retryable
+	}
+}
+
+func NewTransactor(db *sql.DB, opts ...Option) Transactor {
+	t := &transactorImpl{
+		db:          db,
+		maxAttempts: 1,
+		retryable:   func(err error) bool { return false },
+		backoff:     10 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// WithinTransaction runs fn with a context carrying a *sql.Tx. Repositories
+// that resolve their connection through Conn join that transaction, which is
+// committed when fn returns nil and rolled back otherwise.
+//
+// Called with a context that already carries a transaction, it opens a
+// savepoint instead: an error from fn rolls back to the savepoint and leaves
+// the outer transaction usable. A panic in fn rolls back and is re-raised.
+func (t *transactorImpl) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return savepoint(ctx, state, fn)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := t.run(ctx, fn)
+		if err == nil || attempt >= t.maxAttempts || !t.retryable(err) {
+			return err
+		}
+
+		logger.Ctx(ctx).Warn("retrying transaction", "attempt", attempt, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(t.delay(attempt)):
+		}
+	}
+}
+
+func (t *transactorImpl) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	var afterCommit []func()
+	var aborted error
+
+	err = fn(context.WithValue(ctx, txKey{}, &txState{tx: tx, afterCommit: &afterCommit, aborted: &aborted}))
+	if aborted != nil {
+		// Whatever fn made of it, the work it did after the savepoint was
+		// lost is not in the transaction.
+		err = aborted
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range afterCommit {
+		f()
+	}
+
+	return nil
+}
+
+// delay doubles the wait after every attempt, with jitter so transactions
+// that deadlocked on each other do not collide again.
+func (t *transactorImpl) delay(attempt int) time.Duration {
+	d := t.backoff << (attempt - 1)
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func savepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	if *state.aborted != nil {
+		return *state.aborted
+	}
+
+	nested := &txState{tx: state.tx, depth: state.depth + 1, afterCommit: state.afterCommit, aborted: state.aborted}
+	name := fmt.Sprintf("sp_%d", nested.depth)
+
+	_, err := state.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		return err
+	}
+
+	// Rolling back to a savepoint keeps it, so it is released either way.
+	rollback := func(cause error) error {
+		_, err := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		if err == nil {
+			_, err = state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+		}
+
+		if err != nil {
+			return abort(state, fmt.Errorf("roll back to savepoint %s after %w: %v", name, cause, err))
+		}
+
+		return cause
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			rollback(fmt.Errorf("panic: %v", p))
+			panic(p)
+		}
+	}()
+
+	err = fn(context.WithValue(ctx, txKey{}, nested))
+	if err != nil {
+		return rollback(err)
+	}
+
+	_, err = state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	if err != nil {
+		return abort(state, fmt.Errorf("release savepoint %s: %w", name, err))
+	}
+
+	return nil
+}
+
+// abort rolls back the whole transaction once a savepoint is gone, which
+// happens when the database already rolled it back, as MySQL does on a
+// deadlock. Every later statement then fails with sql.ErrTxDone instead of
+// running outside the transaction, and the outermost WithinTransaction
+// returns err, which keeps the cause so a deadlock is still retried.
+func abort(state *txState, err error) error {
+	state.tx.Rollback()
+
+	if *state.aborted == nil {
+		*state.aborted = err
+	}
+
+	return *state.aborted
+}
+
+// Conn returns the transaction stored in ctx, or db when there is none.
+func Conn(ctx context.Context, db *sql.DB) DBTX {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.tx
+	}
+
+	return db
+}
+
+// Active reports whether ctx carries a transaction.
+func Active(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*txState)
+	return ok
+}
+
+// AfterCommit runs f once the transaction in ctx has committed, or right away
+// when ctx carries none. f is dropped when the transaction rolls back; a
+// savepoint rolled back inside a committed transaction does not drop it.
+func AfterCommit(ctx context.Context, f func()) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		f()
+		return
+	}
+
+	*state.afterCommit = append(*state.afterCommit, f)
+}
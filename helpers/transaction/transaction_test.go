@@ -0,0 +1,312 @@
+package transaction_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+
+	"waizly/helpers/transaction"
+)
+
+var errRetry = errors.New("deadlock")
+
+func open(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "tx.db")+"?_pragma=busy_timeout(5000)&_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE item (name TEXT NOT NULL)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func insert(ctx context.Context, db *sql.DB, name string) error {
+	_, err := transaction.Conn(ctx, db).ExecContext(ctx, `INSERT INTO item (name) VALUES (?)`, name)
+
+	return err
+}
+
+func names(t *testing.T, db *sql.DB) []string {
+	rows, err := db.Query(`SELECT name FROM item ORDER BY rowid`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func TestWithinTransaction(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Commit", func(t *testing.T) {
+		db := open(t)
+
+		err := transaction.NewTransactor(db).WithinTransaction(ctx, func(ctx context.Context) error {
+			return insert(ctx, db, "a")
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a"}, names(t, db))
+	})
+
+	t.Run("Rollback On Error", func(t *testing.T) {
+		db := open(t)
+		failure := errors.New("failure")
+
+		err := transaction.NewTransactor(db).WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := insert(ctx, db, "a"); err != nil {
+				return err
+			}
+
+			return failure
+		})
+
+		assert.Equal(t, failure, err)
+		assert.Empty(t, names(t, db))
+	})
+
+	t.Run("Rollback On Panic", func(t *testing.T) {
+		db := open(t)
+		transactor := transaction.NewTransactor(db)
+
+		assert.PanicsWithValue(t, "boom", func() {
+			transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				insert(ctx, db, "a")
+				panic("boom")
+			})
+		})
+
+		assert.Empty(t, names(t, db))
+
+		// The connection went back to the pool without an open transaction.
+		assert.NoError(t, insert(ctx, db, "b"))
+		assert.Equal(t, []string{"b"}, names(t, db))
+	})
+
+	t.Run("Nested Savepoint", func(t *testing.T) {
+		db := open(t)
+		transactor := transaction.NewTransactor(db)
+		failure := errors.New("failure")
+
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := insert(ctx, db, "outer"); err != nil {
+				return err
+			}
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				if err := insert(ctx, db, "discarded"); err != nil {
+					return err
+				}
+
+				return failure
+			})
+			assert.Equal(t, failure, err)
+
+			err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					return insert(ctx, db, "kept")
+				})
+			})
+			assert.NoError(t, err)
+
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"outer", "kept"}, names(t, db))
+	})
+
+	t.Run("Nested Panic", func(t *testing.T) {
+		db := open(t)
+		transactor := transaction.NewTransactor(db)
+
+		assert.Panics(t, func() {
+			transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				insert(ctx, db, "outer")
+
+				return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					panic("boom")
+				})
+			})
+		})
+
+		assert.Empty(t, names(t, db))
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		db := open(t)
+		transactor := transaction.NewTransactor(db, transaction.WithRetry(3, func(err error) bool {
+			return errors.Is(err, errRetry)
+		}))
+
+		attempts := 0
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			attempts++
+			if err := insert(ctx, db, "a"); err != nil {
+				return err
+			}
+
+			if attempts < 3 {
+				return errRetry
+			}
+
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, []string{"a"}, names(t, db), "failed attempts must leave nothing behind")
+	})
+
+	t.Run("Retry Gives Up", func(t *testing.T) {
+		db := open(t)
+		transactor := transaction.NewTransactor(db, transaction.WithRetry(2, func(err error) bool {
+			return errors.Is(err, errRetry)
+		}))
+
+		attempts := 0
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			attempts++
+			return errRetry
+		})
+
+		assert.Equal(t, errRetry, err)
+		assert.Equal(t, 2, attempts)
+
+		attempts = 0
+		err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			attempts++
+			return errors.New("permanent")
+		})
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, attempts, "only retryable errors are retried")
+	})
+
+	t.Run("Nested Is Not Retried", func(t *testing.T) {
+		db := open(t)
+		transactor := transaction.NewTransactor(db, transaction.WithRetry(3, func(err error) bool {
+			return errors.Is(err, errRetry)
+		}))
+
+		outer, inner := 0, 0
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			outer++
+
+			return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				inner++
+				return errRetry
+			})
+		})
+
+		assert.Equal(t, errRetry, err)
+		assert.Equal(t, 3, outer)
+		assert.Equal(t, 3, inner, "the whole transaction is retried, not the savepoint")
+	})
+
+	t.Run("Lost Savepoint Aborts", func(t *testing.T) {
+		db := open(t)
+		transactor := transaction.NewTransactor(db, transaction.WithRetry(2, func(err error) bool {
+			return errors.Is(err, errRetry)
+		}))
+
+		attempts := 0
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			attempts++
+
+			err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				if attempts == 1 {
+					// Stands in for a database that dropped the savepoint
+					// together with the transaction after a deadlock.
+					transaction.Conn(ctx, db).ExecContext(ctx, "RELEASE SAVEPOINT sp_1")
+					return errRetry
+				}
+
+				return insert(ctx, db, "audit")
+			})
+			assert.Equal(t, attempts == 1, err != nil)
+
+			// The caller ignores the failure, as a best-effort write does,
+			// but nothing may be committed after the savepoint was lost.
+			insert(ctx, db, fmt.Sprintf("account %d", attempts))
+
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, attempts, "the abort keeps the deadlock as its cause and is retried")
+		assert.Equal(t, []string{"audit", "account 2"}, names(t, db))
+
+		err = transaction.NewTransactor(db).WithinTransaction(ctx, func(ctx context.Context) error {
+			transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				transaction.Conn(ctx, db).ExecContext(ctx, "RELEASE SAVEPOINT sp_1")
+				return errors.New("failure")
+			})
+
+			return insert(ctx, db, "lost")
+		})
+
+		assert.ErrorContains(t, err, "roll back to savepoint sp_1")
+		assert.Equal(t, []string{"audit", "account 2"}, names(t, db))
+	})
+
+	t.Run("After Commit", func(t *testing.T) {
+		db := open(t)
+		transactor := transaction.NewTransactor(db)
+
+		var ran []string
+		transaction.AfterCommit(ctx, func() { ran = append(ran, "no transaction") })
+		assert.Equal(t, []string{"no transaction"}, ran)
+
+		ran = nil
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			assert.True(t, transaction.Active(ctx))
+			transaction.AfterCommit(ctx, func() { ran = append(ran, "outer") })
+
+			transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				transaction.AfterCommit(ctx, func() { ran = append(ran, "savepoint") })
+				return errors.New("failure")
+			})
+
+			assert.Empty(t, ran, "nothing runs before the commit")
+
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"outer", "savepoint"}, ran)
+		assert.False(t, transaction.Active(ctx))
+
+		ran = nil
+		err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			transaction.AfterCommit(ctx, func() { ran = append(ran, "rolled back") })
+			return errors.New("failure")
+		})
+
+		assert.Error(t, err)
+		assert.Empty(t, ran)
+	})
+}
@@ -0,0 +1,383 @@
+// Package accounttest holds the conformance suite every
+// account.AccountRepository implementation must pass.
+package accounttest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/exception"
+	"waizly/internal/account"
+	"waizly/models"
+)
+
+// Options relaxes the suite for backends that cannot provide a guarantee
+// yet.
+type Options struct {
+	// SkipConflicts skips the checks that a duplicate email or username is
+	// rejected with exception.ErrConflicted, for a repository running on a
+	// driver whose error codes it does not know.
+	SkipConflicts bool
+
+	// OmitsPassword expects FindByID to leave the password hash out, for a
+	// repository that keeps accounts where it must not store the hash.
+	OmitsPassword bool
+}
+
+// Run checks the behaviour the use cases rely on. newRepository is called
+// once per subtest and must return a repository with no accounts.
+//
+// Timestamps are whole seconds in UTC so they survive any column type.
+func Run(t *testing.T, newRepository func(t *testing.T) account.AccountRepository, opts Options) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	t.Run("Create Assigns Increasing IDs", func(t *testing.T) {
+		repo := newRepository(t)
+
+		first := create(t, repo, "first")
+		second := create(t, repo, "second")
+
+		assert.Positive(t, first)
+		assert.Greater(t, second, first)
+	})
+
+	t.Run("Create Defaults Role", func(t *testing.T) {
+		repo := newRepository(t)
+
+		found, err := repo.FindByID(ctx, create(t, repo, "jane"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, account.RoleUser, found.Role)
+	})
+
+	t.Run("Find Round Trip", func(t *testing.T) {
+		repo := newRepository(t)
+
+		params := newAccount("jane")
+		params.CreatedAt = now
+		params.Role = account.RoleAdmin
+
+		id, err := repo.Create(ctx, params)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		byID, err := repo.FindByID(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, id, byID.ID)
+		assert.Equal(t, params.Username, byID.Username)
+		if opts.OmitsPassword {
+			assert.Empty(t, byID.Password)
+		} else {
+			assert.Equal(t, params.Password, byID.Password)
+		}
+		assert.Equal(t, params.Email, byID.Email)
+		assert.Equal(t, account.RoleAdmin, byID.Role)
+		assert.True(t, now.Equal(byID.CreatedAt), "created_at %s, want %s", byID.CreatedAt, now)
+		assert.Nil(t, byID.DisabledAt)
+		assert.Nil(t, byID.SessionsRevokedAt)
+
+		byEmail, err := repo.FindByEmail(ctx, params.Email)
+		assert.NoError(t, err)
+		assert.Equal(t, id, byEmail.ID)
+	})
+
+	t.Run("Find Missing", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.FindByID(ctx, 42)
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+
+		_, err = repo.FindByEmail(ctx, "nobody@example.com")
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+	})
+
+	t.Run("List Pages By ID", func(t *testing.T) {
+		repo := newRepository(t)
+
+		ids := []int64{create(t, repo, "a"), create(t, repo, "b"), create(t, repo, "c")}
+
+		page, err := repo.List(ctx, 0, 2)
+		if assert.NoError(t, err) && assert.Len(t, page, 2) {
+			assert.Equal(t, ids[0], page[0].ID)
+			assert.Equal(t, ids[1], page[1].ID)
+			assert.Empty(t, page[0].Password, "List must not return password hashes")
+		}
+
+		page, err = repo.List(ctx, ids[1], 2)
+		if assert.NoError(t, err) && assert.Len(t, page, 1) {
+			assert.Equal(t, ids[2], page[0].ID)
+		}
+
+		page, err = repo.List(ctx, ids[2], 2)
+		assert.NoError(t, err)
+		assert.NotNil(t, page)
+		assert.Empty(t, page)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepository(t)
+
+		id := create(t, repo, "jane")
+
+		params := newAccount("janet")
+		params.UpdateAt = now
+		params.Role = account.RoleAdmin
+		params.DisabledAt = &now
+		params.SessionsRevokedAt = &now
+		params.Version = 1
+
+		assert.NoError(t, repo.Update(ctx, id, params))
+
+		found, err := repo.FindByID(ctx, id)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "janet", found.Username)
+			assert.Equal(t, params.Email, found.Email)
+			assert.Equal(t, account.RoleAdmin, found.Role)
+			assert.True(t, now.Equal(found.UpdateAt), "update_at %s, want %s", found.UpdateAt, now)
+			if assert.NotNil(t, found.DisabledAt) {
+				assert.True(t, now.Equal(*found.DisabledAt))
+			}
+			if assert.NotNil(t, found.SessionsRevokedAt) {
+				assert.True(t, now.Equal(*found.SessionsRevokedAt))
+			}
+		}
+
+		assert.ErrorIs(t, repo.Update(ctx, id+100, params), exception.ErrNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepository(t)
+
+		id := create(t, repo, "jane")
+
+		assert.NoError(t, repo.Delete(ctx, id, 0))
+
+		_, err := repo.FindByID(ctx, id)
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+		assert.ErrorIs(t, repo.Delete(ctx, id, 0), exception.ErrNotFound)
+	})
+
+	t.Run("Versions", func(t *testing.T) {
+		repo := newRepository(t)
+
+		id := create(t, repo, "jane")
+
+		found, err := repo.FindByID(ctx, id)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, int64(1), found.Version)
+
+		stale := found
+		found.Username = "janet"
+		found.UpdateAt = now
+		assert.NoError(t, repo.Update(ctx, id, found))
+
+		found, err = repo.FindByID(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), found.Version)
+
+		stale.Username = "overwritten"
+		stale.UpdateAt = now
+		err = repo.Update(ctx, id, stale)
+		assert.ErrorIs(t, err, exception.ErrConflicted)
+		assert.ErrorIs(t, err, exception.ErrVersionMismatch)
+
+		found, err = repo.FindByID(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, "janet", found.Username, "a stale update must not apply")
+
+		err = repo.Delete(ctx, id, 1)
+		assert.ErrorIs(t, err, exception.ErrVersionMismatch)
+
+		err = repo.Update(ctx, id+100, found)
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+		assert.NotErrorIs(t, err, exception.ErrVersionMismatch)
+
+		assert.NoError(t, repo.Delete(ctx, id, 2))
+	})
+
+	t.Run("Concurrent Updates", func(t *testing.T) {
+		repo := newRepository(t)
+
+		id := create(t, repo, "jane")
+
+		const n = 8
+
+		var wg sync.WaitGroup
+		errs := make([]error, n)
+
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				params := newAccount("jane")
+				params.Password = fmt.Sprintf("hash-%d", i)
+				params.UpdateAt = now
+				params.Role = account.RoleUser
+				params.Version = 1
+
+				errs[i] = repo.Update(ctx, id, params)
+			}(i)
+		}
+		wg.Wait()
+
+		updated := 0
+		for _, err := range errs {
+			if err == nil {
+				updated++
+				continue
+			}
+
+			assert.ErrorIs(t, err, exception.ErrVersionMismatch)
+		}
+
+		assert.Equal(t, 1, updated, "exactly one update of version 1 must win")
+	})
+
+	t.Run("Unique Email", func(t *testing.T) {
+		if opts.SkipConflicts {
+			t.Skip("conflicts are not mapped on this driver")
+		}
+
+		repo := newRepository(t)
+
+		create(t, repo, "jane")
+		other := create(t, repo, "john")
+
+		params := newAccount("janet")
+		params.Email = "JANE@example.com"
+
+		_, err := repo.Create(ctx, params)
+		assert.ErrorIs(t, err, exception.ErrConflicted, "emails compare case-insensitively")
+
+		params = newAccount("john")
+		params.Email = "jane@example.com"
+		params.UpdateAt = now
+		params.Role = account.RoleUser
+		params.Version = 1
+
+		err = repo.Update(ctx, other, params)
+		assert.ErrorIs(t, err, exception.ErrConflicted)
+		assert.NotErrorIs(t, err, exception.ErrVersionMismatch)
+	})
+
+	t.Run("Unique Username", func(t *testing.T) {
+		if opts.SkipConflicts {
+			t.Skip("conflicts are not mapped on this driver")
+		}
+
+		repo := newRepository(t)
+
+		create(t, repo, "jane")
+		other := create(t, repo, "john")
+
+		params := newAccount("jane")
+		params.Email = "someone-else@example.com"
+
+		_, err := repo.Create(ctx, params)
+		assert.ErrorIs(t, err, exception.ErrConflicted)
+
+		params = newAccount("jane")
+		params.Email = "john@example.com"
+		params.UpdateAt = now
+		params.Role = account.RoleUser
+		params.Version = 1
+
+		err = repo.Update(ctx, other, params)
+		assert.ErrorIs(t, err, exception.ErrConflicted)
+		assert.NotErrorIs(t, err, exception.ErrVersionMismatch)
+	})
+
+	t.Run("Concurrent Duplicate Creates", func(t *testing.T) {
+		if opts.SkipConflicts {
+			t.Skip("conflicts are not mapped on this driver")
+		}
+
+		repo := newRepository(t)
+
+		const n = 8
+
+		var wg sync.WaitGroup
+		errs := make([]error, n)
+
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = repo.Create(ctx, newAccount("jane"))
+			}(i)
+		}
+		wg.Wait()
+
+		created := 0
+		for _, err := range errs {
+			if err == nil {
+				created++
+				continue
+			}
+
+			assert.ErrorIs(t, err, exception.ErrConflicted)
+		}
+
+		assert.Equal(t, 1, created, "exactly one create must win")
+	})
+
+	t.Run("Concurrent Creates", func(t *testing.T) {
+		repo := newRepository(t)
+
+		const n = 8
+
+		var wg sync.WaitGroup
+		ids := make([]int64, n)
+		errs := make([]error, n)
+
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				ids[i], errs[i] = repo.Create(ctx, newAccount(fmt.Sprintf("user%d", i)))
+			}(i)
+		}
+		wg.Wait()
+
+		seen := map[int64]bool{}
+		for i := range ids {
+			assert.NoError(t, errs[i])
+			assert.False(t, seen[ids[i]], "id %d assigned twice", ids[i])
+			seen[ids[i]] = true
+		}
+
+		accounts, err := repo.List(ctx, 0, 2*n)
+		assert.NoError(t, err)
+		assert.Len(t, accounts, n)
+	})
+}
+
+func newAccount(username string) models.Account {
+	return models.Account{
+		Username:  username,
+		Password:  "hash-of-" + username,
+		Email:     username + "@example.com",
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func create(t *testing.T, repo account.AccountRepository, username string) int64 {
+	t.Helper()
+
+	id, err := repo.Create(context.Background(), newAccount(username))
+	if err != nil {
+		t.Fatalf("create %s: %v", username, err)
+	}
+
+	return id
+}
@@ -0,0 +1,189 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"waizly/helpers/cache"
+	"waizly/helpers/exception"
+	"waizly/helpers/logger"
+	"waizly/helpers/metrics"
+	"waizly/helpers/transaction"
+	"waizly/models"
+)
+
+// loadTimeout bounds a load shared by concurrent misses. It runs detached
+// from the caller that started it, so it outlives a caller that gave up; the
+// repository still bounds every query with its own timeout.
+const loadTimeout = 30 * time.Second
+
+type cachedRepository struct {
+	AccountRepository
+	cache   cache.Cache
+	ttl     time.Duration
+	metrics *metrics.Metrics
+	loads   singleflight.Group
+
+	// generation grows with every invalidation. A load only stores what it
+	// read when no invalidation happened meanwhile, so a read racing a write
+	// cannot put the old row back.
+	generation atomic.Uint64
+}
+
+// NewCachedRepository answers FindByID from c, loading misses from repo and
+// keeping them for ttl. Concurrent misses for one account share a single
+// load. Update and Delete drop the entry, again once their transaction has
+// committed. Calls inside a transaction bypass the cache, and a cache that
+// fails is skipped rather than failing the call.
+//
+// The password hash is never cached, so FindByID outside a transaction
+// returns it empty. Code that needs the hash reads it in a transaction or
+// through FindByEmail.
+func NewCachedRepository(repo AccountRepository, c cache.Cache, ttl time.Duration, m *metrics.Metrics) AccountRepository {
+	return &cachedRepository{
+		AccountRepository: repo,
+		cache:             c,
+		ttl:               ttl,
+		metrics:           m,
+	}
+}
+
+func (cr *cachedRepository) FindByID(ctx context.Context, id int64) (models.Account, error) {
+	// A transaction has to see its own writes.
+	if transaction.Active(ctx) {
+		return cr.AccountRepository.FindByID(ctx, id)
+	}
+
+	key := cacheKey(id)
+
+	account, ok := cr.lookup(ctx, key)
+	if ok {
+		return account, nil
+	}
+
+	loaded := cr.loads.DoChan(key, func() (interface{}, error) {
+		// Every waiter shares this load, so the first one cancelling must
+		// not fail the others.
+		ctx, cancel := context.WithTimeout(detach(ctx), loadTimeout)
+		defer cancel()
+
+		generation := cr.generation.Load()
+
+		account, err := cr.AccountRepository.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		account.Password = ""
+
+		if cr.generation.Load() == generation {
+			cr.store(ctx, key, account)
+		}
+
+		return account, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return models.Account{}, exception.Database("AccountRepository.FindByID", ctx.Err())
+	case result := <-loaded:
+		if result.Err != nil {
+			return models.Account{}, result.Err
+		}
+
+		return copyAccount(result.Val.(models.Account)), nil
+	}
+}
+
+func (cr *cachedRepository) Update(ctx context.Context, id int64, params models.Account) error {
+	defer cr.invalidate(ctx, id)
+	return cr.AccountRepository.Update(ctx, id, params)
+}
+
+func (cr *cachedRepository) Delete(ctx context.Context, id int64, version int64) error {
+	defer cr.invalidate(ctx, id)
+	return cr.AccountRepository.Delete(ctx, id, version)
+}
+
+func (cr *cachedRepository) lookup(ctx context.Context, key string) (models.Account, bool) {
+	var account models.Account
+
+	value, ok, err := cr.cache.Get(ctx, key)
+	if err == nil && ok {
+		err = json.Unmarshal(value, &account)
+	}
+
+	switch {
+	case err != nil:
+		logger.Ctx(ctx).Warn("AccountRepository.cache", "key", key, "error", err)
+		cr.metrics.CacheRequests.WithLabelValues("account", "error").Inc()
+		return models.Account{}, false
+	case !ok:
+		cr.metrics.CacheRequests.WithLabelValues("account", "miss").Inc()
+		return models.Account{}, false
+	default:
+		cr.metrics.CacheRequests.WithLabelValues("account", "hit").Inc()
+		return account, true
+	}
+}
+
+func (cr *cachedRepository) store(ctx context.Context, key string, account models.Account) {
+	value, err := json.Marshal(account)
+	if err == nil {
+		err = cr.cache.Set(ctx, key, value, cr.ttl)
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Warn("AccountRepository.cache", "key", key, "error", err)
+	}
+}
+
+// invalidate drops the entry of id now, for readers outside the transaction,
+// and again after the commit, since until then they may load and cache the
+// row as it was before the write.
+func (cr *cachedRepository) invalidate(ctx context.Context, id int64) {
+	key := cacheKey(id)
+
+	drop := func() {
+		cr.generation.Add(1)
+		cr.loads.Forget(key)
+
+		err := cr.cache.Delete(ctx, key)
+		if err != nil {
+			logger.Ctx(ctx).Error("AccountRepository.cache", "key", key, "error", err)
+		}
+	}
+
+	drop()
+
+	if transaction.Active(ctx) {
+		transaction.AfterCommit(ctx, drop)
+	}
+}
+
+// detachedContext keeps the values of its parent, the trace id for logs
+// among them, but none of its deadline or cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
+
+func cacheKey(id int64) string {
+	return "account:" + strconv.FormatInt(id, 10)
+}
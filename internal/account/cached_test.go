@@ -0,0 +1,236 @@
+package account_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"waizly/helpers/cache"
+	"waizly/helpers/exception"
+	"waizly/helpers/metrics"
+	"waizly/helpers/transaction"
+	"waizly/internal/account"
+	"waizly/internal/account/mocks"
+	"waizly/models"
+)
+
+func cacheRequests(m *metrics.Metrics, result string) float64 {
+	return testutil.ToFloat64(m.CacheRequests.WithLabelValues("account", result))
+}
+
+func TestCachedRepository(t *testing.T) {
+	ctx := context.Background()
+	jane := models.Account{ID: 1, Username: "jane", Email: "jane@test.com", Version: 1}
+
+	t.Run("Hit And Miss", func(t *testing.T) {
+		m := metrics.New()
+
+		repository := new(mocks.AccountRepository)
+		repository.On("FindByID", mock.Anything, int64(1)).Return(jane, nil).Once()
+		repository.On("FindByID", mock.Anything, int64(2)).Return(models.Account{}, exception.ErrNotFound).Twice()
+
+		cached := account.NewCachedRepository(repository, cache.NewLRU(10), time.Minute, m)
+
+		for i := 0; i < 3; i++ {
+			found, err := cached.FindByID(ctx, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, jane, found)
+		}
+
+		// A missing account is not cached.
+		for i := 0; i < 2; i++ {
+			_, err := cached.FindByID(ctx, 2)
+			assert.ErrorIs(t, err, exception.ErrNotFound)
+		}
+
+		assert.Equal(t, float64(2), cacheRequests(m, "hit"))
+		assert.Equal(t, float64(3), cacheRequests(m, "miss"))
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("Writes Invalidate", func(t *testing.T) {
+		updated := jane
+		updated.Version = 2
+
+		repository := new(mocks.AccountRepository)
+		repository.On("FindByID", mock.Anything, int64(1)).Return(jane, nil).Once()
+		repository.On("Update", mock.Anything, int64(1), jane).Return(nil)
+		repository.On("FindByID", mock.Anything, int64(1)).Return(updated, nil).Once()
+		repository.On("Delete", mock.Anything, int64(1), int64(2)).Return(nil)
+		repository.On("FindByID", mock.Anything, int64(1)).Return(models.Account{}, exception.ErrNotFound).Once()
+
+		cached := account.NewCachedRepository(repository, cache.NewLRU(10), time.Minute, metrics.New())
+
+		found, _ := cached.FindByID(ctx, 1)
+		assert.Equal(t, int64(1), found.Version)
+
+		assert.NoError(t, cached.Update(ctx, 1, jane))
+
+		found, _ = cached.FindByID(ctx, 1)
+		assert.Equal(t, int64(2), found.Version)
+
+		assert.NoError(t, cached.Delete(ctx, 1, 2))
+
+		_, err := cached.FindByID(ctx, 1)
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("Concurrent Misses Share A Load", func(t *testing.T) {
+		release := make(chan time.Time)
+
+		repository := new(mocks.AccountRepository)
+		repository.On("FindByID", mock.Anything, int64(1)).WaitUntil(release).Return(jane, nil).Once()
+
+		cached := account.NewCachedRepository(repository, cache.NewLRU(10), time.Minute, metrics.New())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				found, err := cached.FindByID(ctx, 1)
+				assert.NoError(t, err)
+				assert.Equal(t, jane, found)
+			}()
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		repository.AssertNumberOfCalls(t, "FindByID", 1)
+	})
+
+	t.Run("Password Is Not Cached", func(t *testing.T) {
+		c := cache.NewLRU(10)
+		withHash := jane
+		withHash.Password = "$2a$10$hash"
+
+		repository := new(mocks.AccountRepository)
+		repository.On("FindByID", mock.Anything, int64(1)).Return(withHash, nil).Once()
+
+		cached := account.NewCachedRepository(repository, c, time.Minute, metrics.New())
+
+		found, err := cached.FindByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Empty(t, found.Password)
+
+		value, ok, err := c.Get(ctx, "account:1")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NotContains(t, string(value), "$2a$10$hash")
+	})
+
+	t.Run("Cancelled Caller Leaves Shared Load Running", func(t *testing.T) {
+		release := make(chan time.Time)
+
+		repository := new(mocks.AccountRepository)
+		repository.On("FindByID", mock.Anything, int64(1)).WaitUntil(release).Return(jane, nil).Run(func(args mock.Arguments) {
+			assert.NoError(t, args.Get(0).(context.Context).Err())
+		}).Once()
+
+		cached := account.NewCachedRepository(repository, cache.NewLRU(10), time.Minute, metrics.New())
+
+		first, cancel := context.WithCancel(ctx)
+		done := make(chan error)
+		go func() {
+			_, err := cached.FindByID(first, 1)
+			done <- err
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+
+		var found models.Account
+		var err error
+		waited := make(chan struct{})
+		go func() {
+			found, err = cached.FindByID(ctx, 1)
+			close(waited)
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+		assert.Error(t, <-done)
+
+		close(release)
+		<-waited
+
+		assert.NoError(t, err)
+		assert.Equal(t, jane, found)
+		repository.AssertNumberOfCalls(t, "FindByID", 1)
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		db := openSQLite(t)
+		updated := jane
+		updated.Version = 2
+
+		repository := new(mocks.AccountRepository)
+		repository.On("FindByID", mock.Anything, int64(1)).Return(jane, nil).Twice()
+		repository.On("Update", mock.Anything, int64(1), jane).Return(nil)
+		repository.On("FindByID", mock.Anything, int64(1)).Return(updated, nil).Once()
+
+		cached := account.NewCachedRepository(repository, cache.NewLRU(10), time.Minute, metrics.New())
+
+		err := transaction.NewTransactor(db).WithinTransaction(ctx, func(txCtx context.Context) error {
+			// Inside the transaction the cache is bypassed.
+			cached.FindByID(txCtx, 1)
+
+			err := cached.Update(txCtx, 1, jane)
+			if err != nil {
+				return err
+			}
+
+			// Until the commit other readers still see and cache the old row.
+			found, _ := cached.FindByID(ctx, 1)
+			assert.Equal(t, int64(1), found.Version)
+
+			return nil
+		})
+		assert.NoError(t, err)
+
+		found, _ := cached.FindByID(ctx, 1)
+		assert.Equal(t, int64(2), found.Version, "the commit drops the entry cached meanwhile")
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("Redis", func(t *testing.T) {
+		m := metrics.New()
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		repository := new(mocks.AccountRepository)
+		repository.On("FindByID", mock.Anything, int64(1)).Return(jane, nil)
+
+		cached := account.NewCachedRepository(repository, cache.NewRedis(client, "waizly:"), time.Minute, m)
+
+		for i := 0; i < 2; i++ {
+			found, err := cached.FindByID(ctx, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, jane, found)
+		}
+
+		assert.True(t, server.Exists("waizly:account:1"))
+		assert.Equal(t, time.Minute, server.TTL("waizly:account:1"))
+		repository.AssertNumberOfCalls(t, "FindByID", 1)
+
+		// Without the cache calls go straight to the repository.
+		server.Close()
+
+		found, err := cached.FindByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, jane, found)
+		assert.Equal(t, float64(1), cacheRequests(m, "error"))
+		repository.AssertNumberOfCalls(t, "FindByID", 2)
+	})
+}
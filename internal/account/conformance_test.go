@@ -0,0 +1,116 @@
+package account_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+
+	"waizly/db/migration"
+	"waizly/helpers/cache"
+	"waizly/helpers/dialect"
+	"waizly/helpers/metrics"
+	"waizly/internal/account"
+	"waizly/internal/account/accounttest"
+	"waizly/internal/constant"
+	"waizly/internal/migrator"
+)
+
+func TestConformance(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		accounttest.Run(t, func(t *testing.T) account.AccountRepository {
+			return account.NewMemoryAccountRepository()
+		}, accounttest.Options{})
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		accounttest.Run(t, func(t *testing.T) account.AccountRepository {
+			return account.NewSQLiteAccountRepository(openSQLite(t), constant.TableAccount)
+		}, accounttest.Options{})
+	})
+
+	t.Run("cached sqlite", func(t *testing.T) {
+		accounttest.Run(t, func(t *testing.T) account.AccountRepository {
+			repo := account.NewSQLiteAccountRepository(openSQLite(t), constant.TableAccount)
+
+			return account.NewCachedRepository(repo, cache.NewLRU(100), time.Minute, metrics.New())
+		}, accounttest.Options{OmitsPassword: true})
+	})
+
+	// The replica reads the same file as the primary, so the suite checks
+	// that routing is invisible to callers.
+	t.Run("replicated sqlite", func(t *testing.T) {
+		accounttest.Run(t, func(t *testing.T) account.AccountRepository {
+			db := openSQLite(t)
+			primary := account.NewSQLiteAccountRepository(db, constant.TableAccount)
+			replicas := []account.AccountRepository{account.NewSQLiteAccountRepository(db, constant.TableAccount)}
+
+			return account.NewReplicatedRepository(primary, replicas, healthyPool(t, 1), time.Minute)
+		}, accounttest.Options{})
+	})
+
+	// The MySQL repository's SQL also runs on SQLite, which covers it when no
+	// MySQL server is around. It only knows MySQL error codes, so conflicts
+	// are left to the mysql run.
+	t.Run("mysql on sqlite", func(t *testing.T) {
+		accounttest.Run(t, func(t *testing.T) account.AccountRepository {
+			return account.NewAccountRepository(openSQLite(t), constant.TableAccount)
+		}, accounttest.Options{SkipConflicts: true})
+	})
+
+	for _, d := range []dialect.Dialect{dialect.MySQL, dialect.Postgres} {
+		d := d
+
+		t.Run(string(d), func(t *testing.T) {
+			db := openServer(t, d)
+
+			accounttest.Run(t, func(t *testing.T) account.AccountRepository {
+				_, err := db.Exec("DELETE FROM " + constant.TableAccount)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return account.NewRepository(db, d, constant.TableAccount)
+			}, accounttest.Options{})
+		})
+	}
+}
+
+// openServer connects to the database named by WAIZLY_TEST_MYSQL_DSN or
+// WAIZLY_TEST_POSTGRES_DSN and migrates it, or skips the test when the
+// variable is unset. The account table is emptied, so never point it at
+// real data.
+func openServer(t *testing.T, d dialect.Dialect) *sql.DB {
+	env := map[dialect.Dialect]string{
+		dialect.MySQL:    "WAIZLY_TEST_MYSQL_DSN",
+		dialect.Postgres: "WAIZLY_TEST_POSTGRES_DSN",
+	}[d]
+
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skipf("%s is not set", env)
+	}
+
+	db, err := sql.Open(string(d), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := migrator.Load(migration.For(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrator.New(db, d, migrations, constant.TableSchemaMigrations).Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
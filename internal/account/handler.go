@@ -3,6 +3,8 @@ package account
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 
 	newJWT "github.com/dgrijalva/jwt-go"
 	"github.com/go-playground/validator/v10"
@@ -15,16 +17,33 @@ import (
 )
 
 type AccountHandler struct {
-	Validate *validator.Validate
-	UseCase  AccountUseCase
+	Validate        *validator.Validate
+	UseCase         AccountUseCase
+	IfMatchRequired bool
 }
 
-func NewAccountHandler(router *mux.Router, validate *validator.Validate, usecase AccountUseCase) {
+// HandlerOption configures the handler built by NewAccountHandler.
+type HandlerOption func(handler *AccountHandler)
+
+// RequireIfMatch makes updates and deletes without an If-Match header fail
+// with 428 Precondition Required, so clients cannot overwrite changes they
+// have not seen.
+func RequireIfMatch(required bool) HandlerOption {
+	return func(handler *AccountHandler) {
+		handler.IfMatchRequired = required
+	}
+}
+
+func NewAccountHandler(router *mux.Router, validate *validator.Validate, usecase AccountUseCase, opts ...HandlerOption) {
 	handler := &AccountHandler{
 		Validate: validate,
 		UseCase:  usecase,
 	}
 
+	for _, opt := range opts {
+		opt(handler)
+	}
+
 	router.HandleFunc("/account/register", handler.Register).Methods(http.MethodPost)
 	router.HandleFunc("/account/login", handler.Login).Methods(http.MethodPost)
 	router.HandleFunc("/account/detail", handler.DetailAccount).Methods(http.MethodGet)
@@ -41,20 +60,20 @@ func (handler *AccountHandler) Register(w http.ResponseWriter, r *http.Request)
 	err := json.NewDecoder(r.Body).Decode(&params)
 	if err != nil {
 		res = response.Error(response.StatusUnprocessableEntity, err)
-		res.JSON(w)
+		res.Render(w, r)
 		return
 	}
 
 	err = handler.Validate.StructCtx(ctx, params)
 	if err != nil {
 		res = response.Error(response.StatusBadRequest, err)
-		res.JSON(w)
+		res.Render(w, r)
 		return
 	}
 
 	res = handler.UseCase.Register(ctx, params)
 
-	res.JSON(w)
+	res.Render(w, r)
 }
 
 func (handler *AccountHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -66,14 +85,14 @@ func (handler *AccountHandler) Login(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(&params)
 	if err != nil {
 		res = response.Error(response.StatusUnprocessableEntity, err)
-		res.JSON(w)
+		res.Render(w, r)
 		return
 	}
 
 	err = handler.Validate.StructCtx(ctx, params)
 	if err != nil {
 		res = response.Error(response.StatusBadRequest, err)
-		res.JSON(w)
+		res.Render(w, r)
 		return
 	}
 
@@ -96,36 +115,22 @@ func (handler *AccountHandler) Login(w http.ResponseWriter, r *http.Request) {
 		HttpOnly: true,
 	})
 
-	res.JSON(w)
+	res.Render(w, r)
 }
 
 func (handler *AccountHandler) DetailAccount(w http.ResponseWriter, r *http.Request) {
 	var res response.Response
 	ctx := r.Context()
 
-	c, err := r.Cookie("token")
-	if err != nil {
-		res = response.Error(response.StatusUnauthorized, exception.ErrUnauthorized)
-		res.JSON(w)
-		return
-	}
-
-	tokenString := c.Value
-	claims := &jwt.JWTclaim{}
-
-	newJWT.ParseWithClaims(tokenString, claims, func(t *newJWT.Token) (interface{}, error) {
-		return jwt.JWT_KEY, nil
-	})
-
-	if claims.ID == 0 {
-		res = response.Error(response.StatusUnauthorized, exception.ErrUnauthorized)
-		res.JSON(w)
+	id, ok := handler.authenticate(w, r)
+	if !ok {
 		return
 	}
 
-	res = handler.UseCase.DetailAccount(ctx, claims.ID)
+	res = handler.UseCase.DetailAccount(ctx, id)
 
-	res.JSON(w)
+	setETag(w, res)
+	res.Render(w, r)
 }
 
 func (handler *AccountHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
@@ -133,58 +138,162 @@ func (handler *AccountHandler) UpdateAccount(w http.ResponseWriter, r *http.Requ
 	var account models.Account
 	ctx := r.Context()
 
-	c, err := r.Cookie("token")
-	if err != nil {
-		res = response.Error(response.StatusUnauthorized, exception.ErrUnauthorized)
-		res.JSON(w)
+	id, ok := handler.authenticate(w, r)
+	if !ok {
 		return
 	}
 
-	tokenString := c.Value
-	claims := &jwt.JWTclaim{}
-
-	newJWT.ParseWithClaims(tokenString, claims, func(t *newJWT.Token) (interface{}, error) {
-		return jwt.JWT_KEY, nil
-	})
+	version, ok := handler.precondition(w, r)
+	if !ok {
+		return
+	}
 
-	err = json.NewDecoder(r.Body).Decode(&account)
+	err := json.NewDecoder(r.Body).Decode(&account)
 	if err != nil {
 		res = response.Error(response.StatusUnprocessableEntity, exception.ErrBadRequest)
-		res.JSON(w)
+		res.Render(w, r)
 		return
 	}
 
 	err = handler.Validate.StructCtx(ctx, account)
 	if err != nil {
-		res = response.Error(response.StatusBadRequest, exception.ErrBadRequest)
-		res.JSON(w)
+		res = response.Error(response.StatusBadRequest, err)
+		res.Render(w, r)
 		return
 	}
 
-	res = handler.UseCase.UpdateAccount(ctx, claims.ID, account)
+	account.Version = version
+	res = handler.UseCase.UpdateAccount(ctx, id, account)
 
-	res.JSON(w)
+	setETag(w, res)
+	res.Render(w, r)
 }
 
 func (handler *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	var res response.Response
 	ctx := r.Context()
 
-	c, err := r.Cookie("token")
-	if err != nil {
-		res = response.Error(response.StatusUnauthorized, exception.ErrUnauthorized)
-		res.JSON(w)
+	id, ok := handler.authenticate(w, r)
+	if !ok {
 		return
 	}
 
-	tokenString := c.Value
+	version, ok := handler.precondition(w, r)
+	if !ok {
+		return
+	}
+
+	res = handler.UseCase.DeleteAccount(ctx, id, version)
+
+	res.Render(w, r)
+}
+
+// authenticate reads the token from the Authorization bearer header or the
+// token cookie and resolves it to an account ID. The token must carry a
+// valid signature and the account must still be allowed to act; otherwise
+// the error response is written and ok is false.
+func (handler *AccountHandler) authenticate(w http.ResponseWriter, r *http.Request) (id int64, ok bool) {
+	var res response.Response
+
+	token := bearerToken(r)
+	if token == "" {
+		c, err := r.Cookie("token")
+		if err != nil {
+			res = response.Error(response.StatusUnauthorized, exception.ErrUnauthorized)
+			res.Render(w, r)
+			return 0, false
+		}
+
+		token = c.Value
+	}
+
 	claims := &jwt.JWTclaim{}
 
-	newJWT.ParseWithClaims(tokenString, claims, func(t *newJWT.Token) (interface{}, error) {
-		return jwt.JWT_KEY, nil
-	})
+	_, err := newJWT.ParseWithClaims(token, claims, jwt.KeyFunc)
+	if err != nil || claims.ID == 0 {
+		res = response.Error(response.StatusUnauthorized, exception.ErrUnauthorized)
+		res.Render(w, r)
+		return 0, false
+	}
+
+	res = handler.UseCase.Authenticate(r.Context(), *claims)
+	if res.Err() != nil {
+		res.Render(w, r)
+		return 0, false
+	}
+
+	return claims.ID, true
+}
+
+// precondition returns the account version named by the If-Match header, or
+// 0 for any version when the header is absent or "*". A missing header while
+// handler.IfMatchRequired is set, or a tag that cannot be an account's ETag,
+// writes the error response and ok is false. Lists of tags are not
+// supported.
+func (handler *AccountHandler) precondition(w http.ResponseWriter, r *http.Request) (version int64, ok bool) {
+	var res response.Response
+
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+
+	switch {
+	case header == "" && handler.IfMatchRequired:
+		res = response.Error(response.StatusPreconditionRequired, exception.ErrPreconditionRequired)
+	case header == "", header == "*":
+		return 0, true
+	default:
+		version, ok = parseETag(header)
+		if ok {
+			return version, true
+		}
+
+		res = response.Error(response.StatusPreconditionFailed, exception.ErrPreconditionFailed)
+	}
+
+	res.Render(w, r)
+
+	return 0, false
+}
+
+// setETag sends the version of the account carried by res as its ETag.
+func setETag(w http.ResponseWriter, res response.Response) {
+	impl, ok := res.(*response.ResponseImpl)
+	if !ok {
+		return
+	}
+
+	account, ok := impl.Data.(models.Account)
+	if ok && account.Version > 0 {
+		w.Header().Set("ETag", etag(account.Version))
+	}
+}
+
+// etag returns the strong entity tag of an account at version.
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
 
-	res = handler.UseCase.DeleteAccount(ctx, claims.ID)
+// parseETag reverses etag. Weak tags are refused since If-Match compares
+// tags strongly.
+func parseETag(tag string) (version int64, ok bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+func bearerToken(r *http.Request) string {
+	const prefix = "bearer "
+
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
 
-	res.JSON(w)
+	return strings.TrimSpace(header[len(prefix):])
 }
@@ -16,11 +16,16 @@ import (
 
 	"waizly/config/jwt"
 	"waizly/helpers/response"
+	"waizly/helpers/validation"
 	"waizly/internal/account"
 	"waizly/internal/account/mocks"
 	"waizly/models"
 )
 
+func init() {
+	jwt.Configure("0123456789abcdef", nil)
+}
+
 func TestHandler_Register(t *testing.T) {
 	t.Run("Register Success", func(t *testing.T) {
 		req := models.RegisterRequest{
@@ -104,6 +109,50 @@ func TestHandler_Register(t *testing.T) {
 
 		accountUseCase.AssertExpectations(t)
 	})
+
+	t.Run("Register Failed Problem Details", func(t *testing.T) {
+		req := models.RegisterRequest{
+			Username: "test-1",
+			Email:    "not-an-email",
+		}
+
+		accountUseCase := new(mocks.AccountUseCase)
+
+		newReq, err := json.Marshal(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		accountHandler := account.AccountHandler{
+			Validate: validation.New(),
+			UseCase:  accountUseCase,
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/account/register", bytes.NewReader(newReq))
+		r.Header.Set("Accept", response.ContentTypeProblem)
+		recorder := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(accountHandler.Register)
+		handler.ServeHTTP(recorder, r)
+
+		problem := response.Problem{}
+		if err := json.NewDecoder(recorder.Body).Decode(&problem); err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, response.ContentTypeProblem, recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "/problems/validation", problem.Type)
+		assert.Equal(t, "/account/register", problem.Instance)
+		assert.Equal(t, []response.FieldError{
+			{Field: "password", Rule: "required", Message: "password is required"},
+			{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+		}, problem.Errors)
+
+		accountUseCase.AssertExpectations(t)
+	})
 }
 
 func TestHandler_login(t *testing.T) {
@@ -197,6 +246,7 @@ func TestHandler_DetailAccount(t *testing.T) {
 
 		accountUseCase := new(mocks.AccountUseCase)
 		accountUseCase.On("DetailAccount", mock.Anything, mock.AnythingOfType("int64")).Return(resp)
+		accountUseCase.On("Authenticate", mock.Anything, mock.AnythingOfType("jwt.JWTclaim")).Return(response.Success(response.StatusOK, models.Account{}))
 
 		accountHandler := account.AccountHandler{
 			UseCase: accountUseCase,
@@ -244,6 +294,38 @@ func TestHandler_DetailAccount(t *testing.T) {
 		accountUseCase.AssertExpectations(t)
 	})
 
+	t.Run("Get Detail Bearer Token", func(t *testing.T) {
+		accountUseCase := new(mocks.AccountUseCase)
+		accountUseCase.On("DetailAccount", mock.Anything, int64(1)).Return(response.Success(response.StatusOK, models.Account{}))
+		accountUseCase.On("Authenticate", mock.Anything, mock.AnythingOfType("jwt.JWTclaim")).Return(response.Success(response.StatusOK, models.Account{}))
+
+		accountHandler := account.AccountHandler{
+			UseCase: accountUseCase,
+		}
+
+		token, err := newJWT.NewWithClaims(newJWT.SigningMethodHS256, &jwt.JWTclaim{
+			ID: 1,
+			StandardClaims: newJWT.StandardClaims{
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			},
+		}).SignedString(jwt.JWT_KEY)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		r := httptest.NewRequest(http.MethodGet, "/just/for/testing", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+
+		handler := http.HandlerFunc(accountHandler.DetailAccount)
+		handler.ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		accountUseCase.AssertExpectations(t)
+	})
+
 	t.Run("Get Detail Unauthorized", func(t *testing.T) {
 
 		accountUseCase := new(mocks.AccountUseCase)
@@ -305,6 +387,7 @@ func TestHandler_UpdateAccount(t *testing.T) {
 		resp := response.Success(response.StatusOK, models.Account{})
 		accountUseCase := new(mocks.AccountUseCase)
 		accountUseCase.On("UpdateAccount", mock.Anything, mock.AnythingOfType("int64"), mock.AnythingOfType("models.Account")).Return(resp)
+		accountUseCase.On("Authenticate", mock.Anything, mock.AnythingOfType("jwt.JWTclaim")).Return(response.Success(response.StatusOK, models.Account{}))
 
 		reqData, err := json.Marshal(mockData)
 		if err != nil {
@@ -417,6 +500,7 @@ func TestHandler_UpdateAccount(t *testing.T) {
 
 		validate := validator.New()
 		accountUseCase := new(mocks.AccountUseCase)
+		accountUseCase.On("Authenticate", mock.Anything, mock.AnythingOfType("jwt.JWTclaim")).Return(response.Success(response.StatusOK, models.Account{}))
 
 		reqData, err := json.Marshal(mockData)
 		if err != nil {
@@ -467,7 +551,8 @@ func TestHandler_DeleteAccount(t *testing.T) {
 		resp := response.Success(response.StatusOK, data)
 
 		accountUseCase := new(mocks.AccountUseCase)
-		accountUseCase.On("DeleteAccount", mock.Anything, mock.AnythingOfType("int64")).Return(resp)
+		accountUseCase.On("DeleteAccount", mock.Anything, mock.AnythingOfType("int64"), int64(0)).Return(resp)
+		accountUseCase.On("Authenticate", mock.Anything, mock.AnythingOfType("jwt.JWTclaim")).Return(response.Success(response.StatusOK, models.Account{}))
 
 		accountHandler := account.AccountHandler{
 			UseCase: accountUseCase,
@@ -543,3 +628,143 @@ func TestHandler_DeleteAccount(t *testing.T) {
 		assert.Nil(t, rb.Data, "Should be nil")
 	})
 }
+
+func signedRequest(t *testing.T, method string, body []byte) *http.Request {
+	claims := &jwt.JWTclaim{
+		ID:    1,
+		Email: "test@test.com",
+		StandardClaims: newJWT.StandardClaims{
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	}
+
+	token, err := newJWT.NewWithClaims(newJWT.SigningMethodHS256, claims).SignedString(jwt.JWT_KEY)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(method, "/just/for/testing", bytes.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	return r
+}
+
+func TestHandler_Preconditions(t *testing.T) {
+	authenticated := response.Success(response.StatusOK, models.Account{})
+	update, err := json.Marshal(models.Account{Username: "username-test", Password: "password-test", Email: "email@test.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Detail Sends ETag", func(t *testing.T) {
+		accountUseCase := new(mocks.AccountUseCase)
+		accountUseCase.On("Authenticate", mock.Anything, mock.AnythingOfType("jwt.JWTclaim")).Return(authenticated)
+		accountUseCase.On("DetailAccount", mock.Anything, int64(1)).Return(response.Success(response.StatusOK, models.Account{ID: 1, Version: 4}))
+
+		accountHandler := account.AccountHandler{UseCase: accountUseCase}
+
+		recorder := httptest.NewRecorder()
+		http.HandlerFunc(accountHandler.DetailAccount).ServeHTTP(recorder, signedRequest(t, http.MethodGet, nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"4"`, recorder.Header().Get("ETag"))
+	})
+
+	t.Run("Update Passes If-Match", func(t *testing.T) {
+		accountUseCase := new(mocks.AccountUseCase)
+		accountUseCase.On("Authenticate", mock.Anything, mock.AnythingOfType("jwt.JWTclaim")).Return(authenticated)
+		accountUseCase.On("UpdateAccount", mock.Anything, int64(1), mock.MatchedBy(func(params models.Account) bool {
+			return params.Version == 4
+		})).Return(response.Success(response.StatusOK, models.Account{ID: 1, Version: 5}))
+
+		accountHandler := account.AccountHandler{Validate: validation.New(), UseCase: accountUseCase, IfMatchRequired: true}
+
+		r := signedRequest(t, http.MethodPatch, update)
+		r.Header.Set("If-Match", `"4"`)
+
+		recorder := httptest.NewRecorder()
+		http.HandlerFunc(accountHandler.UpdateAccount).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"5"`, recorder.Header().Get("ETag"))
+		accountUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Update Ignores Version In Body", func(t *testing.T) {
+		body, err := json.Marshal(models.Account{Username: "username-test", Password: "password-test", Email: "email@test.com", Version: 9})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		accountUseCase := new(mocks.AccountUseCase)
+		accountUseCase.On("Authenticate", mock.Anything, mock.AnythingOfType("jwt.JWTclaim")).Return(authenticated)
+		accountUseCase.On("UpdateAccount", mock.Anything, int64(1), mock.MatchedBy(func(params models.Account) bool {
+			return params.Version == 0
+		})).Return(response.Success(response.StatusOK, models.Account{ID: 1, Version: 2}))
+
+		accountHandler := account.AccountHandler{Validate: validation.New(), UseCase: accountUseCase}
+
+		recorder := httptest.NewRecorder()
+		http.HandlerFunc(accountHandler.UpdateAccount).ServeHTTP(recorder, signedRequest(t, http.MethodPatch, body))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		accountUseCase.AssertExpectations(t)
+	})
+
+	for _, tt := range []struct {
+		name     string
+		ifMatch  string
+		required bool
+		code     int
+	}{
+		{"Update Without If-Match When Required", "", true, http.StatusPreconditionRequired},
+		{"Update With Weak Tag", `W/"4"`, false, http.StatusPreconditionFailed},
+		{"Update With Unquoted Tag", `4`, false, http.StatusPreconditionFailed},
+		{"Update With Foreign Tag", `"abc"`, true, http.StatusPreconditionFailed},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			accountUseCase := new(mocks.AccountUseCase)
+			accountUseCase.On("Authenticate", mock.Anything, mock.AnythingOfType("jwt.JWTclaim")).Return(authenticated)
+
+			accountHandler := account.AccountHandler{Validate: validation.New(), UseCase: accountUseCase, IfMatchRequired: tt.required}
+
+			r := signedRequest(t, http.MethodPatch, update)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			recorder := httptest.NewRecorder()
+			http.HandlerFunc(accountHandler.UpdateAccount).ServeHTTP(recorder, r)
+
+			assert.Equal(t, tt.code, recorder.Code)
+			accountUseCase.AssertNotCalled(t, "UpdateAccount", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	for _, tt := range []struct {
+		name    string
+		ifMatch string
+		version int64
+	}{
+		{"Delete With If-Match", `"2"`, 2},
+		{"Delete With Any Version", `*`, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			accountUseCase := new(mocks.AccountUseCase)
+			accountUseCase.On("Authenticate", mock.Anything, mock.AnythingOfType("jwt.JWTclaim")).Return(authenticated)
+			accountUseCase.On("DeleteAccount", mock.Anything, int64(1), tt.version).Return(response.Success(response.StatusOK, "Success Delete Data"))
+
+			accountHandler := account.AccountHandler{UseCase: accountUseCase, IfMatchRequired: true}
+
+			r := signedRequest(t, http.MethodDelete, nil)
+			r.Header.Set("If-Match", tt.ifMatch)
+
+			recorder := httptest.NewRecorder()
+			http.HandlerFunc(accountHandler.DeleteAccount).ServeHTTP(recorder, r)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			accountUseCase.AssertExpectations(t)
+		})
+	}
+}
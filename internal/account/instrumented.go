@@ -0,0 +1,111 @@
+package account
+
+import (
+	"context"
+	"time"
+
+	"waizly/helpers/metrics"
+	"waizly/helpers/response"
+	"waizly/models"
+)
+
+type (
+	instrumentedRepository struct {
+		repository AccountRepository
+		metrics    *metrics.Metrics
+	}
+
+	instrumentedUseCase struct {
+		AccountUseCase
+		metrics *metrics.Metrics
+	}
+)
+
+// NewInstrumentedRepository records the latency and outcome of every call to
+// repo.
+func NewInstrumentedRepository(repo AccountRepository, m *metrics.Metrics) AccountRepository {
+	return &instrumentedRepository{
+		repository: repo,
+		metrics:    m,
+	}
+}
+
+func (ir *instrumentedRepository) observe(method string, start time.Time, err *error) {
+	ir.metrics.RepositoryDuration.WithLabelValues("account", method, metrics.Outcome(*err)).Observe(time.Since(start).Seconds())
+}
+
+func (ir *instrumentedRepository) Create(ctx context.Context, params models.Account) (id int64, err error) {
+	defer ir.observe("Create", time.Now(), &err)
+	return ir.repository.Create(ctx, params)
+}
+
+func (ir *instrumentedRepository) FindByID(ctx context.Context, id int64) (account models.Account, err error) {
+	defer ir.observe("FindByID", time.Now(), &err)
+	return ir.repository.FindByID(ctx, id)
+}
+
+func (ir *instrumentedRepository) FindByEmail(ctx context.Context, email string) (account models.Account, err error) {
+	defer ir.observe("FindByEmail", time.Now(), &err)
+	return ir.repository.FindByEmail(ctx, email)
+}
+
+func (ir *instrumentedRepository) List(ctx context.Context, afterID int64, limit int) (accounts []models.Account, err error) {
+	defer ir.observe("List", time.Now(), &err)
+	return ir.repository.List(ctx, afterID, limit)
+}
+
+func (ir *instrumentedRepository) Update(ctx context.Context, id int64, params models.Account) (err error) {
+	defer ir.observe("Update", time.Now(), &err)
+	return ir.repository.Update(ctx, id, params)
+}
+
+func (ir *instrumentedRepository) Delete(ctx context.Context, id int64, version int64) (err error) {
+	defer ir.observe("Delete", time.Now(), &err)
+	return ir.repository.Delete(ctx, id, version)
+}
+
+// NewInstrumentedUseCase counts registrations and logins by outcome. The
+// failure reason is derived from the response status, so the use case itself
+// stays free of metrics code.
+func NewInstrumentedUseCase(usecase AccountUseCase, m *metrics.Metrics) AccountUseCase {
+	return &instrumentedUseCase{
+		AccountUseCase: usecase,
+		metrics:        m,
+	}
+}
+
+func (iu *instrumentedUseCase) Register(ctx context.Context, params models.RegisterRequest) response.Response {
+	res := iu.AccountUseCase.Register(ctx, params)
+
+	outcome, reason := "failure", "error"
+	switch response.StatusOf(res) {
+	case response.StatusCreated:
+		outcome, reason = "success", ""
+	case response.StatusConflicted:
+		reason = "email_taken"
+	}
+
+	iu.metrics.Registrations.WithLabelValues(outcome, reason).Inc()
+
+	return res
+}
+
+func (iu *instrumentedUseCase) Login(ctx context.Context, params models.LoginRequest) (response.Response, models.Token) {
+	res, token := iu.AccountUseCase.Login(ctx, params)
+
+	outcome, reason := "failure", "error"
+	switch response.StatusOf(res) {
+	case response.StatusOK:
+		outcome, reason = "success", ""
+	case response.StatusNotFound:
+		reason = "unknown_email"
+	case response.StatusUnauthorized:
+		reason = "invalid_password"
+	case response.StatusForbiddend:
+		reason = "disabled"
+	}
+
+	iu.metrics.Logins.WithLabelValues(outcome, reason).Inc()
+
+	return res, token
+}
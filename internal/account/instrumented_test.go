@@ -0,0 +1,87 @@
+package account_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/metrics"
+	"waizly/helpers/response"
+	"waizly/internal/account"
+	"waizly/internal/account/mocks"
+	"waizly/models"
+)
+
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	var metric dto.Metric
+
+	err := observer.(prometheus.Metric).Write(&metric)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestInstrumentedRepository(t *testing.T) {
+	m := metrics.New()
+
+	repository := new(mocks.AccountRepository)
+	repository.On("FindByID", mock.Anything, int64(1)).Return(models.Account{ID: 1}, nil)
+	repository.On("FindByEmail", mock.Anything, "unknown@test.com").Return(models.Account{}, exception.ErrNotFound)
+	repository.On("Delete", mock.Anything, int64(1), int64(0)).Return(exception.ErrInternalServer)
+
+	instrumented := account.NewInstrumentedRepository(repository, m)
+
+	found, err := instrumented.FindByID(context.TODO(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), found.ID)
+
+	_, err = instrumented.FindByEmail(context.TODO(), "unknown@test.com")
+	assert.Equal(t, exception.ErrNotFound, err)
+
+	err = instrumented.Delete(context.TODO(), 1, 0)
+	assert.Equal(t, exception.ErrInternalServer, err)
+
+	assert.Equal(t, 3, testutil.CollectAndCount(m.RepositoryDuration))
+	for _, labels := range [][]string{
+		{"account", "FindByID", "success"},
+		{"account", "FindByEmail", "not_found"},
+		{"account", "Delete", "error"},
+	} {
+		assert.Equal(t, uint64(1), sampleCount(t, m.RepositoryDuration.WithLabelValues(labels...)), labels)
+	}
+
+	repository.AssertExpectations(t)
+}
+
+func TestInstrumentedUseCase(t *testing.T) {
+	m := metrics.New()
+
+	useCase := new(mocks.AccountUseCase)
+	useCase.On("Login", mock.Anything, models.LoginRequest{Email: "ok@test.com"}).Return(response.Success(response.StatusOK, models.Account{}), models.Token{Token: "token"})
+	useCase.On("Login", mock.Anything, models.LoginRequest{Email: "wrong@test.com"}).Return(response.Error(response.StatusUnauthorized, nil), models.Token{})
+	useCase.On("Login", mock.Anything, models.LoginRequest{Email: "disabled@test.com"}).Return(response.Error(response.StatusForbiddend, exception.ErrForbidden), models.Token{})
+	useCase.On("Register", mock.Anything, mock.AnythingOfType("models.RegisterRequest")).Return(response.Error(response.StatusConflicted, exception.ErrConflicted))
+
+	instrumented := account.NewInstrumentedUseCase(useCase, m)
+
+	_, token := instrumented.Login(context.TODO(), models.LoginRequest{Email: "ok@test.com"})
+	assert.Equal(t, "token", token.Token)
+
+	instrumented.Login(context.TODO(), models.LoginRequest{Email: "wrong@test.com"})
+	instrumented.Login(context.TODO(), models.LoginRequest{Email: "wrong@test.com"})
+	instrumented.Login(context.TODO(), models.LoginRequest{Email: "disabled@test.com"})
+	instrumented.Register(context.TODO(), models.RegisterRequest{})
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.Logins.WithLabelValues("success", "")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.Logins.WithLabelValues("failure", "invalid_password")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.Logins.WithLabelValues("failure", "disabled")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.Registrations.WithLabelValues("failure", "email_taken")))
+}
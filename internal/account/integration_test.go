@@ -0,0 +1,352 @@
+package account_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+
+	"waizly/client"
+	"waizly/config/bcrypt"
+	"waizly/db/migration"
+	"waizly/helpers/dialect"
+	"waizly/helpers/exception"
+	"waizly/helpers/middleware"
+	"waizly/helpers/response"
+	"waizly/helpers/transaction"
+	"waizly/helpers/validation"
+	"waizly/internal/account"
+	"waizly/internal/audit"
+	"waizly/internal/constant"
+	"waizly/internal/migrator"
+	"waizly/internal/outbox"
+	"waizly/models"
+)
+
+// openSQLite returns a migrated database in a temporary file, so every test
+// runs the real schema without any server.
+func openSQLite(t *testing.T) *sql.DB {
+	path := filepath.Join(t.TempDir(), "waizly.db")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite&_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := migrator.Load(migration.For(dialect.SQLite))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrator.New(db, dialect.SQLite, migrations, constant.TableSchemaMigrations).Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// serveSQLite runs the account handlers, use case and repositories against
+// db and returns a client for them.
+func serveSQLite(t *testing.T, db *sql.DB) *client.Client {
+	return serve(t, db, account.NewSQLiteAccountRepository(db, constant.TableAccount))
+}
+
+// serve is serveSQLite with accountRepo in place of the SQLite account
+// repository and opts applied to the handler.
+func serve(t *testing.T, db *sql.DB, accountRepo account.AccountRepository, opts ...account.HandlerOption) *client.Client {
+	auditUseCase := audit.NewAuditUseCase(audit.NewAuditRepository(db, dialect.SQLite, constant.TableAuditLog, constant.TableAuditLogHead))
+	outboxRepo := outbox.NewOutboxRepository(db, dialect.SQLite, constant.TableOutbox)
+	useCase := account.NewAccountUseCase(accountRepo, bcrypt.NewBcrypt(4), auditUseCase, outboxRepo, transaction.NewTransactor(db))
+
+	router := mux.NewRouter()
+	router.Use(middleware.RequestMetadata)
+	account.NewAccountHandler(router, validation.New(), useCase, opts...)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return client.New(server.URL, client.WithRetry(client.Retry{MaxAttempts: 1}))
+}
+
+// barrierRepository holds the result of every FindByEmail until all callers
+// have looked up, so concurrent registrations all miss before any of them
+// creates.
+type barrierRepository struct {
+	account.AccountRepository
+	arrived *sync.WaitGroup
+}
+
+func (br barrierRepository) FindByEmail(ctx context.Context, email string) (models.Account, error) {
+	found, err := br.AccountRepository.FindByEmail(ctx, email)
+
+	br.arrived.Done()
+	br.arrived.Wait()
+
+	return found, err
+}
+
+func count(t *testing.T, db *sql.DB, table string) int {
+	var n int
+
+	err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func TestSQLiteIntegration(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Account Lifecycle", func(t *testing.T) {
+		db := openSQLite(t)
+		c := serveSQLite(t, db)
+
+		registered, err := c.Register(ctx, models.RegisterRequest{Username: "jane", Password: "secret", Email: "jane@example.com"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NotZero(t, registered.ID)
+
+		_, err = c.Register(ctx, models.RegisterRequest{Username: "jane", Password: "secret", Email: "jane@example.com"})
+		assert.ErrorIs(t, err, exception.ErrConflicted)
+
+		_, err = c.Login(ctx, models.LoginRequest{Email: "jane@example.com", Password: "wrong"})
+		assert.Error(t, err)
+
+		_, err = c.Login(ctx, models.LoginRequest{Email: "jane@example.com", Password: "secret"})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		detail, err := c.Detail(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, registered.ID, detail.ID)
+		assert.Equal(t, "jane", detail.Username)
+		assert.Equal(t, account.RoleUser, detail.Role)
+		assert.Empty(t, detail.Password)
+
+		_, err = c.Update(ctx, models.Account{Username: "janet", Password: "secret", Email: "janet@example.com"})
+		assert.NoError(t, err)
+
+		detail, err = c.Detail(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "janet", detail.Username)
+		assert.Equal(t, "janet@example.com", detail.Email)
+		assert.False(t, detail.UpdateAt.IsZero())
+
+		assert.NoError(t, c.Delete(ctx))
+
+		_, err = c.Detail(ctx)
+		assert.Error(t, err)
+
+		assert.Equal(t, 0, count(t, db, constant.TableAccount))
+		assert.Equal(t, 3, count(t, db, constant.TableOutbox), "registered, updated and deleted events")
+
+		res := audit.NewAuditUseCase(audit.NewAuditRepository(db, dialect.SQLite, constant.TableAuditLog, constant.TableAuditLogHead)).Verify(ctx)
+		if assert.NoError(t, res.Err()) {
+			verification := res.(*response.ResponseImpl).Data.(models.AuditVerification)
+			assert.True(t, verification.Valid, "audit chain should verify: %+v", verification)
+		}
+	})
+
+	t.Run("Registration Is Atomic", func(t *testing.T) {
+		db := openSQLite(t)
+		c := serveSQLite(t, db)
+
+		_, err := db.Exec("DROP TABLE " + constant.TableOutbox)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = c.Register(ctx, models.RegisterRequest{Username: "jane", Password: "secret", Email: "jane@example.com"})
+		assert.ErrorIs(t, err, exception.ErrInternalServer)
+
+		assert.Equal(t, 0, count(t, db, constant.TableAccount), "the account must roll back with the event")
+		assert.Equal(t, 0, count(t, db, constant.TableAuditLog), "no success may be audited for a rolled back registration")
+	})
+
+	t.Run("Audit Failure Keeps Registration", func(t *testing.T) {
+		db := openSQLite(t)
+		c := serveSQLite(t, db)
+
+		_, err := db.Exec("DROP TABLE " + constant.TableAuditLogHead)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = c.Register(ctx, models.RegisterRequest{Username: "jane", Password: "secret", Email: "jane@example.com"})
+		assert.NoError(t, err)
+
+		assert.Equal(t, 1, count(t, db, constant.TableAccount))
+		assert.Equal(t, 1, count(t, db, constant.TableOutbox))
+	})
+
+	t.Run("Lost Update Is Refused", func(t *testing.T) {
+		db := openSQLite(t)
+		c := serve(t, db, account.NewSQLiteAccountRepository(db, constant.TableAccount), account.RequireIfMatch(true))
+
+		_, err := c.Register(ctx, models.RegisterRequest{Username: "jane", Password: "secret", Email: "jane@example.com"})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		_, err = c.Login(ctx, models.LoginRequest{Email: "jane@example.com", Password: "secret"})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		first, err := c.Detail(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), first.Version)
+		first.Password = "secret"
+		second := first
+
+		first.Username = "janet"
+		updated, err := c.Update(ctx, first)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), updated.Version)
+
+		second.Email = "jane@example.org"
+		_, err = c.Update(ctx, second)
+		assert.ErrorIs(t, err, exception.ErrPreconditionFailed)
+
+		second.Version = 0
+		_, err = c.Update(ctx, second)
+		assert.ErrorIs(t, err, exception.ErrPreconditionRequired)
+
+		detail, err := c.Detail(ctx)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "janet", detail.Username)
+			assert.Equal(t, "jane@example.com", detail.Email, "the stale update must not apply")
+		}
+
+		assert.ErrorIs(t, c.DeleteVersion(ctx, 1), exception.ErrPreconditionFailed)
+		assert.NoError(t, c.DeleteVersion(ctx, 2))
+		assert.Equal(t, 0, count(t, db, constant.TableAccount))
+	})
+
+	t.Run("Concurrent Registration", func(t *testing.T) {
+		const n = 8
+
+		var arrived sync.WaitGroup
+		arrived.Add(n)
+
+		db := openSQLite(t)
+		c := serve(t, db, barrierRepository{
+			AccountRepository: account.NewSQLiteAccountRepository(db, constant.TableAccount),
+			arrived:           &arrived,
+		})
+
+		var wg sync.WaitGroup
+		errs := make([]error, n)
+
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = c.Register(ctx, models.RegisterRequest{
+					Username: fmt.Sprintf("jane%d", i),
+					Password: "secret",
+					Email:    "Jane@example.com",
+				})
+			}(i)
+		}
+		wg.Wait()
+
+		registered := 0
+		for _, err := range errs {
+			if err == nil {
+				registered++
+				continue
+			}
+
+			assert.ErrorIs(t, err, exception.ErrConflicted)
+		}
+
+		assert.Equal(t, 1, registered, "exactly one registration must win")
+		assert.Equal(t, 1, count(t, db, constant.TableAccount))
+		assert.Equal(t, 1, count(t, db, constant.TableOutbox), "losers must not leave events behind")
+	})
+}
+
+// TestRepositoriesOnSQLite runs the MySQL repository, whose SQL SQLite also
+// accepts, and the SQLite one against a real schema so scanning bugs that
+// sqlmock cannot see, such as NULL columns, fail here.
+func TestRepositoriesOnSQLite(t *testing.T) {
+	ctx := context.Background()
+
+	repositories := map[string]func(db *sql.DB) account.AccountRepository{
+		"mysql": func(db *sql.DB) account.AccountRepository {
+			return account.NewAccountRepository(db, constant.TableAccount)
+		},
+		"sqlite": func(db *sql.DB) account.AccountRepository {
+			return account.NewSQLiteAccountRepository(db, constant.TableAccount)
+		},
+	}
+
+	for name, newRepository := range repositories {
+		t.Run(name, func(t *testing.T) {
+			db := openSQLite(t)
+			repo := newRepository(db)
+
+			_, err := db.Exec(`INSERT INTO account (username, password, email, update_at) VALUES ('null', NULL, 'null@example.com', NULL)`)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			found, err := repo.FindByEmail(ctx, "null@example.com")
+			if assert.NoError(t, err) {
+				assert.Empty(t, found.Password)
+				assert.True(t, found.UpdateAt.IsZero())
+			}
+
+			found, err = repo.FindByID(ctx, found.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, "null@example.com", found.Email)
+
+			id, err := repo.Create(ctx, models.Account{Username: "jane", Password: "hash", Email: "jane@example.com", CreatedAt: time.Now().UTC()})
+			assert.NoError(t, err)
+
+			accounts, err := repo.List(ctx, 0, 10)
+			assert.NoError(t, err)
+			assert.Len(t, accounts, 2)
+
+			now := time.Now().UTC().Truncate(time.Second)
+			err = repo.Update(ctx, id, models.Account{Username: "jane", Password: "hash", Email: "jane@example.com", Role: account.RoleAdmin, UpdateAt: now, DisabledAt: &now, Version: 1})
+			assert.NoError(t, err)
+
+			found, err = repo.FindByID(ctx, id)
+			if assert.NoError(t, err) {
+				assert.Equal(t, account.RoleAdmin, found.Role)
+				assert.Equal(t, int64(2), found.Version)
+				assert.True(t, now.Equal(found.UpdateAt))
+				if assert.NotNil(t, found.DisabledAt) {
+					assert.True(t, now.Equal(*found.DisabledAt))
+				}
+			}
+
+			assert.NoError(t, repo.Delete(ctx, id, 0))
+			assert.ErrorIs(t, repo.Delete(ctx, id, 0), exception.ErrNotFound)
+
+			expired, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+			defer cancel()
+
+			_, err = repo.FindByEmail(expired, "null@example.com")
+			assert.ErrorIs(t, err, exception.ErrTimeout)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		})
+	}
+}
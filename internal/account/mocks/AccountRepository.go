@@ -35,13 +35,13 @@ func (_m *AccountRepository) Create(ctx context.Context, params models.Account)
 	return r0, r1
 }
 
-// Delete provides a mock function with given fields: ctx, id
-func (_m *AccountRepository) Delete(ctx context.Context, id int64) error {
-	ret := _m.Called(ctx, id)
+// Delete provides a mock function with given fields: ctx, id, version
+func (_m *AccountRepository) Delete(ctx context.Context, id int64, version int64) error {
+	ret := _m.Called(ctx, id, version)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, id, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -91,6 +91,29 @@ func (_m *AccountRepository) FindByID(ctx context.Context, id int64) (models.Acc
 	return r0, r1
 }
 
+// List provides a mock function with given fields: ctx, afterID, limit
+func (_m *AccountRepository) List(ctx context.Context, afterID int64, limit int) ([]models.Account, error) {
+	ret := _m.Called(ctx, afterID, limit)
+
+	var r0 []models.Account
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []models.Account); ok {
+		r0 = rf(ctx, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Account)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: ctx, id, params
 func (_m *AccountRepository) Update(ctx context.Context, id int64, params models.Account) error {
 	ret := _m.Called(ctx, id, params)
@@ -4,10 +4,12 @@ package mocks
 
 import (
 	context "context"
-	models "waizly/models"
+	jwt "waizly/config/jwt"
 
 	mock "github.com/stretchr/testify/mock"
 
+	models "waizly/models"
+
 	response "waizly/helpers/response"
 )
 
@@ -16,13 +18,45 @@ type AccountUseCase struct {
 	mock.Mock
 }
 
-// DeleteAccount provides a mock function with given fields: ctx, id
-func (_m *AccountUseCase) DeleteAccount(ctx context.Context, id int64) response.Response {
-	ret := _m.Called(ctx, id)
+// AssignRole provides a mock function with given fields: ctx, id, role
+func (_m *AccountUseCase) AssignRole(ctx context.Context, id int64, role string) response.Response {
+	ret := _m.Called(ctx, id, role)
 
 	var r0 response.Response
-	if rf, ok := ret.Get(0).(func(context.Context, int64) response.Response); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) response.Response); ok {
+		r0 = rf(ctx, id, role)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(response.Response)
+		}
+	}
+
+	return r0
+}
+
+// Authenticate provides a mock function with given fields: ctx, claims
+func (_m *AccountUseCase) Authenticate(ctx context.Context, claims jwt.JWTclaim) response.Response {
+	ret := _m.Called(ctx, claims)
+
+	var r0 response.Response
+	if rf, ok := ret.Get(0).(func(context.Context, jwt.JWTclaim) response.Response); ok {
+		r0 = rf(ctx, claims)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(response.Response)
+		}
+	}
+
+	return r0
+}
+
+// DeleteAccount provides a mock function with given fields: ctx, id, version
+func (_m *AccountUseCase) DeleteAccount(ctx context.Context, id int64, version int64) response.Response {
+	ret := _m.Called(ctx, id, version)
+
+	var r0 response.Response
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) response.Response); ok {
+		r0 = rf(ctx, id, version)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(response.Response)
@@ -48,6 +82,22 @@ func (_m *AccountUseCase) DetailAccount(ctx context.Context, id int64) response.
 	return r0
 }
 
+// ListAccounts provides a mock function with given fields: ctx, afterID, limit
+func (_m *AccountUseCase) ListAccounts(ctx context.Context, afterID int64, limit int) response.Response {
+	ret := _m.Called(ctx, afterID, limit)
+
+	var r0 response.Response
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) response.Response); ok {
+		r0 = rf(ctx, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(response.Response)
+		}
+	}
+
+	return r0
+}
+
 // Login provides a mock function with given fields: ctx, params
 func (_m *AccountUseCase) Login(ctx context.Context, params models.LoginRequest) (response.Response, models.Token) {
 	ret := _m.Called(ctx, params)
@@ -87,6 +137,54 @@ func (_m *AccountUseCase) Register(ctx context.Context, params models.RegisterRe
 	return r0
 }
 
+// ResetPassword provides a mock function with given fields: ctx, id, password
+func (_m *AccountUseCase) ResetPassword(ctx context.Context, id int64, password string) response.Response {
+	ret := _m.Called(ctx, id, password)
+
+	var r0 response.Response
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) response.Response); ok {
+		r0 = rf(ctx, id, password)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(response.Response)
+		}
+	}
+
+	return r0
+}
+
+// RevokeSessions provides a mock function with given fields: ctx, id
+func (_m *AccountUseCase) RevokeSessions(ctx context.Context, id int64) response.Response {
+	ret := _m.Called(ctx, id)
+
+	var r0 response.Response
+	if rf, ok := ret.Get(0).(func(context.Context, int64) response.Response); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(response.Response)
+		}
+	}
+
+	return r0
+}
+
+// SetDisabled provides a mock function with given fields: ctx, id, disabled
+func (_m *AccountUseCase) SetDisabled(ctx context.Context, id int64, disabled bool) response.Response {
+	ret := _m.Called(ctx, id, disabled)
+
+	var r0 response.Response
+	if rf, ok := ret.Get(0).(func(context.Context, int64, bool) response.Response); ok {
+		r0 = rf(ctx, id, disabled)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(response.Response)
+		}
+	}
+
+	return r0
+}
+
 // UpdateAccount provides a mock function with given fields: ctx, id, params
 func (_m *AccountUseCase) UpdateAccount(ctx context.Context, id int64, params models.Account) response.Response {
 	ret := _m.Called(ctx, id, params)
@@ -0,0 +1,155 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/replica"
+	"waizly/models"
+)
+
+type replicatedRepository struct {
+	AccountRepository
+	replicas []AccountRepository
+	pool     *replica.Pool
+	// pinFor is how long an account written through this repository is
+	// read from the primary, so readers in later requests, the cache among
+	// them, do not pick up a replica that has not caught up yet.
+	pinFor time.Duration
+
+	mu           sync.Mutex
+	pinned       map[int64]time.Time
+	pinnedEmails map[string]time.Time
+}
+
+// NewReplicatedRepository sends FindByID and FindByEmail to the replica
+// repository pool picks, replicas[i] being the one on pool's replica i, and
+// everything else to primary. A read falls back to primary when the replica
+// is unreachable, which also ejects it from pool. An account written through
+// the repository is read from primary for pinFor afterwards, whether it is
+// looked up by id or by email.
+func NewReplicatedRepository(primary AccountRepository, replicas []AccountRepository, pool *replica.Pool, pinFor time.Duration) AccountRepository {
+	return &replicatedRepository{
+		AccountRepository: primary,
+		replicas:          replicas,
+		pool:              pool,
+		pinFor:            pinFor,
+		pinned:            make(map[int64]time.Time),
+		pinnedEmails:      make(map[string]time.Time),
+	}
+}
+
+func (rr *replicatedRepository) FindByID(ctx context.Context, id int64) (models.Account, error) {
+	if rr.isPinned(id) {
+		return rr.AccountRepository.FindByID(ctx, id)
+	}
+
+	return rr.read(ctx, func(repo AccountRepository) (models.Account, error) {
+		return repo.FindByID(ctx, id)
+	})
+}
+
+// FindByEmail reads from primary for an email written recently. A replica
+// may still hold a pinned account under an email it no longer has, or one
+// that was deleted, so such a result is read again from primary.
+func (rr *replicatedRepository) FindByEmail(ctx context.Context, email string) (models.Account, error) {
+	if rr.isPinnedEmail(email) {
+		return rr.AccountRepository.FindByEmail(ctx, email)
+	}
+
+	account, err := rr.read(ctx, func(repo AccountRepository) (models.Account, error) {
+		return repo.FindByEmail(ctx, email)
+	})
+	if err == nil && rr.isPinned(account.ID) {
+		return rr.AccountRepository.FindByEmail(ctx, email)
+	}
+
+	return account, err
+}
+
+func (rr *replicatedRepository) Create(ctx context.Context, params models.Account) (int64, error) {
+	replica.MarkWritten(ctx)
+
+	id, err := rr.AccountRepository.Create(ctx, params)
+	if err == nil {
+		rr.pin(id, params.Email)
+	}
+
+	return id, err
+}
+
+func (rr *replicatedRepository) Update(ctx context.Context, id int64, params models.Account) error {
+	replica.MarkWritten(ctx)
+	rr.pin(id, params.Email)
+
+	return rr.AccountRepository.Update(ctx, id, params)
+}
+
+func (rr *replicatedRepository) Delete(ctx context.Context, id int64, version int64) error {
+	replica.MarkWritten(ctx)
+	rr.pin(id, "")
+
+	return rr.AccountRepository.Delete(ctx, id, version)
+}
+
+func (rr *replicatedRepository) read(ctx context.Context, find func(repo AccountRepository) (models.Account, error)) (models.Account, error) {
+	i, ok := rr.pool.Pick(ctx)
+	if !ok {
+		return find(rr.AccountRepository)
+	}
+
+	account, err := find(rr.replicas[i])
+	if errors.Is(err, exception.ErrUnavailable) || errors.Is(err, exception.ErrTimeout) {
+		rr.pool.Eject(ctx, i, err)
+		return find(rr.AccountRepository)
+	}
+
+	return account, err
+}
+
+// pin reads id, and email unless it is empty, from primary for pinFor.
+// Emails are compared case-insensitively, like the backends look them up.
+func (rr *replicatedRepository) pin(id int64, email string) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	now := time.Now()
+	for pinned, until := range rr.pinned {
+		if now.After(until) {
+			delete(rr.pinned, pinned)
+		}
+	}
+
+	for pinned, until := range rr.pinnedEmails {
+		if now.After(until) {
+			delete(rr.pinnedEmails, pinned)
+		}
+	}
+
+	rr.pinned[id] = now.Add(rr.pinFor)
+	if email != "" {
+		rr.pinnedEmails[strings.ToLower(email)] = now.Add(rr.pinFor)
+	}
+}
+
+func (rr *replicatedRepository) isPinned(id int64) bool {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	until, ok := rr.pinned[id]
+
+	return ok && time.Now().Before(until)
+}
+
+func (rr *replicatedRepository) isPinnedEmail(email string) bool {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	until, ok := rr.pinnedEmails[strings.ToLower(email)]
+
+	return ok && time.Now().Before(until)
+}
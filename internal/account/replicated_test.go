@@ -0,0 +1,179 @@
+package account_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/replica"
+	"waizly/internal/account"
+	"waizly/internal/account/mocks"
+	"waizly/models"
+)
+
+// healthyPool returns a pool of n replicas that are all in rotation.
+func healthyPool(t *testing.T, n int) *replica.Pool {
+	dbs := make([]*sql.DB, n)
+	for i := range dbs {
+		dbs[i] = new(sql.DB)
+	}
+
+	pool := replica.NewPool(dbs, func(ctx context.Context, db *sql.DB) (time.Duration, error) {
+		return 0, nil
+	}, replica.Options{MaxLag: time.Second, CheckInterval: time.Second})
+	pool.Check(context.Background())
+
+	return pool
+}
+
+func TestReplicatedRepository(t *testing.T) {
+	jane := models.Account{ID: 1, Username: "jane", Email: "jane@test.com", Version: 1}
+
+	t.Run("Reads Go To Replicas", func(t *testing.T) {
+		primary, first, second := new(mocks.AccountRepository), new(mocks.AccountRepository), new(mocks.AccountRepository)
+		first.On("FindByID", mock.Anything, int64(1)).Return(jane, nil).Once()
+		second.On("FindByEmail", mock.Anything, "jane@test.com").Return(jane, nil).Once()
+		primary.On("List", mock.Anything, int64(0), 10).Return([]models.Account{jane}, nil)
+
+		repo := account.NewReplicatedRepository(primary, []account.AccountRepository{first, second}, healthyPool(t, 2), time.Minute)
+
+		ctx := replica.NewSession(context.Background())
+
+		found, err := repo.FindByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, jane, found)
+
+		found, err = repo.FindByEmail(ctx, "jane@test.com")
+		assert.NoError(t, err)
+		assert.Equal(t, jane, found)
+
+		_, err = repo.List(ctx, 0, 10)
+		assert.NoError(t, err)
+
+		primary.AssertExpectations(t)
+		first.AssertExpectations(t)
+		second.AssertExpectations(t)
+	})
+
+	t.Run("Reads After Write", func(t *testing.T) {
+		primary, replicaRepo := new(mocks.AccountRepository), new(mocks.AccountRepository)
+		primary.On("Update", mock.Anything, int64(1), jane).Return(nil)
+		primary.On("FindByEmail", mock.Anything, "jane@test.com").Return(jane, nil).Twice()
+		primary.On("FindByID", mock.Anything, int64(1)).Return(jane, nil).Once()
+		replicaRepo.On("FindByEmail", mock.Anything, "john@test.com").Return(models.Account{ID: 2}, nil).Once()
+
+		repo := account.NewReplicatedRepository(primary, []account.AccountRepository{replicaRepo}, healthyPool(t, 1), time.Minute)
+
+		ctx := replica.NewSession(context.Background())
+		assert.NoError(t, repo.Update(ctx, 1, jane))
+
+		// The rest of the request reads from the primary.
+		repo.FindByEmail(ctx, "jane@test.com")
+
+		// Another request reads the updated account from the primary too,
+		// by id and by email; other lookups keep using the replica.
+		other := replica.NewSession(context.Background())
+		repo.FindByID(other, 1)
+		repo.FindByEmail(other, "jane@test.com")
+		repo.FindByEmail(other, "john@test.com")
+
+		primary.AssertExpectations(t)
+		replicaRepo.AssertExpectations(t)
+	})
+
+	t.Run("Email Lookup After Email Change", func(t *testing.T) {
+		primary, replicaRepo := new(mocks.AccountRepository), new(mocks.AccountRepository)
+		renamed := jane
+		renamed.Email = "jane.doe@test.com"
+
+		primary.On("Update", mock.Anything, int64(1), renamed).Return(nil)
+		primary.On("FindByEmail", mock.Anything, "jane@test.com").Return(models.Account{}, exception.Wrap("AccountRepository.FindByEmail", exception.ErrNotFound, nil)).Once()
+		replicaRepo.On("FindByEmail", mock.Anything, "jane@test.com").Return(jane, nil).Once()
+
+		repo := account.NewReplicatedRepository(primary, []account.AccountRepository{replicaRepo}, healthyPool(t, 1), time.Minute)
+
+		assert.NoError(t, repo.Update(context.Background(), 1, renamed))
+
+		// The replica still has the old email, the pinned id sends the
+		// lookup to the primary.
+		_, err := repo.FindByEmail(replica.NewSession(context.Background()), "jane@test.com")
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+
+		primary.AssertExpectations(t)
+		replicaRepo.AssertExpectations(t)
+	})
+
+	t.Run("Email Pin Ignores Case", func(t *testing.T) {
+		primary, replicaRepo := new(mocks.AccountRepository), new(mocks.AccountRepository)
+		mixed := jane
+		mixed.Email = "Jane@Test.com"
+
+		primary.On("Create", mock.Anything, mixed).Return(int64(1), nil)
+		primary.On("FindByEmail", mock.Anything, "jane@test.com").Return(jane, nil).Once()
+
+		repo := account.NewReplicatedRepository(primary, []account.AccountRepository{replicaRepo}, healthyPool(t, 1), time.Minute)
+
+		_, err := repo.Create(context.Background(), mixed)
+		assert.NoError(t, err)
+
+		_, err = repo.FindByEmail(replica.NewSession(context.Background()), "jane@test.com")
+		assert.NoError(t, err)
+
+		primary.AssertExpectations(t)
+		replicaRepo.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Pin Expires", func(t *testing.T) {
+		primary, replicaRepo := new(mocks.AccountRepository), new(mocks.AccountRepository)
+		primary.On("Create", mock.Anything, jane).Return(int64(1), nil)
+		replicaRepo.On("FindByID", mock.Anything, int64(1)).Return(jane, nil).Once()
+
+		repo := account.NewReplicatedRepository(primary, []account.AccountRepository{replicaRepo}, healthyPool(t, 1), time.Millisecond)
+
+		_, err := repo.Create(context.Background(), jane)
+		assert.NoError(t, err)
+
+		time.Sleep(5 * time.Millisecond)
+		repo.FindByID(context.Background(), 1)
+
+		primary.AssertExpectations(t)
+		replicaRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unavailable Replica Is Ejected", func(t *testing.T) {
+		primary, down := new(mocks.AccountRepository), new(mocks.AccountRepository)
+		down.On("FindByID", mock.Anything, int64(1)).Return(models.Account{}, exception.Wrap("AccountRepository.FindByID", exception.ErrUnavailable, nil)).Once()
+		primary.On("FindByID", mock.Anything, int64(1)).Return(jane, nil).Twice()
+
+		pool := healthyPool(t, 1)
+		repo := account.NewReplicatedRepository(primary, []account.AccountRepository{down}, pool, time.Minute)
+
+		for i := 0; i < 2; i++ {
+			found, err := repo.FindByID(context.Background(), 1)
+			assert.NoError(t, err)
+			assert.Equal(t, jane, found)
+		}
+
+		_, ok := pool.Pick(context.Background())
+		assert.False(t, ok)
+
+		primary.AssertExpectations(t)
+		down.AssertExpectations(t)
+	})
+
+	t.Run("Not Found Is An Answer", func(t *testing.T) {
+		primary, replicaRepo := new(mocks.AccountRepository), new(mocks.AccountRepository)
+		replicaRepo.On("FindByID", mock.Anything, int64(2)).Return(models.Account{}, exception.Wrap("AccountRepository.FindByID", exception.ErrNotFound, nil))
+
+		repo := account.NewReplicatedRepository(primary, []account.AccountRepository{replicaRepo}, healthyPool(t, 1), time.Minute)
+
+		_, err := repo.FindByID(context.Background(), 2)
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+		primary.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+}
@@ -3,160 +3,381 @@ package account
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel"
+
+	"waizly/helpers/dialect"
 	"waizly/helpers/exception"
+	"waizly/helpers/logger"
+	"waizly/helpers/tracing"
+	"waizly/helpers/transaction"
 	"waizly/models"
 )
 
+var tracer = otel.Tracer("waizly/internal/account")
+
+// MySQL error numbers the repository gives a meaning to.
+const (
+	duplicateEntry        = 1062
+	lockWaitTimeout       = 1205
+	executionTimeExceeded = 3024
+)
+
 type (
 	AccountRepository interface {
 		Create(ctx context.Context, params models.Account) (int64, error)
 		FindByID(ctx context.Context, id int64) (models.Account, error)
 		FindByEmail(ctx context.Context, email string) (models.Account, error)
+		List(ctx context.Context, afterID int64, limit int) ([]models.Account, error)
+		// Update stores params over the account while it is still at
+		// params.Version and moves it to the next version. An account at
+		// another version fails with exception.ErrConflicted caused by
+		// exception.ErrVersionMismatch.
 		Update(ctx context.Context, id int64, params models.Account) error
-		Delete(ctx context.Context, id int64) error
+		// Delete removes the account while it is still at version, or at
+		// any version when version is 0.
+		Delete(ctx context.Context, id int64, version int64) error
+	}
+
+	// RepositoryOption configures the SQL repositories.
+	RepositoryOption func(limits *queryLimits)
+
+	// queryLimits bounds how long a repository call may spend in the
+	// database and reports the calls that were slow.
+	queryLimits struct {
+		timeout time.Duration
+		slow    time.Duration
 	}
 
 	accountRepositoryImpl struct {
 		db        *sql.DB
 		tableName string
+		limits    queryLimits
 	}
 )
 
-func NewAccountRepository(db *sql.DB, tableName string) AccountRepository {
+// WithQueryTimeout cancels a repository call whose queries have not finished
+// after timeout. The call then fails with exception.ErrTimeout. 0 leaves the
+// calls to the deadline of their context.
+func WithQueryTimeout(timeout time.Duration) RepositoryOption {
+	return func(limits *queryLimits) {
+		limits.timeout = timeout
+	}
+}
+
+// WithSlowQueryLog logs the query of every repository call that took
+// threshold or longer. 0 disables the log.
+func WithSlowQueryLog(threshold time.Duration) RepositoryOption {
+	return func(limits *queryLimits) {
+		limits.slow = threshold
+	}
+}
+
+func newQueryLimits(opts []RepositoryOption) queryLimits {
+	var limits queryLimits
+	for _, opt := range opts {
+		opt(&limits)
+	}
+
+	return limits
+}
+
+// start applies the query timeout to ctx. The returned function releases
+// ctx, logs query when op ran past the slow-query threshold and reports a
+// failure caused by the timeout as exception.ErrTimeout, whatever error the
+// driver gave for the cancelled query.
+func (ql queryLimits) start(ctx context.Context, op, query string) (context.Context, func(err *error)) {
+	began := time.Now()
+
+	cancel := context.CancelFunc(func() {})
+	if ql.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, ql.timeout)
+	}
+
+	return ctx, func(err *error) {
+		expired := errors.Is(ctx.Err(), context.DeadlineExceeded)
+		cancel()
+
+		elapsed := time.Since(began)
+		if ql.slow > 0 && elapsed >= ql.slow {
+			logger.Ctx(ctx).Warn("slow query", "op", op, "query", query, "duration", elapsed.String())
+		}
+
+		if *err == nil || !expired || errors.Is(*err, exception.ErrTimeout) {
+			return
+		}
+
+		if errors.Is(*err, exception.ErrInternalServer) || errors.Is(*err, exception.ErrUnavailable) {
+			cause := *err
+
+			var repoErr *exception.RepositoryError
+			if errors.As(cause, &repoErr) && repoErr.Err != nil {
+				cause = repoErr.Err
+			}
+
+			*err = exception.Wrap(op, exception.ErrTimeout, cause)
+		}
+	}
+}
+
+// NewRepository returns the AccountRepository written for d.
+func NewRepository(db *sql.DB, d dialect.Dialect, tableName string, opts ...RepositoryOption) AccountRepository {
+	switch d {
+	case dialect.Postgres:
+		return NewPostgresAccountRepository(db, tableName, opts...)
+	case dialect.SQLite:
+		return NewSQLiteAccountRepository(db, tableName, opts...)
+	default:
+		return NewAccountRepository(db, tableName, opts...)
+	}
+}
+
+func NewAccountRepository(db *sql.DB, tableName string, opts ...RepositoryOption) AccountRepository {
 	return &accountRepositoryImpl{
 		db:        db,
 		tableName: tableName,
+		limits:    newQueryLimits(opts),
 	}
 }
 
-func (ar *accountRepositoryImpl) Create(ctx context.Context, params models.Account) (int64, error) {
-	query := fmt.Sprintf("INSERT INTO %s (username, password, email, created_at) VALUES (?, ?, ?, ?)", ar.tableName)
-	stmt, err := ar.db.PrepareContext(ctx, query)
+func (ar *accountRepositoryImpl) Create(ctx context.Context, params models.Account) (id int64, err error) {
+	query := fmt.Sprintf("INSERT INTO %s (username, password, email, role, created_at) VALUES (?, ?, ?, ?, ?)", ar.tableName)
+	ctx, span := tracing.StartSQL(ctx, tracer, "AccountRepository.Create", ar.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := ar.limits.start(ctx, "AccountRepository.Create", query)
+	defer done(&err)
+
+	stmt, err := transaction.Conn(ctx, ar.db).PrepareContext(ctx, query)
 	if err != nil {
-		log.Println(err)
-		return 0, exception.ErrInternalServer
+		logger.Ctx(ctx).Error("AccountRepository.Create", "error", err)
+		return 0, mysqlError("AccountRepository.Create", err)
 	}
 
 	defer stmt.Close()
 
+	role := params.Role
+	if role == "" {
+		role = RoleUser
+	}
+
 	result, err := stmt.ExecContext(
 		ctx,
 		params.Username,
 		params.Password,
 		params.Email,
+		role,
 		params.CreatedAt,
 	)
 
 	if err != nil {
-		log.Println(err)
-		return 0, exception.ErrInternalServer
+		logger.Ctx(ctx).Error("AccountRepository.Create", "error", err)
+		return 0, mysqlError("AccountRepository.Create", err)
 	}
 
 	ID, err := result.LastInsertId()
 
 	if err != nil {
-		log.Println(err)
-		return 0, exception.ErrInternalServer
+		logger.Ctx(ctx).Error("AccountRepository.Create", "error", err)
+		return 0, mysqlError("AccountRepository.Create", err)
 	}
 
 	return ID, nil
 }
 
-func (ar *accountRepositoryImpl) FindByID(ctx context.Context, id int64) (models.Account, error) {
-	account := models.Account{}
+func (ar *accountRepositoryImpl) FindByID(ctx context.Context, id int64) (account models.Account, err error) {
 
-	query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at FROM %s WHERE id = ?`, ar.tableName)
+	query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id = ?`, ar.tableName)
+	ctx, span := tracing.StartSQL(ctx, tracer, "AccountRepository.FindByID", ar.tableName, query)
+	defer tracing.End(span, &err)
 
-	stmt, err := ar.db.PrepareContext(ctx, query)
+	ctx, done := ar.limits.start(ctx, "AccountRepository.FindByID", query)
+	defer done(&err)
+
+	stmt, err := transaction.Conn(ctx, ar.db).PrepareContext(ctx, query)
 	if err != nil {
-		log.Println(err)
-		return account, exception.ErrInternalServer
+		logger.Ctx(ctx).Error("AccountRepository.FindByID", "error", err)
+		return account, mysqlError("AccountRepository.FindByID", err)
 	}
 
 	defer stmt.Close()
 
 	row := stmt.QueryRowContext(ctx, id)
 
-	var password sql.NullString
+	var username, password, email sql.NullString
 	var updateAt sql.NullTime
+	var disabledAt, sessionsRevokedAt sql.NullTime
 
 	err = row.Scan(
 		&account.ID,
-		&account.Username,
-		&account.Password,
-		&account.Email,
+		&username,
+		&password,
+		&email,
 		&account.CreatedAt,
-		&account.UpdateAt,
+		&updateAt,
+		&account.Role,
+		&disabledAt,
+		&sessionsRevokedAt,
+		&account.Version,
 	)
 
-	if err != nil {
-		log.Println(err)
-		return account, exception.ErrInternalServer
+	if err == sql.ErrNoRows {
+		return account, exception.Wrap("AccountRepository.FindByID", exception.ErrNotFound, err)
 	}
 
-	if password.Valid {
-		account.Password = password.String
+	if err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.FindByID", "error", err)
+		return account, mysqlError("AccountRepository.FindByID", err)
 	}
 
-	if updateAt.Valid {
-		account.UpdateAt = updateAt.Time
-	}
+	account.Username = username.String
+	account.Password = password.String
+	account.Email = email.String
+	account.UpdateAt = updateAt.Time
+
+	account.DisabledAt = nullTime(disabledAt)
+	account.SessionsRevokedAt = nullTime(sessionsRevokedAt)
 
 	return account, nil
 }
 
-func (ar *accountRepositoryImpl) FindByEmail(ctx context.Context, email string) (models.Account, error) {
-	account := models.Account{}
+func (ar *accountRepositoryImpl) FindByEmail(ctx context.Context, email string) (account models.Account, err error) {
+
+	query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE email = ?`, ar.tableName)
+	ctx, span := tracing.StartSQL(ctx, tracer, "AccountRepository.FindByEmail", ar.tableName, query)
+	defer tracing.End(span, &err)
 
-	query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at FROM %s WHERE email = ?`, ar.tableName)
-	stmt, err := ar.db.PrepareContext(ctx, query)
+	ctx, done := ar.limits.start(ctx, "AccountRepository.FindByEmail", query)
+	defer done(&err)
+
+	stmt, err := transaction.Conn(ctx, ar.db).PrepareContext(ctx, query)
 	if err != nil {
-		log.Println(err)
-		return account, exception.ErrInternalServer
+		logger.Ctx(ctx).Error("AccountRepository.FindByEmail", "error", err)
+		return account, mysqlError("AccountRepository.FindByEmail", err)
 	}
 
 	defer stmt.Close()
 
 	row := stmt.QueryRowContext(ctx, email)
 
-	var password sql.NullString
+	var username, password, address sql.NullString
 	var updateAt sql.NullTime
+	var disabledAt, sessionsRevokedAt sql.NullTime
 
 	err = row.Scan(
 		&account.ID,
-		&account.Username,
-		&account.Password,
-		&account.Email,
+		&username,
+		&password,
+		&address,
 		&account.CreatedAt,
-		&account.UpdateAt,
+		&updateAt,
+		&account.Role,
+		&disabledAt,
+		&sessionsRevokedAt,
+		&account.Version,
 	)
 
+	if err == sql.ErrNoRows {
+		return account, exception.Wrap("AccountRepository.FindByEmail", exception.ErrNotFound, err)
+	}
+
 	if err != nil {
-		log.Println(err)
+		logger.Ctx(ctx).Error("AccountRepository.FindByEmail", "error", err)
+		return account, mysqlError("AccountRepository.FindByEmail", err)
+	}
+
+	account.Username = username.String
+	account.Password = password.String
+	account.Email = address.String
+	account.UpdateAt = updateAt.Time
+
+	account.DisabledAt = nullTime(disabledAt)
+	account.SessionsRevokedAt = nullTime(sessionsRevokedAt)
+
+	return account, nil
+}
+
+func (ar *accountRepositoryImpl) List(ctx context.Context, afterID int64, limit int) (accounts []models.Account, err error) {
+	query := fmt.Sprintf(`SELECT id, username, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id > ? ORDER BY id LIMIT ?`, ar.tableName)
+	ctx, span := tracing.StartSQL(ctx, tracer, "AccountRepository.List", ar.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := ar.limits.start(ctx, "AccountRepository.List", query)
+	defer done(&err)
 
-		return account, exception.ErrNotFound
+	stmt, err := transaction.Conn(ctx, ar.db).PrepareContext(ctx, query)
+	if err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.List", "error", err)
+		return nil, mysqlError("AccountRepository.List", err)
 	}
 
-	if password.Valid {
-		account.Password = password.String
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, afterID, limit)
+	if err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.List", "error", err)
+		return nil, mysqlError("AccountRepository.List", err)
 	}
 
-	if updateAt.Valid {
+	defer rows.Close()
+
+	accounts = []models.Account{}
+	for rows.Next() {
+		account := models.Account{}
+
+		var username, email sql.NullString
+		var updateAt, disabledAt, sessionsRevokedAt sql.NullTime
+
+		err = rows.Scan(
+			&account.ID,
+			&username,
+			&email,
+			&account.CreatedAt,
+			&updateAt,
+			&account.Role,
+			&disabledAt,
+			&sessionsRevokedAt,
+			&account.Version,
+		)
+
+		if err != nil {
+			logger.Ctx(ctx).Error("AccountRepository.List", "error", err)
+			return nil, mysqlError("AccountRepository.List", err)
+		}
+
+		account.Username = username.String
+		account.Email = email.String
 		account.UpdateAt = updateAt.Time
+		account.DisabledAt = nullTime(disabledAt)
+		account.SessionsRevokedAt = nullTime(sessionsRevokedAt)
+
+		accounts = append(accounts, account)
 	}
 
-	return account, nil
+	if err = rows.Err(); err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.List", "error", err)
+		return nil, mysqlError("AccountRepository.List", err)
+	}
+
+	return accounts, nil
 }
 
-func (ar *accountRepositoryImpl) Update(ctx context.Context, id int64, params models.Account) error {
-	query := fmt.Sprintf(`UPDATE %s SET username = ?, password = ?, email = ?, update_at = ? WHERE id = %d`, ar.tableName, id)
-	stmt, err := ar.db.PrepareContext(ctx, query)
+func (ar *accountRepositoryImpl) Update(ctx context.Context, id int64, params models.Account) (err error) {
+	query := fmt.Sprintf(`UPDATE %s SET username = ?, password = ?, email = ?, update_at = ?, role = ?, disabled_at = ?, sessions_revoked_at = ?, version = version + 1 WHERE id = %d AND version = ?`, ar.tableName, id)
+	ctx, span := tracing.StartSQL(ctx, tracer, "AccountRepository.Update", ar.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := ar.limits.start(ctx, "AccountRepository.Update", query)
+	defer done(&err)
+
+	stmt, err := transaction.Conn(ctx, ar.db).PrepareContext(ctx, query)
 	if err != nil {
-		log.Println(err)
-		return exception.ErrInternalServer
+		logger.Ctx(ctx).Error("AccountRepository.Update", "error", err)
+		return mysqlError("AccountRepository.Update", err)
 	}
 
 	defer stmt.Close()
@@ -167,46 +388,105 @@ func (ar *accountRepositoryImpl) Update(ctx context.Context, id int64, params mo
 		params.Password,
 		params.Email,
 		params.UpdateAt,
+		params.Role,
+		params.DisabledAt,
+		params.SessionsRevokedAt,
+		params.Version,
 	)
 
 	if err != nil {
-		log.Println(err)
-		return exception.ErrInternalServer
+		logger.Ctx(ctx).Error("AccountRepository.Update", "error", err)
+		return mysqlError("AccountRepository.Update", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
 
 	if rowsAffected < 1 {
-		return exception.ErrNotFound
+		return ar.missed(ctx, "AccountRepository.Update", id)
 	}
 
 	return nil
 }
 
-func (ar *accountRepositoryImpl) Delete(ctx context.Context, id int64) error {
-	query := fmt.Sprintf(`DELETE FROM %s WHERE id = %d`, ar.tableName, id)
-	stmt, err := ar.db.PrepareContext(ctx, query)
+func (ar *accountRepositoryImpl) Delete(ctx context.Context, id int64, version int64) (err error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = %d AND (? = 0 OR version = ?)`, ar.tableName, id)
+	ctx, span := tracing.StartSQL(ctx, tracer, "AccountRepository.Delete", ar.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := ar.limits.start(ctx, "AccountRepository.Delete", query)
+	defer done(&err)
+
+	stmt, err := transaction.Conn(ctx, ar.db).PrepareContext(ctx, query)
 	if err != nil {
-		log.Println(err)
-		return exception.ErrInternalServer
+		logger.Ctx(ctx).Error("AccountRepository.Delete", "error", err)
+		return mysqlError("AccountRepository.Delete", err)
 	}
 
 	defer stmt.Close()
 
 	result, err := stmt.ExecContext(
 		ctx,
+		version,
+		version,
 	)
 
 	if err != nil {
-		log.Println(err)
-		return exception.ErrInternalServer
+		logger.Ctx(ctx).Error("AccountRepository.Delete", "error", err)
+		return mysqlError("AccountRepository.Delete", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
 
 	if rowsAffected < 1 {
-		return exception.ErrNotFound
+		return ar.missed(ctx, "AccountRepository.Delete", id)
 	}
 
 	return nil
 }
+
+// missed explains a write to id that matched no row: the account is gone,
+// or it is at another version than the caller read.
+func (ar *accountRepositoryImpl) missed(ctx context.Context, op string, id int64) error {
+	query := fmt.Sprintf(`SELECT version FROM %s WHERE id = ?`, ar.tableName)
+
+	var version int64
+
+	err := transaction.Conn(ctx, ar.db).QueryRowContext(ctx, query, id).Scan(&version)
+	if err == sql.ErrNoRows {
+		return exception.Wrap(op, exception.ErrNotFound, nil)
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return mysqlError(op, err)
+	}
+
+	return exception.Wrap(op, exception.ErrConflicted, exception.ErrVersionMismatch)
+}
+
+// mysqlError classifies err, returned by op, as an exception.RepositoryError.
+func mysqlError(op string, err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case duplicateEntry:
+			return exception.Wrap(op, exception.ErrConflicted, err)
+		case lockWaitTimeout, executionTimeExceeded:
+			return exception.Wrap(op, exception.ErrTimeout, err)
+		}
+	}
+
+	if errors.Is(err, mysql.ErrInvalidConn) {
+		return exception.Wrap(op, exception.ErrUnavailable, err)
+	}
+
+	return exception.Database(op, err)
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
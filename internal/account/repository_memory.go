@@ -0,0 +1,187 @@
+package account
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"waizly/helpers/exception"
+	"waizly/models"
+)
+
+// memoryAccountRepositoryImpl keeps accounts in a map. It follows the same
+// rules as the SQL repositories, see accounttest.Run, but ignores any
+// transaction in the context: writes are applied immediately.
+type memoryAccountRepositoryImpl struct {
+	mu       sync.RWMutex
+	lastID   int64
+	accounts map[int64]models.Account
+}
+
+// NewMemoryAccountRepository returns an empty AccountRepository held in
+// memory, for tests and demos that should not need a database.
+func NewMemoryAccountRepository() AccountRepository {
+	return &memoryAccountRepositoryImpl{
+		accounts: make(map[int64]models.Account),
+	}
+}
+
+func (mr *memoryAccountRepositoryImpl) Create(ctx context.Context, params models.Account) (int64, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	if mr.taken(params, 0) {
+		return 0, exception.Wrap("AccountRepository.Create", exception.ErrConflicted, nil)
+	}
+
+	mr.lastID++
+
+	// update_at, version and role fall back to the column defaults of the SQL
+	// schema.
+	account := copyAccount(params)
+	account.ID = mr.lastID
+	account.UpdateAt = time.Now()
+	account.Version = 1
+	if account.Role == "" {
+		account.Role = RoleUser
+	}
+
+	mr.accounts[account.ID] = account
+
+	return account.ID, nil
+}
+
+func (mr *memoryAccountRepositoryImpl) FindByID(ctx context.Context, id int64) (models.Account, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	account, ok := mr.accounts[id]
+	if !ok {
+		return models.Account{}, exception.Wrap("AccountRepository.FindByID", exception.ErrNotFound, nil)
+	}
+
+	return copyAccount(account), nil
+}
+
+func (mr *memoryAccountRepositoryImpl) FindByEmail(ctx context.Context, email string) (models.Account, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	for _, account := range mr.accounts {
+		if strings.EqualFold(account.Email, email) {
+			return copyAccount(account), nil
+		}
+	}
+
+	return models.Account{}, exception.Wrap("AccountRepository.FindByEmail", exception.ErrNotFound, nil)
+}
+
+func (mr *memoryAccountRepositoryImpl) List(ctx context.Context, afterID int64, limit int) ([]models.Account, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+
+	accounts := []models.Account{}
+	for id, account := range mr.accounts {
+		if id > afterID {
+			account = copyAccount(account)
+			account.Password = ""
+			accounts = append(accounts, account)
+		}
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].ID < accounts[j].ID
+	})
+
+	if len(accounts) > limit {
+		accounts = accounts[:limit]
+	}
+
+	return accounts, nil
+}
+
+func (mr *memoryAccountRepositoryImpl) Update(ctx context.Context, id int64, params models.Account) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	account, ok := mr.accounts[id]
+	if !ok {
+		return exception.Wrap("AccountRepository.Update", exception.ErrNotFound, nil)
+	}
+
+	if params.Version != account.Version {
+		return exception.Wrap("AccountRepository.Update", exception.ErrConflicted, exception.ErrVersionMismatch)
+	}
+
+	if mr.taken(params, id) {
+		return exception.Wrap("AccountRepository.Update", exception.ErrConflicted, nil)
+	}
+
+	params = copyAccount(params)
+
+	account.Username = params.Username
+	account.Password = params.Password
+	account.Email = params.Email
+	account.UpdateAt = params.UpdateAt
+	account.Role = params.Role
+	account.DisabledAt = params.DisabledAt
+	account.SessionsRevokedAt = params.SessionsRevokedAt
+	account.Version++
+
+	mr.accounts[id] = account
+
+	return nil
+}
+
+func (mr *memoryAccountRepositoryImpl) Delete(ctx context.Context, id int64, version int64) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	account, ok := mr.accounts[id]
+	if !ok {
+		return exception.Wrap("AccountRepository.Delete", exception.ErrNotFound, nil)
+	}
+
+	if version != 0 && version != account.Version {
+		return exception.Wrap("AccountRepository.Delete", exception.ErrConflicted, exception.ErrVersionMismatch)
+	}
+
+	delete(mr.accounts, id)
+
+	return nil
+}
+
+// taken reports whether an account other than exceptID uses the username
+// or email of params, compared case-insensitively like the unique indexes
+// of the SQL schema. The caller must hold mr.mu.
+func (mr *memoryAccountRepositoryImpl) taken(params models.Account, exceptID int64) bool {
+	for id, account := range mr.accounts {
+		if id == exceptID {
+			continue
+		}
+
+		if strings.EqualFold(account.Email, params.Email) || strings.EqualFold(account.Username, params.Username) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// copyAccount returns account with its pointer fields copied, so callers
+// cannot change stored accounts through them.
+func copyAccount(account models.Account) models.Account {
+	if account.DisabledAt != nil {
+		disabledAt := *account.DisabledAt
+		account.DisabledAt = &disabledAt
+	}
+
+	if account.SessionsRevokedAt != nil {
+		sessionsRevokedAt := *account.SessionsRevokedAt
+		account.SessionsRevokedAt = &sessionsRevokedAt
+	}
+
+	return account
+}
@@ -0,0 +1,320 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/logger"
+	"waizly/helpers/tracing"
+	"waizly/helpers/transaction"
+	"waizly/models"
+)
+
+// PostgreSQL SQLSTATE codes the repository gives a meaning to. A canceled
+// query is almost always statement_timeout firing.
+const (
+	uniqueViolation     = "23505"
+	queryCanceled       = "57014"
+	lockNotAvailable    = "55P03"
+	adminShutdown       = "57P01"
+	cannotConnectNow    = "57P03"
+	connectionException = "08"
+)
+
+type postgresAccountRepositoryImpl struct {
+	db        *sql.DB
+	tableName string
+	limits    queryLimits
+}
+
+// NewPostgresAccountRepository returns an AccountRepository for a PostgreSQL
+// database migrated with db/migration/postgres.
+func NewPostgresAccountRepository(db *sql.DB, tableName string, opts ...RepositoryOption) AccountRepository {
+	return &postgresAccountRepositoryImpl{
+		db:        db,
+		tableName: tableName,
+		limits:    newQueryLimits(opts),
+	}
+}
+
+func (pr *postgresAccountRepositoryImpl) Create(ctx context.Context, params models.Account) (id int64, err error) {
+	query := fmt.Sprintf("INSERT INTO %s (username, password, email, role, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id", pr.tableName)
+	ctx, span := tracing.StartPostgresSQL(ctx, tracer, "AccountRepository.Create", pr.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := pr.limits.start(ctx, "AccountRepository.Create", query)
+	defer done(&err)
+
+	stmt, err := transaction.Conn(ctx, pr.db).PrepareContext(ctx, query)
+	if err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.Create", "error", err)
+		return 0, postgresError("AccountRepository.Create", err)
+	}
+
+	defer stmt.Close()
+
+	role := params.Role
+	if role == "" {
+		role = RoleUser
+	}
+
+	err = stmt.QueryRowContext(
+		ctx,
+		params.Username,
+		params.Password,
+		params.Email,
+		role,
+		params.CreatedAt,
+	).Scan(&id)
+
+	if err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.Create", "error", err)
+		return 0, postgresError("AccountRepository.Create", err)
+	}
+
+	return id, nil
+}
+
+func (pr *postgresAccountRepositoryImpl) FindByID(ctx context.Context, id int64) (account models.Account, err error) {
+	query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id = $1`, pr.tableName)
+	ctx, span := tracing.StartPostgresSQL(ctx, tracer, "AccountRepository.FindByID", pr.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := pr.limits.start(ctx, "AccountRepository.FindByID", query)
+	defer done(&err)
+
+	return pr.find(ctx, "AccountRepository.FindByID", query, id)
+}
+
+func (pr *postgresAccountRepositoryImpl) FindByEmail(ctx context.Context, email string) (account models.Account, err error) {
+	query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE lower(email) = lower($1)`, pr.tableName)
+	ctx, span := tracing.StartPostgresSQL(ctx, tracer, "AccountRepository.FindByEmail", pr.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := pr.limits.start(ctx, "AccountRepository.FindByEmail", query)
+	defer done(&err)
+
+	return pr.find(ctx, "AccountRepository.FindByEmail", query, email)
+}
+
+func (pr *postgresAccountRepositoryImpl) List(ctx context.Context, afterID int64, limit int) (accounts []models.Account, err error) {
+	query := fmt.Sprintf(`SELECT id, username, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id > $1 ORDER BY id LIMIT $2`, pr.tableName)
+	ctx, span := tracing.StartPostgresSQL(ctx, tracer, "AccountRepository.List", pr.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := pr.limits.start(ctx, "AccountRepository.List", query)
+	defer done(&err)
+
+	stmt, err := transaction.Conn(ctx, pr.db).PrepareContext(ctx, query)
+	if err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.List", "error", err)
+		return nil, postgresError("AccountRepository.List", err)
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, afterID, limit)
+	if err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.List", "error", err)
+		return nil, postgresError("AccountRepository.List", err)
+	}
+
+	defer rows.Close()
+
+	accounts = []models.Account{}
+	for rows.Next() {
+		account := models.Account{}
+
+		var username, email sql.NullString
+		var updateAt, disabledAt, sessionsRevokedAt sql.NullTime
+
+		err = rows.Scan(
+			&account.ID,
+			&username,
+			&email,
+			&account.CreatedAt,
+			&updateAt,
+			&account.Role,
+			&disabledAt,
+			&sessionsRevokedAt,
+			&account.Version,
+		)
+
+		if err != nil {
+			logger.Ctx(ctx).Error("AccountRepository.List", "error", err)
+			return nil, postgresError("AccountRepository.List", err)
+		}
+
+		account.Username = username.String
+		account.Email = email.String
+		account.UpdateAt = updateAt.Time
+		account.DisabledAt = nullTime(disabledAt)
+		account.SessionsRevokedAt = nullTime(sessionsRevokedAt)
+
+		accounts = append(accounts, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.List", "error", err)
+		return nil, postgresError("AccountRepository.List", err)
+	}
+
+	return accounts, nil
+}
+
+func (pr *postgresAccountRepositoryImpl) Update(ctx context.Context, id int64, params models.Account) (err error) {
+	query := fmt.Sprintf(`UPDATE %s SET username = $1, password = $2, email = $3, update_at = $4, role = $5, disabled_at = $6, sessions_revoked_at = $7, version = version + 1 WHERE id = $8 AND version = $9`, pr.tableName)
+	ctx, span := tracing.StartPostgresSQL(ctx, tracer, "AccountRepository.Update", pr.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := pr.limits.start(ctx, "AccountRepository.Update", query)
+	defer done(&err)
+
+	err = pr.exec(
+		ctx,
+		"AccountRepository.Update",
+		query,
+		params.Username,
+		params.Password,
+		params.Email,
+		params.UpdateAt,
+		params.Role,
+		params.DisabledAt,
+		params.SessionsRevokedAt,
+		id,
+		params.Version,
+	)
+	if errors.Is(err, exception.ErrNotFound) {
+		return pr.missed(ctx, "AccountRepository.Update", id)
+	}
+
+	return err
+}
+
+func (pr *postgresAccountRepositoryImpl) Delete(ctx context.Context, id int64, version int64) (err error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND ($2::bigint = 0 OR version = $2)`, pr.tableName)
+	ctx, span := tracing.StartPostgresSQL(ctx, tracer, "AccountRepository.Delete", pr.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := pr.limits.start(ctx, "AccountRepository.Delete", query)
+	defer done(&err)
+
+	err = pr.exec(ctx, "AccountRepository.Delete", query, id, version)
+	if errors.Is(err, exception.ErrNotFound) {
+		return pr.missed(ctx, "AccountRepository.Delete", id)
+	}
+
+	return err
+}
+
+func (pr *postgresAccountRepositoryImpl) find(ctx context.Context, op, query string, arg interface{}) (account models.Account, err error) {
+	stmt, err := transaction.Conn(ctx, pr.db).PrepareContext(ctx, query)
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return account, postgresError(op, err)
+	}
+
+	defer stmt.Close()
+
+	var username, password, email sql.NullString
+	var updateAt, disabledAt, sessionsRevokedAt sql.NullTime
+
+	err = stmt.QueryRowContext(ctx, arg).Scan(
+		&account.ID,
+		&username,
+		&password,
+		&email,
+		&account.CreatedAt,
+		&updateAt,
+		&account.Role,
+		&disabledAt,
+		&sessionsRevokedAt,
+		&account.Version,
+	)
+
+	if err == sql.ErrNoRows {
+		return account, exception.Wrap(op, exception.ErrNotFound, err)
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return account, postgresError(op, err)
+	}
+
+	account.Username = username.String
+	account.Password = password.String
+	account.Email = email.String
+	account.UpdateAt = updateAt.Time
+	account.DisabledAt = nullTime(disabledAt)
+	account.SessionsRevokedAt = nullTime(sessionsRevokedAt)
+
+	return account, nil
+}
+
+// missed explains a write to id that matched no row: the account is gone,
+// or it is at another version than the caller read.
+func (pr *postgresAccountRepositoryImpl) missed(ctx context.Context, op string, id int64) error {
+	query := fmt.Sprintf(`SELECT version FROM %s WHERE id = $1`, pr.tableName)
+
+	var version int64
+
+	err := transaction.Conn(ctx, pr.db).QueryRowContext(ctx, query, id).Scan(&version)
+	if err == sql.ErrNoRows {
+		return exception.Wrap(op, exception.ErrNotFound, nil)
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return postgresError(op, err)
+	}
+
+	return exception.Wrap(op, exception.ErrConflicted, exception.ErrVersionMismatch)
+}
+
+// exec runs a write and maps "no rows affected" to exception.ErrNotFound.
+func (pr *postgresAccountRepositoryImpl) exec(ctx context.Context, op, query string, args ...interface{}) error {
+	stmt, err := transaction.Conn(ctx, pr.db).PrepareContext(ctx, query)
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return postgresError(op, err)
+	}
+
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return postgresError(op, err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+
+	if rowsAffected < 1 {
+		return exception.Wrap(op, exception.ErrNotFound, nil)
+	}
+
+	return nil
+}
+
+// postgresError classifies err, returned by op, as an
+// exception.RepositoryError.
+func postgresError(op string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == uniqueViolation:
+			return exception.Wrap(op, exception.ErrConflicted, err)
+		case pqErr.Code == queryCanceled, pqErr.Code == lockNotAvailable:
+			return exception.Wrap(op, exception.ErrTimeout, err)
+		case pqErr.Code.Class() == connectionException, pqErr.Code == adminShutdown, pqErr.Code == cannotConnectNow:
+			return exception.Wrap(op, exception.ErrUnavailable, err)
+		}
+	}
+
+	return exception.Database(op, err)
+}
@@ -0,0 +1,180 @@
+package account_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/exception"
+	"waizly/internal/account"
+	"waizly/internal/constant"
+	"waizly/internal/mock"
+)
+
+func TestPostgresCreate(t *testing.T) {
+	query := regexp.QuoteMeta(fmt.Sprintf(`INSERT INTO %s (username, password, email, role, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`, constant.TableAccount))
+
+	t.Run("Test Create Success", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewPostgresAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{"id"}).AddRow(7)
+		mock.ExpectPrepare(query).ExpectQuery().WithArgs(accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.Role, accountStruct.CreatedAt).WillReturnRows(rows)
+
+		ID, err := repo.Create(context.TODO(), accountStruct)
+
+		assert.Equal(t, int64(7), ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test Create Unique Violation", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewPostgresAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		mock.ExpectPrepare(query).ExpectQuery().WillReturnError(&pq.Error{Code: "23505"})
+
+		ID, err := repo.Create(context.TODO(), accountStruct)
+
+		assert.Zero(t, ID)
+		assert.ErrorIs(t, err, exception.ErrConflicted)
+	})
+
+	t.Run("Test Create Connection Lost", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewPostgresAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		mock.ExpectPrepare(query).ExpectQuery().WillReturnError(sql.ErrConnDone)
+
+		_, err := repo.Create(context.TODO(), accountStruct)
+
+		assert.ErrorIs(t, err, exception.ErrUnavailable)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("Test Create Statement Timeout", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewPostgresAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		mock.ExpectPrepare(query).ExpectQuery().WillReturnError(&pq.Error{Code: "57014"})
+
+		_, err := repo.Create(context.TODO(), accountStruct)
+
+		assert.ErrorIs(t, err, exception.ErrTimeout)
+	})
+}
+
+func TestPostgresFindByID(t *testing.T) {
+	query := regexp.QuoteMeta(fmt.Sprintf(`FROM %s WHERE id = $1`, constant.TableAccount))
+	columns := []string{"id", "username", "password", "email", "created_at", "update_at", "role", "disabled_at", "sessions_revoked_at", "version"}
+
+	t.Run("Test FindByID Success", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewPostgresAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		rows := sqlmock.NewRows(columns).AddRow(1, accountStruct.Username, nil, accountStruct.Email, currentTime, nil, account.RoleUser, nil, nil, 1)
+		mock.ExpectPrepare(query).ExpectQuery().WithArgs(int64(1)).WillReturnRows(rows)
+
+		result, err := repo.FindByID(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, accountStruct.Username, result.Username)
+		assert.Empty(t, result.Password)
+		assert.True(t, result.UpdateAt.IsZero())
+		assert.Nil(t, result.DisabledAt)
+	})
+
+	t.Run("Test FindByID Not Found", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewPostgresAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		mock.ExpectPrepare(query).ExpectQuery().WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := repo.FindByID(context.TODO(), 1)
+
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+	})
+}
+
+func TestPostgresUpdate(t *testing.T) {
+	query := regexp.QuoteMeta(fmt.Sprintf(`UPDATE %s SET username = $1, password = $2, email = $3, update_at = $4, role = $5, disabled_at = $6, sessions_revoked_at = $7, version = version + 1 WHERE id = $8 AND version = $9`, constant.TableAccount))
+	versionQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT version FROM %s WHERE id = $1`, constant.TableAccount))
+
+	t.Run("Test Update Success", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewPostgresAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.UpdateAt, accountStruct.Role, accountStruct.DisabledAt, accountStruct.SessionsRevokedAt, int64(1), accountStruct.Version).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.Update(context.TODO(), 1, accountStruct))
+	})
+
+	t.Run("Test Update Unique Violation", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewPostgresAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		mock.ExpectPrepare(query).ExpectExec().WillReturnError(&pq.Error{Code: "23505"})
+
+		assert.ErrorIs(t, repo.Update(context.TODO(), 1, accountStruct), exception.ErrConflicted)
+	})
+
+	t.Run("Test Update Not Found", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewPostgresAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		mock.ExpectPrepare(query).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(versionQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+		assert.ErrorIs(t, repo.Update(context.TODO(), 1, accountStruct), exception.ErrNotFound)
+	})
+
+	t.Run("Test Update Stale Version", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewPostgresAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		mock.ExpectPrepare(query).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(versionQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+
+		err := repo.Update(context.TODO(), 1, accountStruct)
+
+		assert.ErrorIs(t, err, exception.ErrConflicted)
+		assert.ErrorIs(t, err, exception.ErrVersionMismatch)
+	})
+}
+
+func TestPostgresDelete(t *testing.T) {
+	db, mock := mock.NewMock()
+	repo := account.NewPostgresAccountRepository(db, constant.TableAccount)
+
+	defer db.Close()
+
+	query := regexp.QuoteMeta(fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND ($2::bigint = 0 OR version = $2)`, constant.TableAccount))
+	mock.ExpectPrepare(query).ExpectExec().WithArgs(int64(1), int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Delete(context.TODO(), 1, 3))
+}
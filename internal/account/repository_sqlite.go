@@ -0,0 +1,316 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/logger"
+	"waizly/helpers/tracing"
+	"waizly/helpers/transaction"
+	"waizly/models"
+)
+
+type sqliteAccountRepositoryImpl struct {
+	db        *sql.DB
+	tableName string
+	limits    queryLimits
+}
+
+// NewSQLiteAccountRepository returns an AccountRepository for an SQLite
+// database migrated with db/migration/sqlite.
+func NewSQLiteAccountRepository(db *sql.DB, tableName string, opts ...RepositoryOption) AccountRepository {
+	return &sqliteAccountRepositoryImpl{
+		db:        db,
+		tableName: tableName,
+		limits:    newQueryLimits(opts),
+	}
+}
+
+func (sr *sqliteAccountRepositoryImpl) Create(ctx context.Context, params models.Account) (id int64, err error) {
+	query := fmt.Sprintf("INSERT INTO %s (username, password, email, role, created_at) VALUES (?, ?, ?, ?, ?)", sr.tableName)
+	ctx, span := tracing.StartSQLiteSQL(ctx, tracer, "AccountRepository.Create", sr.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := sr.limits.start(ctx, "AccountRepository.Create", query)
+	defer done(&err)
+
+	stmt, err := transaction.Conn(ctx, sr.db).PrepareContext(ctx, query)
+	if err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.Create", "error", err)
+		return 0, sqliteError("AccountRepository.Create", err)
+	}
+
+	defer stmt.Close()
+
+	role := params.Role
+	if role == "" {
+		role = RoleUser
+	}
+
+	result, err := stmt.ExecContext(
+		ctx,
+		params.Username,
+		params.Password,
+		params.Email,
+		role,
+		params.CreatedAt,
+	)
+
+	if err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.Create", "error", err)
+		return 0, sqliteError("AccountRepository.Create", err)
+	}
+
+	id, err = result.LastInsertId()
+	if err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.Create", "error", err)
+		return 0, sqliteError("AccountRepository.Create", err)
+	}
+
+	return id, nil
+}
+
+func (sr *sqliteAccountRepositoryImpl) FindByID(ctx context.Context, id int64) (account models.Account, err error) {
+	query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id = ?`, sr.tableName)
+	ctx, span := tracing.StartSQLiteSQL(ctx, tracer, "AccountRepository.FindByID", sr.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := sr.limits.start(ctx, "AccountRepository.FindByID", query)
+	defer done(&err)
+
+	return sr.find(ctx, "AccountRepository.FindByID", query, id)
+}
+
+func (sr *sqliteAccountRepositoryImpl) FindByEmail(ctx context.Context, email string) (account models.Account, err error) {
+	query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE email = ?`, sr.tableName)
+	ctx, span := tracing.StartSQLiteSQL(ctx, tracer, "AccountRepository.FindByEmail", sr.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := sr.limits.start(ctx, "AccountRepository.FindByEmail", query)
+	defer done(&err)
+
+	return sr.find(ctx, "AccountRepository.FindByEmail", query, email)
+}
+
+func (sr *sqliteAccountRepositoryImpl) List(ctx context.Context, afterID int64, limit int) (accounts []models.Account, err error) {
+	query := fmt.Sprintf(`SELECT id, username, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id > ? ORDER BY id LIMIT ?`, sr.tableName)
+	ctx, span := tracing.StartSQLiteSQL(ctx, tracer, "AccountRepository.List", sr.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := sr.limits.start(ctx, "AccountRepository.List", query)
+	defer done(&err)
+
+	stmt, err := transaction.Conn(ctx, sr.db).PrepareContext(ctx, query)
+	if err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.List", "error", err)
+		return nil, sqliteError("AccountRepository.List", err)
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, afterID, limit)
+	if err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.List", "error", err)
+		return nil, sqliteError("AccountRepository.List", err)
+	}
+
+	defer rows.Close()
+
+	accounts = []models.Account{}
+	for rows.Next() {
+		account := models.Account{}
+
+		var username, email sql.NullString
+		var updateAt, disabledAt, sessionsRevokedAt sql.NullTime
+
+		err = rows.Scan(
+			&account.ID,
+			&username,
+			&email,
+			&account.CreatedAt,
+			&updateAt,
+			&account.Role,
+			&disabledAt,
+			&sessionsRevokedAt,
+			&account.Version,
+		)
+
+		if err != nil {
+			logger.Ctx(ctx).Error("AccountRepository.List", "error", err)
+			return nil, sqliteError("AccountRepository.List", err)
+		}
+
+		account.Username = username.String
+		account.Email = email.String
+		account.UpdateAt = updateAt.Time
+		account.DisabledAt = nullTime(disabledAt)
+		account.SessionsRevokedAt = nullTime(sessionsRevokedAt)
+
+		accounts = append(accounts, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Ctx(ctx).Error("AccountRepository.List", "error", err)
+		return nil, sqliteError("AccountRepository.List", err)
+	}
+
+	return accounts, nil
+}
+
+func (sr *sqliteAccountRepositoryImpl) Update(ctx context.Context, id int64, params models.Account) (err error) {
+	query := fmt.Sprintf(`UPDATE %s SET username = ?, password = ?, email = ?, update_at = ?, role = ?, disabled_at = ?, sessions_revoked_at = ?, version = version + 1 WHERE id = ? AND version = ?`, sr.tableName)
+	ctx, span := tracing.StartSQLiteSQL(ctx, tracer, "AccountRepository.Update", sr.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := sr.limits.start(ctx, "AccountRepository.Update", query)
+	defer done(&err)
+
+	err = sr.exec(
+		ctx,
+		"AccountRepository.Update",
+		query,
+		params.Username,
+		params.Password,
+		params.Email,
+		params.UpdateAt,
+		params.Role,
+		params.DisabledAt,
+		params.SessionsRevokedAt,
+		id,
+		params.Version,
+	)
+	if errors.Is(err, exception.ErrNotFound) {
+		return sr.missed(ctx, "AccountRepository.Update", id)
+	}
+
+	return err
+}
+
+func (sr *sqliteAccountRepositoryImpl) Delete(ctx context.Context, id int64, version int64) (err error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = ? AND (? = 0 OR version = ?)`, sr.tableName)
+	ctx, span := tracing.StartSQLiteSQL(ctx, tracer, "AccountRepository.Delete", sr.tableName, query)
+	defer tracing.End(span, &err)
+
+	ctx, done := sr.limits.start(ctx, "AccountRepository.Delete", query)
+	defer done(&err)
+
+	err = sr.exec(ctx, "AccountRepository.Delete", query, id, version, version)
+	if errors.Is(err, exception.ErrNotFound) {
+		return sr.missed(ctx, "AccountRepository.Delete", id)
+	}
+
+	return err
+}
+
+func (sr *sqliteAccountRepositoryImpl) find(ctx context.Context, op, query string, arg interface{}) (account models.Account, err error) {
+	stmt, err := transaction.Conn(ctx, sr.db).PrepareContext(ctx, query)
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return account, sqliteError(op, err)
+	}
+
+	defer stmt.Close()
+
+	var username, password, email sql.NullString
+	var updateAt, disabledAt, sessionsRevokedAt sql.NullTime
+
+	err = stmt.QueryRowContext(ctx, arg).Scan(
+		&account.ID,
+		&username,
+		&password,
+		&email,
+		&account.CreatedAt,
+		&updateAt,
+		&account.Role,
+		&disabledAt,
+		&sessionsRevokedAt,
+		&account.Version,
+	)
+
+	if err == sql.ErrNoRows {
+		return account, exception.Wrap(op, exception.ErrNotFound, err)
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return account, sqliteError(op, err)
+	}
+
+	account.Username = username.String
+	account.Password = password.String
+	account.Email = email.String
+	account.UpdateAt = updateAt.Time
+	account.DisabledAt = nullTime(disabledAt)
+	account.SessionsRevokedAt = nullTime(sessionsRevokedAt)
+
+	return account, nil
+}
+
+// missed explains a write to id that matched no row: the account is gone,
+// or it is at another version than the caller read.
+func (sr *sqliteAccountRepositoryImpl) missed(ctx context.Context, op string, id int64) error {
+	query := fmt.Sprintf(`SELECT version FROM %s WHERE id = ?`, sr.tableName)
+
+	var version int64
+
+	err := transaction.Conn(ctx, sr.db).QueryRowContext(ctx, query, id).Scan(&version)
+	if err == sql.ErrNoRows {
+		return exception.Wrap(op, exception.ErrNotFound, nil)
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return sqliteError(op, err)
+	}
+
+	return exception.Wrap(op, exception.ErrConflicted, exception.ErrVersionMismatch)
+}
+
+// exec runs a write and maps "no rows affected" to exception.ErrNotFound.
+func (sr *sqliteAccountRepositoryImpl) exec(ctx context.Context, op, query string, args ...interface{}) error {
+	stmt, err := transaction.Conn(ctx, sr.db).PrepareContext(ctx, query)
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return sqliteError(op, err)
+	}
+
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return sqliteError(op, err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+
+	if rowsAffected < 1 {
+		return exception.Wrap(op, exception.ErrNotFound, nil)
+	}
+
+	return nil
+}
+
+// sqliteError classifies err, returned by op, as an
+// exception.RepositoryError. SQLITE_BUSY means busy_timeout ran out while
+// another connection held the write lock, SQLITE_INTERRUPT that the context
+// of the query was cancelled.
+func sqliteError(op string, err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch {
+		case sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+			return exception.Wrap(op, exception.ErrConflicted, err)
+		case sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY, sqliteErr.Code() == sqlite3.SQLITE_INTERRUPT:
+			return exception.Wrap(op, exception.ErrTimeout, err)
+		}
+	}
+
+	return exception.Database(op, err)
+}
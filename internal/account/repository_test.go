@@ -1,17 +1,23 @@
 package account_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"testing"
 	"time"
 
+	"waizly/helpers/exception"
+	"waizly/helpers/logger"
 	"waizly/internal/account"
 	"waizly/internal/constant"
 	"waizly/internal/mock"
 	"waizly/models"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -23,6 +29,8 @@ var accountStruct = models.Account{
 	Email:     "email@test.com",
 	CreatedAt: currentTime,
 	UpdateAt:  currentTime,
+	Role:      account.RoleUser,
+	Version:   1,
 }
 
 func TestCreat(t *testing.T) {
@@ -35,7 +43,7 @@ func TestCreat(t *testing.T) {
 		query := fmt.Sprintf(`INSERT INTO %s`, constant.TableAccount)
 		ctx := context.TODO()
 
-		mock.ExpectPrepare(query).ExpectExec().WithArgs(accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.CreatedAt).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.Role, accountStruct.CreatedAt).WillReturnResult(sqlmock.NewResult(1, 1))
 
 		ID, err := repo.Create(ctx, accountStruct)
 
@@ -52,13 +60,29 @@ func TestCreat(t *testing.T) {
 		query := fmt.Sprintf(`INSERT INTO %s`, constant.TableAccount)
 		ctx := context.TODO()
 
-		mock.ExpectPrepare(query).ExpectExec().WithArgs(accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.CreatedAt).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.Role, accountStruct.CreatedAt).WillReturnResult(sqlmock.NewResult(0, 0))
 
 		ID, err := repo.Create(ctx, accountStruct)
 
 		assert.Equal(t, int64(0), ID)
 		assert.NoError(t, err)
 	})
+
+	t.Run("Test Create Duplicate Entry", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`INSERT INTO %s`, constant.TableAccount)
+		ctx := context.TODO()
+
+		mock.ExpectPrepare(query).ExpectExec().WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+		_, err := repo.Create(ctx, accountStruct)
+
+		assert.ErrorIs(t, err, exception.ErrConflicted)
+	})
 }
 
 func TestFindByID(t *testing.T) {
@@ -68,8 +92,8 @@ func TestFindByID(t *testing.T) {
 
 		defer db.Close()
 
-		query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at FROM %s WHERE id = ?`, constant.TableAccount)
-		rows := sqlmock.NewRows([]string{"id", "username", "password", "email", "created_at", "update_at"}).AddRow(accountStruct.ID, accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.CreatedAt, accountStruct.UpdateAt)
+		query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id = ?`, constant.TableAccount)
+		rows := sqlmock.NewRows([]string{"id", "username", "password", "email", "created_at", "update_at", "role", "disabled_at", "sessions_revoked_at", "version"}).AddRow(accountStruct.ID, accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.CreatedAt, accountStruct.UpdateAt, accountStruct.Role, nil, nil, accountStruct.Version)
 
 		ctx := context.TODO()
 
@@ -87,8 +111,8 @@ func TestFindByID(t *testing.T) {
 
 		defer db.Close()
 
-		query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at FROM %s WHERE id = ?`, constant.TableAccount)
-		rows := sqlmock.NewRows([]string{"id", "username", "password", "email", "created_at", "update_at"})
+		query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id = ?`, constant.TableAccount)
+		rows := sqlmock.NewRows([]string{"id", "username", "password", "email", "created_at", "update_at", "role", "disabled_at", "sessions_revoked_at", "version"})
 
 		ctx := context.TODO()
 
@@ -99,6 +123,46 @@ func TestFindByID(t *testing.T) {
 		assert.Empty(t, accountStruct)
 		assert.Error(t, err)
 	})
+
+	t.Run("Test FindByID Timeout", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewAccountRepository(db, constant.TableAccount, account.WithQueryTimeout(10*time.Millisecond))
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id = ?`, constant.TableAccount)
+		rows := sqlmock.NewRows([]string{"id"}).AddRow(accountStruct.ID)
+
+		mock.ExpectPrepare(query).ExpectQuery().WithArgs(accountStruct.ID).WillDelayFor(time.Second).WillReturnRows(rows)
+
+		started := time.Now()
+		_, err := repo.FindByID(context.TODO(), accountStruct.ID)
+
+		assert.ErrorIs(t, err, exception.ErrTimeout)
+		assert.Less(t, time.Since(started), 500*time.Millisecond)
+	})
+
+	t.Run("Test FindByID Slow Query Logged", func(t *testing.T) {
+		var out bytes.Buffer
+		logger.SetDefault(logger.New(&out, logger.FormatJSON, logger.LevelInfo))
+		defer logger.SetDefault(logger.New(&bytes.Buffer{}, logger.FormatJSON, logger.LevelInfo))
+
+		db, mock := mock.NewMock()
+		repo := account.NewAccountRepository(db, constant.TableAccount, account.WithSlowQueryLog(5*time.Millisecond))
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id = ?`, constant.TableAccount)
+		rows := sqlmock.NewRows([]string{"id", "username", "password", "email", "created_at", "update_at", "role", "disabled_at", "sessions_revoked_at", "version"}).AddRow(accountStruct.ID, accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.CreatedAt, accountStruct.UpdateAt, accountStruct.Role, nil, nil, accountStruct.Version)
+
+		mock.ExpectPrepare(query).ExpectQuery().WithArgs(accountStruct.ID).WillDelayFor(20 * time.Millisecond).WillReturnRows(rows)
+
+		_, err := repo.FindByID(context.TODO(), accountStruct.ID)
+
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), `"msg":"slow query"`)
+		assert.Contains(t, out.String(), `"op":"AccountRepository.FindByID"`)
+	})
 }
 
 func TestFindByEmail(t *testing.T) {
@@ -108,8 +172,8 @@ func TestFindByEmail(t *testing.T) {
 
 		defer db.Close()
 
-		query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at FROM %s WHERE email = ?`, constant.TableAccount)
-		rows := sqlmock.NewRows([]string{"id", "username", "password", "email", "created_at", "update_at"}).AddRow(accountStruct.ID, accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.CreatedAt, accountStruct.UpdateAt)
+		query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE email = ?`, constant.TableAccount)
+		rows := sqlmock.NewRows([]string{"id", "username", "password", "email", "created_at", "update_at", "role", "disabled_at", "sessions_revoked_at", "version"}).AddRow(accountStruct.ID, accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.CreatedAt, accountStruct.UpdateAt, accountStruct.Role, nil, nil, accountStruct.Version)
 
 		ctx := context.TODO()
 
@@ -127,8 +191,8 @@ func TestFindByEmail(t *testing.T) {
 
 		defer db.Close()
 
-		query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at FROM %s WHERE email = ?`, constant.TableAccount)
-		rows := sqlmock.NewRows([]string{"id", "username", "password", "email", "created_at", "update_at"})
+		query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE email = ?`, constant.TableAccount)
+		rows := sqlmock.NewRows([]string{"id", "username", "password", "email", "created_at", "update_at", "role", "disabled_at", "sessions_revoked_at", "version"})
 
 		ctx := context.TODO()
 
@@ -137,6 +201,84 @@ func TestFindByEmail(t *testing.T) {
 		accountStruct, err := repo.FindByEmail(ctx, accountStruct.Email)
 
 		assert.Empty(t, accountStruct)
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+	})
+
+	t.Run("Test FindByEmail Connection Lost", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE email = ?`, constant.TableAccount)
+
+		ctx := context.TODO()
+
+		mock.ExpectPrepare(query).ExpectQuery().WithArgs(accountStruct.Email).WillReturnError(mysql.ErrInvalidConn)
+
+		_, err := repo.FindByEmail(ctx, accountStruct.Email)
+
+		assert.ErrorIs(t, err, exception.ErrUnavailable)
+		assert.False(t, errors.Is(err, exception.ErrNotFound), "a failed lookup must not look like a miss")
+	})
+
+	t.Run("Test FindByEmail Lock Wait Timeout", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE email = ?`, constant.TableAccount)
+
+		ctx := context.TODO()
+
+		mock.ExpectPrepare(query).ExpectQuery().WithArgs(accountStruct.Email).WillReturnError(&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"})
+
+		_, err := repo.FindByEmail(ctx, accountStruct.Email)
+
+		assert.ErrorIs(t, err, exception.ErrTimeout)
+	})
+}
+
+func TestList(t *testing.T) {
+	t.Run("Test List Success", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		query := regexp.QuoteMeta(fmt.Sprintf(`SELECT id, username, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id > ? ORDER BY id LIMIT ?`, constant.TableAccount))
+		rows := sqlmock.NewRows([]string{"id", "username", "email", "created_at", "update_at", "role", "disabled_at", "sessions_revoked_at", "version"}).
+			AddRow(accountStruct.ID, accountStruct.Username, accountStruct.Email, accountStruct.CreatedAt, nil, accountStruct.Role, currentTime, nil, accountStruct.Version)
+
+		ctx := context.TODO()
+
+		mock.ExpectPrepare(query).ExpectQuery().WithArgs(int64(0), 10).WillReturnRows(rows)
+
+		accounts, err := repo.List(ctx, 0, 10)
+
+		assert.NoError(t, err)
+		assert.Len(t, accounts, 1)
+		assert.Empty(t, accounts[0].Password)
+		assert.Equal(t, currentTime, *accounts[0].DisabledAt)
+		assert.Nil(t, accounts[0].SessionsRevokedAt)
+	})
+
+	t.Run("Test List Error", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		query := regexp.QuoteMeta(fmt.Sprintf(`SELECT id, username, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id > ? ORDER BY id LIMIT ?`, constant.TableAccount))
+
+		ctx := context.TODO()
+
+		mock.ExpectPrepare(query).ExpectQuery().WithArgs(int64(0), 10).WillReturnError(fmt.Errorf("connection reset"))
+
+		accounts, err := repo.List(ctx, 0, 10)
+
+		assert.Nil(t, accounts)
 		assert.Error(t, err)
 	})
 }
@@ -152,7 +294,7 @@ func TestUpdate(t *testing.T) {
 
 		ctx := context.TODO()
 
-		mock.ExpectPrepare(query).ExpectExec().WithArgs(accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.UpdateAt).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.UpdateAt, accountStruct.Role, accountStruct.DisabledAt, accountStruct.SessionsRevokedAt, accountStruct.Version).WillReturnResult(sqlmock.NewResult(0, 1))
 
 		err := repo.Update(ctx, accountStruct.ID, accountStruct)
 
@@ -166,47 +308,105 @@ func TestUpdate(t *testing.T) {
 		defer db.Close()
 
 		query := fmt.Sprintf(`UPDATE %s SET`, constant.TableAccount)
+		versionQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT version FROM %s WHERE id = ?`, constant.TableAccount))
 
 		ctx := context.TODO()
 
-		mock.ExpectPrepare(query).ExpectExec().WithArgs(accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.UpdateAt).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.UpdateAt, accountStruct.Role, accountStruct.DisabledAt, accountStruct.SessionsRevokedAt, accountStruct.Version).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(versionQuery).WithArgs(accountStruct.ID).WillReturnRows(sqlmock.NewRows([]string{"version"}))
 
 		err := repo.Update(ctx, accountStruct.ID, accountStruct)
 
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+	})
+
+	t.Run("Test Update Stale Version", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		query := regexp.QuoteMeta(fmt.Sprintf(`version = version + 1 WHERE id = %d AND version = ?`, accountStruct.ID))
+		versionQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT version FROM %s WHERE id = ?`, constant.TableAccount))
+
+		ctx := context.TODO()
+
+		mock.ExpectPrepare(query).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(versionQuery).WithArgs(accountStruct.ID).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+
+		err := repo.Update(ctx, accountStruct.ID, accountStruct)
+
+		assert.ErrorIs(t, err, exception.ErrConflicted)
+		assert.ErrorIs(t, err, exception.ErrVersionMismatch)
+	})
+
+	t.Run("Test Update Duplicate Entry", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`UPDATE %s SET`, constant.TableAccount)
+
+		ctx := context.TODO()
+
+		mock.ExpectPrepare(query).ExpectExec().WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+		err := repo.Update(ctx, accountStruct.ID, accountStruct)
+
+		assert.ErrorIs(t, err, exception.ErrConflicted)
 	})
 }
 
 func TestDelete(t *testing.T) {
+	query := regexp.QuoteMeta(fmt.Sprintf(`DELETE FROM %s WHERE id = %d AND (? = 0 OR version = ?)`, constant.TableAccount, accountStruct.ID))
+	versionQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT version FROM %s WHERE id = ?`, constant.TableAccount))
+
 	t.Run("Test Delete Success", func(t *testing.T) {
 		db, mock := mock.NewMock()
 		repo := account.NewAccountRepository(db, constant.TableAccount)
 
 		defer db.Close()
 
-		query := fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, constant.TableAccount)
 		ctx := context.TODO()
 
-		mock.ExpectPrepare(query).ExpectExec().WithArgs().WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(int64(0), int64(0)).WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err := repo.Delete(ctx, accountStruct.ID)
+		err := repo.Delete(ctx, accountStruct.ID, 0)
 
 		assert.NoError(t, err)
 	})
 
-	t.Run("Test Delete Error", func(t *testing.T) {
+	t.Run("Test Delete Not Found", func(t *testing.T) {
 		db, mock := mock.NewMock()
 		repo := account.NewAccountRepository(db, constant.TableAccount)
 
 		defer db.Close()
 
-		query := fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, constant.TableAccount)
 		ctx := context.TODO()
 
-		mock.ExpectPrepare(query).ExpectExec().WithArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(query).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(versionQuery).WithArgs(accountStruct.ID).WillReturnRows(sqlmock.NewRows([]string{"version"}))
 
-		err := repo.Delete(ctx, accountStruct.ID)
+		err := repo.Delete(ctx, accountStruct.ID, 0)
 
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+	})
+
+	t.Run("Test Delete Stale Version", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := account.NewAccountRepository(db, constant.TableAccount)
+
+		defer db.Close()
+
+		ctx := context.TODO()
+
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(int64(2), int64(2)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(versionQuery).WithArgs(accountStruct.ID).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+
+		err := repo.Delete(ctx, accountStruct.ID, 2)
+
+		assert.ErrorIs(t, err, exception.ErrConflicted)
+		assert.ErrorIs(t, err, exception.ErrVersionMismatch)
 	})
 }
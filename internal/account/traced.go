@@ -0,0 +1,95 @@
+package account
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"waizly/config/jwt"
+	"waizly/helpers/response"
+	"waizly/helpers/tracing"
+	"waizly/models"
+)
+
+type tracedUseCase struct {
+	usecase AccountUseCase
+}
+
+// NewTracedUseCase wraps every AccountUseCase method in a span. Repository
+// calls made by the use case become its children; a response carrying an
+// error marks the span as failed.
+func NewTracedUseCase(usecase AccountUseCase) AccountUseCase {
+	return &tracedUseCase{
+		usecase: usecase,
+	}
+}
+
+func (tu *tracedUseCase) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "AccountUseCase."+method, trace.WithAttributes(attrs...))
+}
+
+func (tu *tracedUseCase) end(span trace.Span, res response.Response) response.Response {
+	err := res.Err()
+	span.SetAttributes(attribute.String("response.status", response.StatusOf(res)))
+	tracing.End(span, &err)
+
+	return res
+}
+
+func (tu *tracedUseCase) Register(ctx context.Context, params models.RegisterRequest) response.Response {
+	ctx, span := tu.start(ctx, "Register")
+	return tu.end(span, tu.usecase.Register(ctx, params))
+}
+
+func (tu *tracedUseCase) Login(ctx context.Context, params models.LoginRequest) (response.Response, models.Token) {
+	ctx, span := tu.start(ctx, "Login")
+	res, token := tu.usecase.Login(ctx, params)
+
+	return tu.end(span, res), token
+}
+
+func (tu *tracedUseCase) DetailAccount(ctx context.Context, id int64) response.Response {
+	ctx, span := tu.start(ctx, "DetailAccount", attribute.Int64("account.id", id))
+	return tu.end(span, tu.usecase.DetailAccount(ctx, id))
+}
+
+func (tu *tracedUseCase) UpdateAccount(ctx context.Context, id int64, params models.Account) response.Response {
+	ctx, span := tu.start(ctx, "UpdateAccount", attribute.Int64("account.id", id))
+	return tu.end(span, tu.usecase.UpdateAccount(ctx, id, params))
+}
+
+func (tu *tracedUseCase) DeleteAccount(ctx context.Context, id int64, version int64) response.Response {
+	ctx, span := tu.start(ctx, "DeleteAccount", attribute.Int64("account.id", id))
+	return tu.end(span, tu.usecase.DeleteAccount(ctx, id, version))
+}
+
+func (tu *tracedUseCase) Authenticate(ctx context.Context, claims jwt.JWTclaim) response.Response {
+	ctx, span := tu.start(ctx, "Authenticate", attribute.Int64("account.id", claims.ID))
+	return tu.end(span, tu.usecase.Authenticate(ctx, claims))
+}
+
+func (tu *tracedUseCase) ListAccounts(ctx context.Context, afterID int64, limit int) response.Response {
+	ctx, span := tu.start(ctx, "ListAccounts")
+	return tu.end(span, tu.usecase.ListAccounts(ctx, afterID, limit))
+}
+
+func (tu *tracedUseCase) SetDisabled(ctx context.Context, id int64, disabled bool) response.Response {
+	ctx, span := tu.start(ctx, "SetDisabled", attribute.Int64("account.id", id))
+	return tu.end(span, tu.usecase.SetDisabled(ctx, id, disabled))
+}
+
+func (tu *tracedUseCase) ResetPassword(ctx context.Context, id int64, password string) response.Response {
+	ctx, span := tu.start(ctx, "ResetPassword", attribute.Int64("account.id", id))
+	return tu.end(span, tu.usecase.ResetPassword(ctx, id, password))
+}
+
+func (tu *tracedUseCase) AssignRole(ctx context.Context, id int64, role string) response.Response {
+	ctx, span := tu.start(ctx, "AssignRole", attribute.Int64("account.id", id))
+	return tu.end(span, tu.usecase.AssignRole(ctx, id, role))
+}
+
+func (tu *tracedUseCase) RevokeSessions(ctx context.Context, id int64) response.Response {
+	ctx, span := tu.start(ctx, "RevokeSessions", attribute.Int64("account.id", id))
+	return tu.end(span, tu.usecase.RevokeSessions(ctx, id))
+}
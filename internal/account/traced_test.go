@@ -0,0 +1,101 @@
+package account_test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+
+	bcryptmocks "waizly/config/bcrypt/mocks"
+	"waizly/helpers/tracing"
+	"waizly/internal/account"
+	"waizly/internal/constant"
+	"waizly/internal/mock"
+)
+
+// Tracers bind to the global provider the first time one is set, so the
+// package installs a single in-memory exporter and tests reset it.
+var spans = tracetest.NewInMemoryExporter()
+
+func init() {
+	otel.SetTracerProvider(tracing.NewProvider(sdktrace.WithSyncer(spans)))
+}
+
+func findSpan(stubs tracetest.SpanStubs, name string) (tracetest.SpanStub, bool) {
+	for _, stub := range stubs {
+		if stub.Name == name {
+			return stub, true
+		}
+	}
+
+	return tracetest.SpanStub{}, false
+}
+
+func TestTracing(t *testing.T) {
+	query := regexp.QuoteMeta(fmt.Sprintf(`SELECT id, username, password, email, created_at, update_at, role, disabled_at, sessions_revoked_at, version FROM %s WHERE id = ?`, constant.TableAccount))
+
+	t.Run("Repository Spans Are Children Of Use Case Span", func(t *testing.T) {
+		spans.Reset()
+
+		db, sqlMock := mock.NewMock()
+		defer db.Close()
+
+		sqlMock.ExpectPrepare(query).ExpectQuery().WithArgs(accountStruct.ID).WillReturnRows(
+			sqlMock.NewRows([]string{"id", "username", "password", "email", "created_at", "update_at", "role", "disabled_at", "sessions_revoked_at", "version"}).
+				AddRow(accountStruct.ID, accountStruct.Username, accountStruct.Password, accountStruct.Email, accountStruct.CreatedAt, accountStruct.UpdateAt, accountStruct.Role, nil, nil, accountStruct.Version),
+		)
+
+		repo := account.NewAccountRepository(db, constant.TableAccount)
+		useCase := account.NewTracedUseCase(account.NewAccountUseCase(repo, new(bcryptmocks.Bcrypt), newAuditUseCase(), newOutboxRepository(), newTransactor()))
+
+		resp := useCase.DetailAccount(context.TODO(), accountStruct.ID)
+		assert.NoError(t, resp.Err())
+
+		stubs := spans.GetSpans()
+		parent, ok := findSpan(stubs, "AccountUseCase.DetailAccount")
+		if !assert.True(t, ok) {
+			return
+		}
+
+		child, ok := findSpan(stubs, "AccountRepository.FindByID")
+		if !assert.True(t, ok) {
+			return
+		}
+
+		assert.Equal(t, parent.SpanContext.SpanID(), child.Parent.SpanID())
+		assert.Equal(t, parent.SpanContext.TraceID(), child.SpanContext.TraceID())
+		assert.Contains(t, child.Attributes, semconv.DBSystemMySQL)
+		assert.Contains(t, child.Attributes, semconv.DBSQLTableKey.String(constant.TableAccount))
+		assert.Equal(t, codes.Unset, child.Status.Code)
+	})
+
+	t.Run("Errors Are Recorded", func(t *testing.T) {
+		spans.Reset()
+
+		db, sqlMock := mock.NewMock()
+		defer db.Close()
+
+		sqlMock.ExpectPrepare(query).ExpectQuery().WithArgs(accountStruct.ID).WillReturnError(fmt.Errorf("connection reset"))
+
+		repo := account.NewAccountRepository(db, constant.TableAccount)
+		useCase := account.NewTracedUseCase(account.NewAccountUseCase(repo, new(bcryptmocks.Bcrypt), newAuditUseCase(), newOutboxRepository(), newTransactor()))
+
+		resp := useCase.DetailAccount(context.TODO(), accountStruct.ID)
+		assert.Error(t, resp.Err())
+
+		for _, name := range []string{"AccountUseCase.DetailAccount", "AccountRepository.FindByID"} {
+			stub, ok := findSpan(spans.GetSpans(), name)
+			if assert.True(t, ok, name) {
+				assert.Equal(t, codes.Error, stub.Status.Code, name)
+				assert.Len(t, stub.Events, 1, name)
+			}
+		}
+	})
+}
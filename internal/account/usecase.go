@@ -2,7 +2,8 @@ package account
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"time"
 
 	newJWT "github.com/dgrijalva/jwt-go"
@@ -10,41 +11,93 @@ import (
 	"waizly/config/bcrypt"
 	"waizly/config/jwt"
 	"waizly/helpers/exception"
+	"waizly/helpers/logger"
 	"waizly/helpers/response"
+	"waizly/helpers/transaction"
+	"waizly/internal/audit"
+	"waizly/internal/outbox"
 	"waizly/models"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type (
 	AccountUseCase interface {
 		Register(ctx context.Context, params models.RegisterRequest) response.Response
 		Login(ctx context.Context, params models.LoginRequest) (response.Response, models.Token)
 		DetailAccount(ctx context.Context, id int64) response.Response
 		UpdateAccount(ctx context.Context, id int64, params models.Account) response.Response
-		DeleteAccount(ctx context.Context, id int64) response.Response
+		DeleteAccount(ctx context.Context, id int64, version int64) response.Response
+		Authenticate(ctx context.Context, claims jwt.JWTclaim) response.Response
+		ListAccounts(ctx context.Context, afterID int64, limit int) response.Response
+		SetDisabled(ctx context.Context, id int64, disabled bool) response.Response
+		ResetPassword(ctx context.Context, id int64, password string) response.Response
+		AssignRole(ctx context.Context, id int64, role string) response.Response
+		RevokeSessions(ctx context.Context, id int64) response.Response
 	}
 
 	accountUseCaseImpl struct {
 		repository AccountRepository
 		bcrypt     bcrypt.Bcrypt
+		audit      audit.AuditUseCase
+		outbox     outbox.OutboxRepository
+		transactor transaction.Transactor
 	}
 )
 
-func NewAccountUseCase(repo AccountRepository, bcrypt bcrypt.Bcrypt) AccountUseCase {
+func NewAccountUseCase(repo AccountRepository, bcrypt bcrypt.Bcrypt, audit audit.AuditUseCase, outbox outbox.OutboxRepository, transactor transaction.Transactor) AccountUseCase {
 	return &accountUseCaseImpl{
 		repository: repo,
 		bcrypt:     bcrypt,
+		audit:      audit,
+		outbox:     outbox,
+		transactor: transactor,
+	}
+}
+
+// emit stores a domain event for account in the outbox. It must be called
+// inside au.transactor so the event commits together with the account write.
+func (au *accountUseCaseImpl) emit(ctx context.Context, eventType string, account models.Account) error {
+	event, err := outbox.NewAccountEvent(eventType, account)
+	if err != nil {
+		return err
+	}
+
+	return au.outbox.Save(ctx, event)
+}
+
+// record writes an audit event. A failing audit write is logged but never
+// fails the request that triggered it. Inside au.transactor the event
+// commits with the change it describes, and a failed write only rolls back
+// its own savepoint; when the database dropped the savepoint, as MySQL does
+// on a deadlock, the whole transaction fails and is retried instead.
+func (au *accountUseCaseImpl) record(ctx context.Context, event models.AuditEvent) {
+	err := au.audit.Record(ctx, event)
+	if err != nil {
+		logger.Ctx(ctx).Error("record audit event", "action", event.Action, "error", err)
 	}
 }
 
 func (au *accountUseCaseImpl) Register(ctx context.Context, params models.RegisterRequest) response.Response {
-	_, err := au.repository.FindByEmail(ctx, params.Email)
+	existing, err := au.repository.FindByEmail(ctx, params.Email)
 
 	if err == nil {
+		au.record(ctx, models.AuditEvent{
+			SubjectID: existing.ID,
+			Action:    audit.ActionRegister,
+			Outcome:   audit.OutcomeFailure,
+			Detail:    "email already registered",
+		})
 		return response.Error(response.StatusConflicted, exception.ErrConflicted)
 	}
 
-	if err != exception.ErrNotFound {
-		return response.Error(response.StatusInternalServerError, exception.ErrInternalServer)
+	// Anything but a clean miss means the lookup could not be trusted, and
+	// registering anyway would rely on the unique indexes alone.
+	if !errors.Is(err, exception.ErrNotFound) {
+		return response.FromError(err)
 	}
 
 	hashedPassword, err := au.bcrypt.HashPassword(params.Password)
@@ -57,14 +110,48 @@ func (au *accountUseCaseImpl) Register(ctx context.Context, params models.Regist
 		Password:  hashedPassword,
 		Email:     params.Email,
 		CreatedAt: time.Now(),
+		Version:   1,
+	}
+
+	err = au.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		ID, err := au.repository.Create(ctx, account)
+		if err != nil {
+			return err
+		}
+
+		account.ID = ID
+
+		err = au.emit(ctx, outbox.EventAccountRegistered, account)
+		if err != nil {
+			return err
+		}
+
+		au.record(ctx, models.AuditEvent{
+			ActorID:   account.ID,
+			SubjectID: account.ID,
+			Action:    audit.ActionRegister,
+			Outcome:   audit.OutcomeSuccess,
+		})
+
+		return nil
+	})
+
+	// The lookup above only catches the common case; a concurrent
+	// registration with the same email or username is caught by the unique
+	// indexes and surfaces here.
+	if errors.Is(err, exception.ErrConflicted) {
+		au.record(ctx, models.AuditEvent{
+			Action:  audit.ActionRegister,
+			Outcome: audit.OutcomeFailure,
+			Detail:  "email or username already registered",
+		})
+		return response.Error(response.StatusConflicted, exception.ErrConflicted)
 	}
 
-	ID, err := au.repository.Create(ctx, account)
 	if err != nil {
-		return response.Error(response.StatusInternalServerError, err)
+		return response.FromError(err)
 	}
 
-	account.ID = ID
 	account.Password = ""
 
 	return response.Success(response.StatusCreated, account)
@@ -73,35 +160,55 @@ func (au *accountUseCaseImpl) Register(ctx context.Context, params models.Regist
 func (au *accountUseCaseImpl) Login(ctx context.Context, params models.LoginRequest) (response.Response, models.Token) {
 	account, err := au.repository.FindByEmail(ctx, params.Email)
 
-	if err == exception.ErrNotFound {
-		log.Println(err)
+	if errors.Is(err, exception.ErrNotFound) {
+		logger.Ctx(ctx).Warn("login for unknown account", "email", params.Email)
+		au.record(ctx, models.AuditEvent{
+			Action:  audit.ActionLogin,
+			Outcome: audit.OutcomeFailure,
+			Detail:  "unknown email",
+		})
 		return response.Error(response.StatusNotFound, exception.ErrNotFound), models.Token{}
 	}
 
 	if err != nil {
-		return response.Error(response.StatusInternalServerError, exception.ErrInternalServer), models.Token{}
+		return response.FromError(err), models.Token{}
 	}
 
 	isPasswordValid := au.bcrypt.ComparePasswordHash(params.Password, account.Password)
 
 	if !isPasswordValid {
+		au.record(ctx, models.AuditEvent{
+			SubjectID: account.ID,
+			Action:    audit.ActionLogin,
+			Outcome:   audit.OutcomeFailure,
+			Detail:    "invalid password",
+		})
 		return response.Error(response.StatusUnauthorized, err), models.Token{}
 	}
 
+	if account.DisabledAt != nil {
+		au.record(ctx, models.AuditEvent{
+			SubjectID: account.ID,
+			Action:    audit.ActionLogin,
+			Outcome:   audit.OutcomeFailure,
+			Detail:    "account disabled",
+		})
+		return response.Error(response.StatusForbiddend, exception.ErrForbidden), models.Token{}
+	}
+
 	account.Password = ""
 
 	claims := &jwt.JWTclaim{
 		ID:    account.ID,
 		Email: account.Email,
+		Role:  account.Role,
 		StandardClaims: newJWT.StandardClaims{
 			IssuedAt:  time.Now().Unix(),
 			ExpiresAt: time.Now().Add(time.Hour * 24 * 1).Unix(),
 		},
 	}
 
-	tokenAlgo := newJWT.NewWithClaims(newJWT.SigningMethodHS256, claims)
-
-	token, err := tokenAlgo.SignedString(jwt.JWT_KEY)
+	token, err := jwt.Sign(claims)
 	if err != nil {
 		return response.Error(response.StatusInternalServerError, exception.ErrInternalServer), models.Token{}
 	}
@@ -110,17 +217,24 @@ func (au *accountUseCaseImpl) Login(ctx context.Context, params models.LoginRequ
 		Token: token,
 	}
 
+	au.record(ctx, models.AuditEvent{
+		ActorID:   account.ID,
+		SubjectID: account.ID,
+		Action:    audit.ActionLogin,
+		Outcome:   audit.OutcomeSuccess,
+	})
+
 	return response.Success(response.StatusOK, account), newToken
 }
 
 func (au *accountUseCaseImpl) DetailAccount(ctx context.Context, id int64) response.Response {
 	account, err := au.repository.FindByID(ctx, id)
-	if err == exception.ErrNotFound {
+	if errors.Is(err, exception.ErrNotFound) {
 		return response.Error(response.StatusNotFound, exception.ErrParams)
 	}
 
 	if err != nil {
-		return response.Error(response.StatusInternalServerError, exception.ErrInternalServer)
+		return response.FromError(err)
 	}
 
 	account.Password = ""
@@ -128,43 +242,288 @@ func (au *accountUseCaseImpl) DetailAccount(ctx context.Context, id int64) respo
 	return response.Success(response.StatusOK, account)
 }
 
+// UpdateAccount replaces the profile of id. A non-zero params.Version is the
+// version the caller last read, and the update fails with
+// exception.ErrPreconditionFailed once the account has moved on.
 func (au *accountUseCaseImpl) UpdateAccount(ctx context.Context, id int64, params models.Account) response.Response {
+	hashedPassword, err := au.bcrypt.HashPassword(params.Password)
+	if err != nil {
+		return response.Error(response.StatusInternalServerError, exception.ErrInternalServer)
+	}
 
-	account, err := au.repository.FindByID(ctx, id)
-	if err == exception.ErrNotFound {
-		return response.Error(response.StatusNotFound, exception.ErrNotFound)
+	var updated models.Account
+
+	// The transaction may run more than once, so every run starts from a
+	// fresh read and leaves nothing behind but updated.
+	err = au.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		loaded, err := au.repository.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if params.Version != 0 && params.Version != loaded.Version {
+			return exception.ErrPreconditionFailed
+		}
+
+		account := loaded
+		account.ID = id
+		account.Username = params.Username
+		account.Password = hashedPassword
+		account.Email = params.Email
+		account.UpdateAt = time.Now()
+
+		err = au.repository.Update(ctx, id, account)
+		if err != nil {
+			return err
+		}
+
+		account.Version = loaded.Version + 1
+
+		err = au.emit(ctx, outbox.EventAccountUpdated, account)
+		if err != nil {
+			return err
+		}
+
+		au.record(ctx, models.AuditEvent{
+			ActorID:   id,
+			SubjectID: id,
+			Action:    audit.ActionUpdate,
+			Outcome:   audit.OutcomeSuccess,
+		})
+
+		if loaded.Email != account.Email {
+			au.record(ctx, models.AuditEvent{
+				ActorID:   id,
+				SubjectID: id,
+				Action:    audit.ActionEmailChange,
+				Outcome:   audit.OutcomeSuccess,
+				Detail:    fmt.Sprintf("%s -> %s", logger.MaskEmail(loaded.Email), logger.MaskEmail(account.Email)),
+			})
+		}
+
+		updated = account
+
+		return nil
+	})
+
+	if errors.Is(err, exception.ErrNotFound) {
+		return response.FromError(err)
 	}
 
 	if err != nil {
-		return response.Error(response.StatusInternalServerError, exception.ErrInternalServer)
+		au.record(ctx, models.AuditEvent{
+			ActorID:   id,
+			SubjectID: id,
+			Action:    audit.ActionUpdate,
+			Outcome:   audit.OutcomeFailure,
+		})
+
+		// Someone else updated the account before or between our read and
+		// our write.
+		if errors.Is(err, exception.ErrPreconditionFailed) || params.Version != 0 && errors.Is(err, exception.ErrVersionMismatch) {
+			return response.Error(response.StatusPreconditionFailed, exception.ErrPreconditionFailed)
+		}
+
+		return response.FromError(err)
 	}
 
-	account.ID = params.ID
-	account.Username = params.Username
-	account.Password = params.Password
-	account.Email = params.Password
-	account.UpdateAt = time.Now()
+	updated.Password = ""
+
+	return response.Success(response.StatusOK, updated)
+}
+
+// DeleteAccount removes id. A non-zero version must be the current one, like
+// for UpdateAccount.
+func (au *accountUseCaseImpl) DeleteAccount(ctx context.Context, id int64, version int64) response.Response {
+
+	err := au.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := au.repository.Delete(ctx, id, version)
+		if err != nil {
+			return err
+		}
+
+		err = au.emit(ctx, outbox.EventAccountDeleted, models.Account{ID: id})
+		if err != nil {
+			return err
+		}
+
+		au.record(ctx, models.AuditEvent{
+			ActorID:   id,
+			SubjectID: id,
+			Action:    audit.ActionDelete,
+			Outcome:   audit.OutcomeSuccess,
+		})
+
+		return nil
+	})
+
+	if errors.Is(err, exception.ErrNotFound) {
+		return response.FromError(err)
+	}
 
-	err = au.repository.Update(ctx, account.ID, account)
 	if err != nil {
-		return response.Error(response.StatusInternalServerError, exception.ErrInternalServer)
+		au.record(ctx, models.AuditEvent{
+			ActorID:   id,
+			SubjectID: id,
+			Action:    audit.ActionDelete,
+			Outcome:   audit.OutcomeFailure,
+		})
+
+		if errors.Is(err, exception.ErrVersionMismatch) {
+			return response.Error(response.StatusPreconditionFailed, exception.ErrPreconditionFailed)
+		}
+
+		return response.FromError(err)
+	}
+
+	msg := "Success Delete Data"
+
+	return response.Success(response.StatusOK, msg)
+}
+
+// Authenticate checks that the account behind a verified token may still act:
+// it must exist, not be disabled, and the token must have been issued after
+// the last session revocation.
+func (au *accountUseCaseImpl) Authenticate(ctx context.Context, claims jwt.JWTclaim) response.Response {
+	account, err := au.repository.FindByID(ctx, claims.ID)
+	if errors.Is(err, exception.ErrNotFound) {
+		return response.Error(response.StatusUnauthorized, exception.ErrUnauthorized)
+	}
+
+	// A store that cannot answer must not log everyone out.
+	if err != nil {
+		return response.FromError(err)
+	}
+
+	if account.DisabledAt != nil {
+		return response.Error(response.StatusForbiddend, exception.ErrForbidden)
+	}
+
+	if account.SessionsRevokedAt != nil && claims.IssuedAt <= account.SessionsRevokedAt.Unix() {
+		return response.Error(response.StatusUnauthorized, exception.ErrUnauthorized)
 	}
 
+	account.Password = ""
+
 	return response.Success(response.StatusOK, account)
 }
 
-func (au *accountUseCaseImpl) DeleteAccount(ctx context.Context, id int64) response.Response {
+func (au *accountUseCaseImpl) ListAccounts(ctx context.Context, afterID int64, limit int) response.Response {
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	accounts, err := au.repository.List(ctx, afterID, limit)
+	if err != nil {
+		return response.FromError(err)
+	}
 
-	err := au.repository.Delete(ctx, id)
-	if err == exception.ErrNotFound {
-		return response.Error(response.StatusNotFound, exception.ErrNotFound)
+	return response.Success(response.StatusOK, accounts)
+}
+
+func (au *accountUseCaseImpl) SetDisabled(ctx context.Context, id int64, disabled bool) response.Response {
+	action := audit.ActionEnable
+	if disabled {
+		action = audit.ActionDisable
 	}
 
+	return au.modify(ctx, id, action, "", func(account *models.Account) {
+		account.DisabledAt = nil
+		if disabled {
+			now := time.Now()
+			account.DisabledAt = &now
+		}
+	})
+}
+
+// ResetPassword replaces the password hash and revokes every session issued
+// with the old one.
+func (au *accountUseCaseImpl) ResetPassword(ctx context.Context, id int64, password string) response.Response {
+	hashedPassword, err := au.bcrypt.HashPassword(password)
 	if err != nil {
 		return response.Error(response.StatusInternalServerError, exception.ErrInternalServer)
 	}
 
-	msg := "Success Delete Data"
+	return au.modify(ctx, id, audit.ActionPassword, "", func(account *models.Account) {
+		now := time.Now()
+		account.Password = hashedPassword
+		account.SessionsRevokedAt = &now
+	})
+}
 
-	return response.Success(response.StatusOK, msg)
+func (au *accountUseCaseImpl) AssignRole(ctx context.Context, id int64, role string) response.Response {
+	if role != RoleUser && role != RoleAdmin {
+		return response.Error(response.StatusBadRequest, exception.ErrBadRequest)
+	}
+
+	return au.modify(ctx, id, audit.ActionRoleChange, role, func(account *models.Account) {
+		account.Role = role
+	})
+}
+
+func (au *accountUseCaseImpl) RevokeSessions(ctx context.Context, id int64) response.Response {
+	return au.modify(ctx, id, audit.ActionRevoke, "", func(account *models.Account) {
+		now := time.Now()
+		account.SessionsRevokedAt = &now
+	})
+}
+
+// modify loads an account, applies change and stores it together with an
+// AccountUpdated event. The outcome is recorded in the audit log under action.
+// A retried transaction loads the account again and reapplies change.
+func (au *accountUseCaseImpl) modify(ctx context.Context, id int64, action, detail string, change func(account *models.Account)) response.Response {
+	var updated models.Account
+
+	err := au.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		loaded, err := au.repository.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		account := loaded
+		change(&account)
+		account.UpdateAt = time.Now()
+
+		err = au.repository.Update(ctx, id, account)
+		if err != nil {
+			return err
+		}
+
+		account.Version = loaded.Version + 1
+
+		err = au.emit(ctx, outbox.EventAccountUpdated, account)
+		if err != nil {
+			return err
+		}
+
+		au.record(ctx, models.AuditEvent{
+			SubjectID: id,
+			Action:    action,
+			Outcome:   audit.OutcomeSuccess,
+			Detail:    detail,
+		})
+
+		updated = account
+
+		return nil
+	})
+
+	if errors.Is(err, exception.ErrNotFound) {
+		return response.FromError(err)
+	}
+
+	if err != nil {
+		au.record(ctx, models.AuditEvent{
+			SubjectID: id,
+			Action:    action,
+			Outcome:   audit.OutcomeFailure,
+			Detail:    detail,
+		})
+
+		return response.FromError(err)
+	}
+
+	updated.Password = ""
+
+	return response.Success(response.StatusOK, updated)
 }
@@ -2,6 +2,7 @@ package account_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -9,12 +10,80 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	bcryptmocks "waizly/config/bcrypt/mocks"
+	"waizly/config/jwt"
 	"waizly/helpers/exception"
+	"waizly/helpers/response"
+	transactionmocks "waizly/helpers/transaction/mocks"
 	"waizly/internal/account"
 	"waizly/internal/account/mocks"
+	"waizly/internal/audit"
+	auditmocks "waizly/internal/audit/mocks"
+	"waizly/internal/outbox"
+	outboxmocks "waizly/internal/outbox/mocks"
 	"waizly/models"
 )
 
+func newAuditUseCase() *auditmocks.AuditUseCase {
+	auditUseCase := new(auditmocks.AuditUseCase)
+	auditUseCase.On("Record", mock.Anything, mock.AnythingOfType("models.AuditEvent")).Return(nil).Maybe()
+
+	return auditUseCase
+}
+
+func newOutboxRepository() *outboxmocks.OutboxRepository {
+	outboxRepository := new(outboxmocks.OutboxRepository)
+	outboxRepository.On("Save", mock.Anything, mock.AnythingOfType("models.Event")).Return(nil).Maybe()
+
+	return outboxRepository
+}
+
+// newHasher hashes every password to "hashed password".
+func newHasher() *bcryptmocks.Bcrypt {
+	bcrypt := new(bcryptmocks.Bcrypt)
+	bcrypt.On("HashPassword", mock.AnythingOfType("string")).Return("hashed password", nil)
+
+	return bcrypt
+}
+
+func newTransactor() *transactionmocks.Transactor {
+	transactor := new(transactionmocks.Transactor)
+	transactor.On("WithinTransaction", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		return fn(ctx)
+	}).Maybe()
+
+	return transactor
+}
+
+// newRetryingTransactor runs fn a second time when the first run fails, as
+// transaction.WithRetry does after a deadlock.
+func newRetryingTransactor() *transactionmocks.Transactor {
+	transactor := new(transactionmocks.Transactor)
+	transactor.On("WithinTransaction", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		if err := fn(ctx); err == nil {
+			return nil
+		}
+
+		return fn(ctx)
+	})
+
+	return transactor
+}
+
+// newDeadlockingOutbox fails the first Save, after the account was written.
+func newDeadlockingOutbox() *outboxmocks.OutboxRepository {
+	outboxRepository := new(outboxmocks.OutboxRepository)
+	outboxRepository.On("Save", mock.Anything, mock.AnythingOfType("models.Event")).Return(errors.New("deadlock")).Once()
+	outboxRepository.On("Save", mock.Anything, mock.AnythingOfType("models.Event")).Return(nil)
+
+	return outboxRepository
+}
+
+func auditEvent(action, outcome string) interface{} {
+	return mock.MatchedBy(func(event models.AuditEvent) bool {
+		return event.Action == action && event.Outcome == outcome
+	})
+}
+
 func TestRegister(t *testing.T) {
 	t.Run("Success Register", func(t *testing.T) {
 		bcrypt := new(bcryptmocks.Bcrypt)
@@ -27,6 +96,9 @@ func TestRegister(t *testing.T) {
 		registerUseCase := account.NewAccountUseCase(
 			registerRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -56,6 +128,9 @@ func TestRegister(t *testing.T) {
 		registerUseCase := account.NewAccountUseCase(
 			registerRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -85,6 +160,9 @@ func TestRegister(t *testing.T) {
 		accountUseCase := account.NewAccountUseCase(
 			registerRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -111,6 +189,9 @@ func TestRegister(t *testing.T) {
 		accountUseCase := account.NewAccountUseCase(
 			registerRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -129,6 +210,65 @@ func TestRegister(t *testing.T) {
 
 	})
 
+	t.Run("Conflict On Create", func(t *testing.T) {
+		bcrypt := new(bcryptmocks.Bcrypt)
+		registerRepository := new(mocks.AccountRepository)
+
+		registerRepository.On("FindByEmail", mock.Anything, mock.AnythingOfType("string")).Return(models.Account{}, exception.ErrNotFound)
+		registerRepository.On("Create", mock.Anything, mock.AnythingOfType("models.Account")).Return(int64(0), exception.ErrConflicted)
+		bcrypt.On("HashPassword", mock.AnythingOfType("string")).Return("hashed", nil)
+
+		accountUseCase := account.NewAccountUseCase(
+			registerRepository,
+			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
+		)
+
+		params := models.RegisterRequest{
+			Username: "username-test",
+			Password: "password-test",
+			Email:    "email@test.com",
+		}
+
+		resp := accountUseCase.Register(context.TODO(), params)
+
+		assert.Equal(t, exception.ErrConflicted, resp.Err())
+		assert.Equal(t, response.StatusConflicted, response.StatusOf(resp))
+		registerRepository.AssertExpectations(t)
+		bcrypt.AssertExpectations(t)
+	})
+
+	t.Run("Lookup Unavailable", func(t *testing.T) {
+		bcrypt := new(bcryptmocks.Bcrypt)
+		registerRepository := new(mocks.AccountRepository)
+
+		// Create is not expected: a failed lookup must not be taken for a miss.
+		registerRepository.On("FindByEmail", mock.Anything, mock.AnythingOfType("string")).Return(models.Account{}, exception.Wrap("AccountRepository.FindByEmail", exception.ErrUnavailable, errors.New("invalid connection")))
+
+		accountUseCase := account.NewAccountUseCase(
+			registerRepository,
+			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
+		)
+
+		params := models.RegisterRequest{
+			Username: "username-test",
+			Password: "password-test",
+			Email:    "email@test.com",
+		}
+
+		resp := accountUseCase.Register(context.TODO(), params)
+
+		assert.Equal(t, exception.ErrUnavailable, resp.Err())
+		assert.Equal(t, response.StatusServiceUnavailable, response.StatusOf(resp))
+		registerRepository.AssertExpectations(t)
+		bcrypt.AssertExpectations(t)
+	})
+
 	t.Run("Error Query To DB", func(t *testing.T) {
 		bcrypt := new(bcryptmocks.Bcrypt)
 		registerRepository := new(mocks.AccountRepository)
@@ -138,6 +278,9 @@ func TestRegister(t *testing.T) {
 		accountUseCase := account.NewAccountUseCase(
 			registerRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -166,6 +309,9 @@ func TestLogin(t *testing.T) {
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -191,6 +337,9 @@ func TestLogin(t *testing.T) {
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -224,6 +373,9 @@ func TestLogin(t *testing.T) {
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -250,6 +402,9 @@ func TestLogin(t *testing.T) {
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -286,6 +441,9 @@ func TestLogin(t *testing.T) {
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -317,6 +475,9 @@ func TestDetailAccount(t *testing.T) {
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -342,6 +503,9 @@ func TestDetailAccount(t *testing.T) {
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -367,6 +531,9 @@ func TestDetailAccount(t *testing.T) {
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -388,13 +555,16 @@ func TestUpdateAccount(t *testing.T) {
 
 	t.Run("Account Not Found", func(t *testing.T) {
 		loginRepository := new(mocks.AccountRepository)
-		bcrypt := new(bcryptmocks.Bcrypt)
+		bcrypt := newHasher()
 
 		loginRepository.On("FindByID", mock.Anything, mock.AnythingOfType("int64")).Return(models.Account{}, exception.ErrNotFound)
 
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -416,13 +586,16 @@ func TestUpdateAccount(t *testing.T) {
 
 	t.Run("Query error to DB", func(t *testing.T) {
 		loginRepository := new(mocks.AccountRepository)
-		bcrypt := new(bcryptmocks.Bcrypt)
+		bcrypt := newHasher()
 
 		loginRepository.On("FindByID", mock.Anything, mock.AnythingOfType("int64")).Return(models.Account{}, exception.ErrInternalServer)
 
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -442,16 +615,103 @@ func TestUpdateAccount(t *testing.T) {
 		bcrypt.AssertExpectations(t)
 	})
 
-	t.Run("Update Success", func(t *testing.T) {
+	t.Run("Conflict", func(t *testing.T) {
 		loginRepository := new(mocks.AccountRepository)
-		bcrypt := new(bcryptmocks.Bcrypt)
+		bcrypt := newHasher()
 
 		loginRepository.On("FindByID", mock.Anything, mock.AnythingOfType("int64")).Return(models.Account{}, nil)
-		loginRepository.On("Update", mock.Anything, mock.AnythingOfType("int64"), mock.AnythingOfType("models.Account")).Return(nil)
+		loginRepository.On("Update", mock.Anything, mock.AnythingOfType("int64"), mock.AnythingOfType("models.Account")).Return(exception.ErrConflicted)
 
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
+		)
+
+		params := models.Account{
+			ID:       1,
+			Username: "username-test",
+			Password: "password-test",
+			Email:    "taken@test.com",
+		}
+
+		resp := accountUseCase.UpdateAccount(context.TODO(), params.ID, params)
+
+		assert.Equal(t, exception.ErrConflicted, resp.Err())
+		assert.Equal(t, response.StatusConflicted, response.StatusOf(resp))
+		loginRepository.AssertExpectations(t)
+	})
+
+	t.Run("Stale Version", func(t *testing.T) {
+		loginRepository := new(mocks.AccountRepository)
+
+		loginRepository.On("FindByID", mock.Anything, int64(1)).Return(models.Account{ID: 1, Version: 3}, nil)
+
+		accountUseCase := account.NewAccountUseCase(
+			loginRepository,
+			newHasher(),
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
+		)
+
+		resp := accountUseCase.UpdateAccount(context.TODO(), 1, models.Account{Username: "username-test", Version: 2})
+
+		assert.Equal(t, exception.ErrPreconditionFailed, resp.Err())
+		assert.Equal(t, response.StatusPreconditionFailed, response.StatusOf(resp))
+		loginRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Changed Concurrently", func(t *testing.T) {
+		for _, tt := range []struct {
+			name    string
+			version int64
+			status  string
+			err     error
+		}{
+			{"With If-Match", 2, response.StatusPreconditionFailed, exception.ErrPreconditionFailed},
+			{"Without If-Match", 0, response.StatusConflicted, exception.ErrConflicted},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				loginRepository := new(mocks.AccountRepository)
+
+				stale := exception.Wrap("AccountRepository.Update", exception.ErrConflicted, exception.ErrVersionMismatch)
+				loginRepository.On("FindByID", mock.Anything, int64(1)).Return(models.Account{ID: 1, Version: 2}, nil)
+				loginRepository.On("Update", mock.Anything, int64(1), mock.AnythingOfType("models.Account")).Return(stale)
+
+				accountUseCase := account.NewAccountUseCase(
+					loginRepository,
+					newHasher(),
+					newAuditUseCase(),
+					newOutboxRepository(),
+					newTransactor(),
+				)
+
+				resp := accountUseCase.UpdateAccount(context.TODO(), 1, models.Account{Username: "username-test", Version: tt.version})
+
+				assert.Equal(t, tt.err, resp.Err())
+				assert.Equal(t, tt.status, response.StatusOf(resp))
+			})
+		}
+	})
+
+	t.Run("Update Success", func(t *testing.T) {
+		loginRepository := new(mocks.AccountRepository)
+		bcrypt := newHasher()
+
+		loginRepository.On("FindByID", mock.Anything, mock.AnythingOfType("int64")).Return(models.Account{Version: 2}, nil)
+		loginRepository.On("Update", mock.Anything, mock.AnythingOfType("int64"), mock.MatchedBy(func(account models.Account) bool {
+			return account.Version == 2 && account.Password == "hashed password"
+		})).Return(nil)
+
+		accountUseCase := account.NewAccountUseCase(
+			loginRepository,
+			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -466,10 +726,55 @@ func TestUpdateAccount(t *testing.T) {
 		resp := accountUseCase.UpdateAccount(ctx, params.ID, params)
 
 		assert.NoError(t, resp.Err())
+		assert.Equal(t, int64(3), resp.(*response.ResponseImpl).Data.(models.Account).Version)
+		assert.Empty(t, resp.(*response.ResponseImpl).Data.(models.Account).Password, "the hash is not sent back")
 
 		loginRepository.AssertExpectations(t)
 		bcrypt.AssertExpectations(t)
 	})
+
+	t.Run("Hash Error", func(t *testing.T) {
+		loginRepository := new(mocks.AccountRepository)
+		bcrypt := new(bcryptmocks.Bcrypt)
+
+		bcrypt.On("HashPassword", "password-test").Return("", exception.ErrInternalServer)
+
+		accountUseCase := account.NewAccountUseCase(
+			loginRepository,
+			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
+		)
+
+		resp := accountUseCase.UpdateAccount(context.TODO(), 1, models.Account{Username: "username-test", Password: "password-test"})
+
+		assert.Equal(t, exception.ErrInternalServer, resp.Err())
+		loginRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Retried Transaction", func(t *testing.T) {
+		loginRepository := new(mocks.AccountRepository)
+
+		loginRepository.On("FindByID", mock.Anything, int64(1)).Return(models.Account{ID: 1, Version: 2}, nil).Twice()
+		loginRepository.On("Update", mock.Anything, int64(1), mock.MatchedBy(func(account models.Account) bool {
+			return account.Version == 2
+		})).Return(nil).Twice()
+
+		accountUseCase := account.NewAccountUseCase(
+			loginRepository,
+			newHasher(),
+			newAuditUseCase(),
+			newDeadlockingOutbox(),
+			newRetryingTransactor(),
+		)
+
+		resp := accountUseCase.UpdateAccount(context.TODO(), 1, models.Account{Username: "username-test", Version: 2})
+
+		assert.NoError(t, resp.Err())
+		assert.Equal(t, int64(3), resp.(*response.ResponseImpl).Data.(models.Account).Version)
+		loginRepository.AssertExpectations(t)
+	})
 }
 
 func TestDeleteAcco(t *testing.T) {
@@ -477,11 +782,14 @@ func TestDeleteAcco(t *testing.T) {
 		loginRepository := new(mocks.AccountRepository)
 		bcrypt := new(bcryptmocks.Bcrypt)
 
-		loginRepository.On("Delete", mock.Anything, mock.AnythingOfType("int64")).Return(exception.ErrNotFound)
+		loginRepository.On("Delete", mock.Anything, mock.AnythingOfType("int64"), int64(0)).Return(exception.ErrNotFound)
 
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -490,7 +798,7 @@ func TestDeleteAcco(t *testing.T) {
 			ID: 1,
 		}
 
-		resp := accountUseCase.DeleteAccount(ctx, params.ID)
+		resp := accountUseCase.DeleteAccount(ctx, params.ID, 0)
 
 		assert.Error(t, resp.Err())
 
@@ -502,11 +810,14 @@ func TestDeleteAcco(t *testing.T) {
 		loginRepository := new(mocks.AccountRepository)
 		bcrypt := new(bcryptmocks.Bcrypt)
 
-		loginRepository.On("Delete", mock.Anything, mock.AnythingOfType("int64")).Return(exception.ErrInternalServer)
+		loginRepository.On("Delete", mock.Anything, mock.AnythingOfType("int64"), int64(0)).Return(exception.ErrInternalServer)
 
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -515,7 +826,7 @@ func TestDeleteAcco(t *testing.T) {
 			ID: 1,
 		}
 
-		resp := accountUseCase.DeleteAccount(ctx, params.ID)
+		resp := accountUseCase.DeleteAccount(ctx, params.ID, 0)
 
 		assert.Error(t, resp.Err())
 
@@ -527,11 +838,14 @@ func TestDeleteAcco(t *testing.T) {
 		loginRepository := new(mocks.AccountRepository)
 		bcrypt := new(bcryptmocks.Bcrypt)
 
-		loginRepository.On("Delete", mock.Anything, mock.AnythingOfType("int64")).Return(nil)
+		loginRepository.On("Delete", mock.Anything, mock.AnythingOfType("int64"), int64(0)).Return(nil)
 
 		accountUseCase := account.NewAccountUseCase(
 			loginRepository,
 			bcrypt,
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
 		)
 
 		ctx := context.TODO()
@@ -540,11 +854,388 @@ func TestDeleteAcco(t *testing.T) {
 			ID: 1,
 		}
 
-		resp := accountUseCase.DeleteAccount(ctx, params.ID)
+		resp := accountUseCase.DeleteAccount(ctx, params.ID, 0)
 
 		assert.NoError(t, resp.Err())
 
 		loginRepository.AssertExpectations(t)
 		bcrypt.AssertExpectations(t)
 	})
+	t.Run("Stale Version", func(t *testing.T) {
+		loginRepository := new(mocks.AccountRepository)
+
+		stale := exception.Wrap("AccountRepository.Delete", exception.ErrConflicted, exception.ErrVersionMismatch)
+		loginRepository.On("Delete", mock.Anything, int64(1), int64(2)).Return(stale)
+
+		accountUseCase := account.NewAccountUseCase(
+			loginRepository,
+			new(bcryptmocks.Bcrypt),
+			newAuditUseCase(),
+			newOutboxRepository(),
+			newTransactor(),
+		)
+
+		resp := accountUseCase.DeleteAccount(context.TODO(), 1, 2)
+
+		assert.Equal(t, exception.ErrPreconditionFailed, resp.Err())
+		assert.Equal(t, response.StatusPreconditionFailed, response.StatusOf(resp))
+		loginRepository.AssertExpectations(t)
+	})
+}
+
+func TestAuditTrail(t *testing.T) {
+	t.Run("Register records success", func(t *testing.T) {
+		bcrypt := new(bcryptmocks.Bcrypt)
+		accountRepository := new(mocks.AccountRepository)
+		auditUseCase := new(auditmocks.AuditUseCase)
+
+		accountRepository.On("FindByEmail", mock.Anything, mock.AnythingOfType("string")).Return(models.Account{}, exception.ErrNotFound)
+		accountRepository.On("Create", mock.Anything, mock.AnythingOfType("models.Account")).Return(int64(7), nil)
+		bcrypt.On("HashPassword", mock.AnythingOfType("string")).Return("hashed password", nil)
+		auditUseCase.On("Record", mock.Anything, mock.MatchedBy(func(event models.AuditEvent) bool {
+			return event.Action == audit.ActionRegister && event.Outcome == audit.OutcomeSuccess && event.SubjectID == 7
+		})).Return(nil)
+
+		accountUseCase := account.NewAccountUseCase(
+			accountRepository,
+			bcrypt,
+			auditUseCase,
+			newOutboxRepository(),
+			newTransactor(),
+		)
+
+		resp := accountUseCase.Register(context.TODO(), models.RegisterRequest{
+			Username: "username-test",
+			Password: "password-test",
+			Email:    "email@test.com",
+		})
+
+		assert.NoError(t, resp.Err())
+		auditUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Login records unknown email", func(t *testing.T) {
+		accountRepository := new(mocks.AccountRepository)
+		auditUseCase := new(auditmocks.AuditUseCase)
+
+		accountRepository.On("FindByEmail", mock.Anything, mock.AnythingOfType("string")).Return(models.Account{}, exception.ErrNotFound)
+		auditUseCase.On("Record", mock.Anything, mock.MatchedBy(func(event models.AuditEvent) bool {
+			return event.Action == audit.ActionLogin && event.Outcome == audit.OutcomeFailure && event.Detail == "unknown email"
+		})).Return(nil)
+
+		accountUseCase := account.NewAccountUseCase(
+			accountRepository,
+			new(bcryptmocks.Bcrypt),
+			auditUseCase,
+			newOutboxRepository(),
+			newTransactor(),
+		)
+
+		accountUseCase.Login(context.TODO(), models.LoginRequest{Email: "email@test.com"})
+
+		auditUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Login records invalid password", func(t *testing.T) {
+		bcrypt := new(bcryptmocks.Bcrypt)
+		accountRepository := new(mocks.AccountRepository)
+		auditUseCase := new(auditmocks.AuditUseCase)
+
+		accountRepository.On("FindByEmail", mock.Anything, mock.AnythingOfType("string")).Return(models.Account{ID: 3, Password: "hashed"}, nil)
+		bcrypt.On("ComparePasswordHash", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(false)
+		auditUseCase.On("Record", mock.Anything, auditEvent(audit.ActionLogin, audit.OutcomeFailure)).Return(nil)
+
+		accountUseCase := account.NewAccountUseCase(
+			accountRepository,
+			bcrypt,
+			auditUseCase,
+			newOutboxRepository(),
+			newTransactor(),
+		)
+
+		accountUseCase.Login(context.TODO(), models.LoginRequest{Email: "email@test.com"})
+
+		auditUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Update records email change", func(t *testing.T) {
+		bcrypt := newHasher()
+		accountRepository := new(mocks.AccountRepository)
+		auditUseCase := new(auditmocks.AuditUseCase)
+
+		accountRepository.On("FindByID", mock.Anything, mock.AnythingOfType("int64")).Return(models.Account{ID: 1, Email: "old@test.com"}, nil)
+		accountRepository.On("Update", mock.Anything, int64(1), mock.AnythingOfType("models.Account")).Return(nil)
+		auditUseCase.On("Record", mock.Anything, auditEvent(audit.ActionUpdate, audit.OutcomeSuccess)).Return(nil)
+		auditUseCase.On("Record", mock.Anything, mock.MatchedBy(func(event models.AuditEvent) bool {
+			return event.Action == audit.ActionEmailChange && event.Detail == "o***@test.com -> n***@test.com"
+		})).Return(nil)
+
+		accountUseCase := account.NewAccountUseCase(
+			accountRepository,
+			bcrypt,
+			auditUseCase,
+			newOutboxRepository(),
+			newTransactor(),
+		)
+
+		resp := accountUseCase.UpdateAccount(context.TODO(), 1, models.Account{
+			Username: "username-test",
+			Password: "password-test",
+			Email:    "new@test.com",
+		})
+
+		assert.NoError(t, resp.Err())
+		auditUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Audit failure does not fail delete", func(t *testing.T) {
+		bcrypt := new(bcryptmocks.Bcrypt)
+		accountRepository := new(mocks.AccountRepository)
+		auditUseCase := new(auditmocks.AuditUseCase)
+
+		accountRepository.On("Delete", mock.Anything, mock.AnythingOfType("int64"), int64(0)).Return(nil)
+		auditUseCase.On("Record", mock.Anything, auditEvent(audit.ActionDelete, audit.OutcomeSuccess)).Return(exception.ErrInternalServer)
+
+		accountUseCase := account.NewAccountUseCase(
+			accountRepository,
+			bcrypt,
+			auditUseCase,
+			newOutboxRepository(),
+			newTransactor(),
+		)
+
+		resp := accountUseCase.DeleteAccount(context.TODO(), 1, 0)
+
+		assert.NoError(t, resp.Err())
+		auditUseCase.AssertExpectations(t)
+	})
+}
+
+func TestDomainEvents(t *testing.T) {
+	t.Run("Register emits AccountRegistered", func(t *testing.T) {
+		bcrypt := new(bcryptmocks.Bcrypt)
+		accountRepository := new(mocks.AccountRepository)
+		outboxRepository := new(outboxmocks.OutboxRepository)
+		transactor := new(transactionmocks.Transactor)
+
+		accountRepository.On("FindByEmail", mock.Anything, mock.AnythingOfType("string")).Return(models.Account{}, exception.ErrNotFound)
+		accountRepository.On("Create", mock.Anything, mock.AnythingOfType("models.Account")).Return(int64(9), nil)
+		bcrypt.On("HashPassword", mock.AnythingOfType("string")).Return("hashed password", nil)
+		outboxRepository.On("Save", mock.Anything, mock.MatchedBy(func(event models.Event) bool {
+			return event.Type == outbox.EventAccountRegistered && event.AggregateID == 9
+		})).Return(nil)
+		transactor.On("WithinTransaction", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+			return fn(ctx)
+		}).Once()
+
+		accountUseCase := account.NewAccountUseCase(
+			accountRepository,
+			bcrypt,
+			newAuditUseCase(),
+			outboxRepository,
+			transactor,
+		)
+
+		resp := accountUseCase.Register(context.TODO(), models.RegisterRequest{
+			Username: "username-test",
+			Password: "password-test",
+			Email:    "email@test.com",
+		})
+
+		assert.NoError(t, resp.Err())
+		outboxRepository.AssertExpectations(t)
+		transactor.AssertExpectations(t)
+	})
+
+	t.Run("Outbox failure fails update", func(t *testing.T) {
+		bcrypt := newHasher()
+		accountRepository := new(mocks.AccountRepository)
+		outboxRepository := new(outboxmocks.OutboxRepository)
+
+		accountRepository.On("FindByID", mock.Anything, mock.AnythingOfType("int64")).Return(models.Account{ID: 1}, nil)
+		accountRepository.On("Update", mock.Anything, int64(1), mock.AnythingOfType("models.Account")).Return(nil)
+		outboxRepository.On("Save", mock.Anything, mock.MatchedBy(func(event models.Event) bool {
+			return event.Type == outbox.EventAccountUpdated
+		})).Return(exception.ErrInternalServer)
+
+		accountUseCase := account.NewAccountUseCase(
+			accountRepository,
+			bcrypt,
+			newAuditUseCase(),
+			outboxRepository,
+			newTransactor(),
+		)
+
+		resp := accountUseCase.UpdateAccount(context.TODO(), 1, models.Account{Email: "email@test.com"})
+
+		assert.Error(t, resp.Err())
+		outboxRepository.AssertExpectations(t)
+	})
+
+	t.Run("Delete emits AccountDeleted", func(t *testing.T) {
+		bcrypt := new(bcryptmocks.Bcrypt)
+		accountRepository := new(mocks.AccountRepository)
+		outboxRepository := new(outboxmocks.OutboxRepository)
+
+		accountRepository.On("Delete", mock.Anything, int64(4), int64(0)).Return(nil)
+		outboxRepository.On("Save", mock.Anything, mock.MatchedBy(func(event models.Event) bool {
+			return event.Type == outbox.EventAccountDeleted && event.AggregateID == 4
+		})).Return(nil)
+
+		accountUseCase := account.NewAccountUseCase(
+			accountRepository,
+			bcrypt,
+			newAuditUseCase(),
+			outboxRepository,
+			newTransactor(),
+		)
+
+		resp := accountUseCase.DeleteAccount(context.TODO(), 4, 0)
+
+		assert.NoError(t, resp.Err())
+		outboxRepository.AssertExpectations(t)
+	})
+}
+
+func TestAdministration(t *testing.T) {
+	t.Run("Disable Account", func(t *testing.T) {
+		repository := new(mocks.AccountRepository)
+		repository.On("FindByID", mock.Anything, int64(1)).Return(models.Account{ID: 1, Password: "hash"}, nil)
+		repository.On("Update", mock.Anything, int64(1), mock.MatchedBy(func(account models.Account) bool {
+			return account.DisabledAt != nil && account.Password == "hash"
+		})).Return(nil)
+
+		auditUseCase := new(auditmocks.AuditUseCase)
+		auditUseCase.On("Record", mock.Anything, auditEvent(audit.ActionDisable, audit.OutcomeSuccess)).Return(nil)
+
+		useCase := account.NewAccountUseCase(repository, new(bcryptmocks.Bcrypt), auditUseCase, newOutboxRepository(), newTransactor())
+
+		resp := useCase.SetDisabled(context.TODO(), 1, true)
+
+		assert.NoError(t, resp.Err())
+		repository.AssertExpectations(t)
+		auditUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Enable Account", func(t *testing.T) {
+		disabledAt := time.Now()
+
+		repository := new(mocks.AccountRepository)
+		repository.On("FindByID", mock.Anything, int64(1)).Return(models.Account{ID: 1, DisabledAt: &disabledAt}, nil)
+		repository.On("Update", mock.Anything, int64(1), mock.MatchedBy(func(account models.Account) bool {
+			return account.DisabledAt == nil
+		})).Return(nil)
+
+		useCase := account.NewAccountUseCase(repository, new(bcryptmocks.Bcrypt), newAuditUseCase(), newOutboxRepository(), newTransactor())
+
+		resp := useCase.SetDisabled(context.TODO(), 1, false)
+
+		assert.NoError(t, resp.Err())
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("Reset Password Revokes Sessions", func(t *testing.T) {
+		bcrypt := new(bcryptmocks.Bcrypt)
+		bcrypt.On("HashPassword", "new-password").Return("new-hash", nil)
+
+		repository := new(mocks.AccountRepository)
+		repository.On("FindByID", mock.Anything, int64(1)).Return(models.Account{ID: 1, Password: "old-hash"}, nil)
+		repository.On("Update", mock.Anything, int64(1), mock.MatchedBy(func(account models.Account) bool {
+			return account.Password == "new-hash" && account.SessionsRevokedAt != nil
+		})).Return(nil)
+
+		useCase := account.NewAccountUseCase(repository, bcrypt, newAuditUseCase(), newOutboxRepository(), newTransactor())
+
+		resp := useCase.ResetPassword(context.TODO(), 1, "new-password")
+
+		assert.NoError(t, resp.Err())
+		assert.Empty(t, resp.(*response.ResponseImpl).Data.(models.Account).Password)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("Retried Transaction", func(t *testing.T) {
+		repository := new(mocks.AccountRepository)
+		repository.On("FindByID", mock.Anything, int64(1)).Return(models.Account{ID: 1, Version: 4}, nil).Twice()
+		repository.On("Update", mock.Anything, int64(1), mock.MatchedBy(func(stored models.Account) bool {
+			return stored.Version == 4 && stored.Role == account.RoleAdmin
+		})).Return(nil).Twice()
+
+		useCase := account.NewAccountUseCase(repository, new(bcryptmocks.Bcrypt), newAuditUseCase(), newDeadlockingOutbox(), newRetryingTransactor())
+
+		resp := useCase.AssignRole(context.TODO(), 1, account.RoleAdmin)
+
+		assert.NoError(t, resp.Err())
+		assert.Equal(t, int64(5), resp.(*response.ResponseImpl).Data.(models.Account).Version)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("Assign Unknown Role", func(t *testing.T) {
+		repository := new(mocks.AccountRepository)
+
+		useCase := account.NewAccountUseCase(repository, new(bcryptmocks.Bcrypt), newAuditUseCase(), newOutboxRepository(), newTransactor())
+
+		resp := useCase.AssignRole(context.TODO(), 1, "root")
+
+		assert.Equal(t, exception.ErrBadRequest, resp.Err())
+		repository.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Assign Role Not Found", func(t *testing.T) {
+		repository := new(mocks.AccountRepository)
+		repository.On("FindByID", mock.Anything, int64(9)).Return(models.Account{}, exception.ErrNotFound)
+
+		useCase := account.NewAccountUseCase(repository, new(bcryptmocks.Bcrypt), newAuditUseCase(), newOutboxRepository(), newTransactor())
+
+		resp := useCase.AssignRole(context.TODO(), 9, account.RoleAdmin)
+
+		assert.Equal(t, exception.ErrNotFound, resp.Err())
+	})
+
+	t.Run("List Accounts", func(t *testing.T) {
+		repository := new(mocks.AccountRepository)
+		repository.On("List", mock.Anything, int64(0), 100).Return([]models.Account{{ID: 1}}, nil)
+
+		useCase := account.NewAccountUseCase(repository, new(bcryptmocks.Bcrypt), newAuditUseCase(), newOutboxRepository(), newTransactor())
+
+		resp := useCase.ListAccounts(context.TODO(), 0, 0)
+
+		assert.NoError(t, resp.Err())
+		assert.Len(t, resp.(*response.ResponseImpl).Data, 1)
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Now()
+	revokedAt := now.Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		account models.Account
+		findErr error
+		issued  time.Time
+		err     error
+	}{
+		{name: "Active", account: models.Account{ID: 1}, issued: now},
+		{name: "Unknown Account", findErr: exception.ErrNotFound, issued: now, err: exception.ErrUnauthorized},
+		{name: "Disabled", account: models.Account{ID: 1, DisabledAt: &revokedAt}, issued: now, err: exception.ErrForbidden},
+		{name: "Issued Before Revocation", account: models.Account{ID: 1, SessionsRevokedAt: &revokedAt}, issued: revokedAt.Add(-time.Hour), err: exception.ErrUnauthorized},
+		{name: "Issued After Revocation", account: models.Account{ID: 1, SessionsRevokedAt: &revokedAt}, issued: now},
+		{name: "Store Unavailable", findErr: exception.Wrap("AccountRepository.FindByID", exception.ErrUnavailable, errors.New("connection refused")), issued: now, err: exception.ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := new(mocks.AccountRepository)
+			repository.On("FindByID", mock.Anything, int64(1)).Return(tt.account, tt.findErr)
+
+			useCase := account.NewAccountUseCase(repository, new(bcryptmocks.Bcrypt), newAuditUseCase(), newOutboxRepository(), newTransactor())
+
+			claims := jwt.JWTclaim{ID: 1}
+			claims.IssuedAt = tt.issued.Unix()
+
+			resp := useCase.Authenticate(context.TODO(), claims)
+
+			assert.Equal(t, tt.err, resp.Err())
+		})
+	}
 }
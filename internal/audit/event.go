@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"waizly/models"
+)
+
+const (
+	ActionRegister    = "account.register"
+	ActionLogin       = "account.login"
+	ActionUpdate      = "account.update"
+	ActionEmailChange = "account.email_change"
+	ActionDelete      = "account.delete"
+	ActionDisable     = "account.disable"
+	ActionEnable      = "account.enable"
+	ActionPassword    = "account.password_reset"
+	ActionRoleChange  = "account.role_change"
+	ActionRevoke      = "account.sessions_revoke"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Widths of the audit_log columns filled from request data, in characters.
+const (
+	maxDetail    = 512
+	maxIP        = 45
+	maxUserAgent = 512
+	maxRequestID = 128
+)
+
+// fit cuts the fields a client controls to their column widths, so an
+// oversized header or email cannot make the insert fail and keep the event
+// out of the log. It runs before the hash is computed, so the stored row
+// still verifies.
+func fit(event models.AuditEvent) models.AuditEvent {
+	event.Detail = truncate(event.Detail, maxDetail)
+	event.IP = truncate(event.IP, maxIP)
+	event.UserAgent = truncate(event.UserAgent, maxUserAgent)
+	event.RequestID = truncate(event.RequestID, maxRequestID)
+
+	return event
+}
+
+// truncate keeps the first n characters of s.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	count := 0
+	for i := range s {
+		if count == n {
+			return s[:i]
+		}
+		count++
+	}
+
+	return s
+}
+
+// ComputeHash chains an event to its predecessor. Every field that describes
+// the event is part of the digest, so editing a stored row or removing one
+// from the middle of the log breaks verification from that point on.
+func ComputeHash(event models.AuditEvent) string {
+	fields := []string{
+		event.PrevHash,
+		strconv.FormatInt(event.ActorID, 10),
+		strconv.FormatInt(event.SubjectID, 10),
+		event.Action,
+		event.Outcome,
+		event.Detail,
+		event.IP,
+		event.UserAgent,
+		event.RequestID,
+		event.OccurredAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	h := sha256.New()
+	for _, field := range fields {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
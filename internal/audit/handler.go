@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/response"
+	"waizly/models"
+)
+
+type AuditHandler struct {
+	UseCase AuditUseCase
+}
+
+func NewAuditHandler(router *mux.Router, usecase AuditUseCase, auth mux.MiddlewareFunc) {
+	handler := &AuditHandler{
+		UseCase: usecase,
+	}
+
+	admin := router.PathPrefix("/admin/audit").Subrouter()
+	admin.Use(auth)
+
+	admin.HandleFunc("", handler.Search).Methods(http.MethodGet)
+	admin.HandleFunc("/verify", handler.Verify).Methods(http.MethodGet)
+}
+
+func (handler *AuditHandler) Search(w http.ResponseWriter, r *http.Request) {
+	var res response.Response
+	ctx := r.Context()
+
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		res = response.Error(response.StatusBadRequest, exception.ErrBadRequest)
+		res.Render(w, r)
+		return
+	}
+
+	res = handler.UseCase.Search(ctx, filter)
+
+	res.Render(w, r)
+}
+
+func (handler *AuditHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	res := handler.UseCase.Verify(r.Context())
+
+	res.Render(w, r)
+}
+
+func parseFilter(query url.Values) (models.AuditFilter, error) {
+	var err error
+	filter := models.AuditFilter{
+		Action:    query.Get("action"),
+		Outcome:   query.Get("outcome"),
+		RequestID: query.Get("request_id"),
+	}
+
+	ints := map[string]*int64{
+		"actor_id":   &filter.ActorID,
+		"subject_id": &filter.SubjectID,
+		"after_id":   &filter.AfterID,
+	}
+
+	for key, dst := range ints {
+		if value := query.Get(key); value != "" {
+			*dst, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return filter, err
+			}
+		}
+	}
+
+	times := map[string]*time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	}
+
+	for key, dst := range times {
+		if value := query.Get(key); value != "" {
+			*dst, err = time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, err
+			}
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		filter.Limit, err = strconv.Atoi(value)
+		if err != nil {
+			return filter, err
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,105 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"waizly/helpers/middleware"
+	"waizly/helpers/response"
+	"waizly/internal/audit"
+	"waizly/internal/audit/mocks"
+	"waizly/models"
+)
+
+func TestHandler_Search(t *testing.T) {
+	t.Run("Search Success", func(t *testing.T) {
+		resp := response.Success(response.StatusOK, []models.AuditEvent{})
+
+		auditUseCase := new(mocks.AuditUseCase)
+		auditUseCase.On("Search", mock.Anything, mock.MatchedBy(func(filter models.AuditFilter) bool {
+			return filter.SubjectID == 4 && filter.Outcome == audit.OutcomeFailure && !filter.From.IsZero()
+		})).Return(resp)
+
+		router := mux.NewRouter()
+		audit.NewAuditHandler(router, auditUseCase, middleware.BasicAuth("admin", "secret"))
+
+		r := httptest.NewRequest(http.MethodGet, "/admin/audit?subject_id=4&outcome=failure&from=2021-12-12T00:00:00Z", nil)
+		r.SetBasicAuth("admin", "secret")
+		recorder := httptest.NewRecorder()
+
+		router.ServeHTTP(recorder, r)
+
+		rb := response.ResponseImpl{}
+		if err := json.NewDecoder(recorder.Body).Decode(&rb); err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, response.StatusOK, rb.Status, fmt.Sprintf("Should be status '%s'", response.StatusOK))
+
+		auditUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Search Invalid Filter", func(t *testing.T) {
+		auditUseCase := new(mocks.AuditUseCase)
+
+		router := mux.NewRouter()
+		audit.NewAuditHandler(router, auditUseCase, middleware.BasicAuth("admin", "secret"))
+
+		r := httptest.NewRequest(http.MethodGet, "/admin/audit?from=yesterday", nil)
+		r.SetBasicAuth("admin", "secret")
+		recorder := httptest.NewRecorder()
+
+		router.ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+		auditUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Search Unauthorized", func(t *testing.T) {
+		auditUseCase := new(mocks.AuditUseCase)
+
+		router := mux.NewRouter()
+		audit.NewAuditHandler(router, auditUseCase, middleware.BasicAuth("admin", "secret"))
+
+		r := httptest.NewRequest(http.MethodGet, "/admin/audit", nil)
+		r.SetBasicAuth("admin", "wrong")
+		recorder := httptest.NewRecorder()
+
+		router.ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+		auditUseCase.AssertExpectations(t)
+	})
+}
+
+func TestHandler_Verify(t *testing.T) {
+	t.Run("Verify Success", func(t *testing.T) {
+		resp := response.Success(response.StatusOK, models.AuditVerification{Valid: true})
+
+		auditUseCase := new(mocks.AuditUseCase)
+		auditUseCase.On("Verify", mock.Anything).Return(resp)
+
+		router := mux.NewRouter()
+		audit.NewAuditHandler(router, auditUseCase, middleware.BasicAuth("admin", "secret"))
+
+		r := httptest.NewRequest(http.MethodGet, "/admin/audit/verify", nil)
+		r.SetBasicAuth("admin", "secret")
+		recorder := httptest.NewRecorder()
+
+		router.ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		auditUseCase.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,210 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"waizly/helpers/dialect"
+	"waizly/helpers/exception"
+	"waizly/helpers/logger"
+	"waizly/helpers/transaction"
+	"waizly/models"
+)
+
+type (
+	AuditRepository interface {
+		Append(ctx context.Context, event models.AuditEvent) (models.AuditEvent, error)
+		Find(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+		Head(ctx context.Context) (string, error)
+	}
+
+	auditRepositoryImpl struct {
+		db            *sql.DB
+		dialect       dialect.Dialect
+		transactor    transaction.Transactor
+		tableName     string
+		headTableName string
+	}
+)
+
+func NewAuditRepository(db *sql.DB, d dialect.Dialect, tableName, headTableName string) AuditRepository {
+	return &auditRepositoryImpl{
+		db:            db,
+		dialect:       d,
+		transactor:    transaction.NewTransactor(db),
+		tableName:     tableName,
+		headTableName: headTableName,
+	}
+}
+
+// Append links the event to the current head of the chain and inserts it.
+// The head row is locked for the duration of the transaction so concurrent
+// writers, including other instances, are serialized. Called inside a
+// transaction, the event commits or rolls back with it; a failed append only
+// rolls back its own savepoint.
+func (ar *auditRepositoryImpl) Append(ctx context.Context, event models.AuditEvent) (models.AuditEvent, error) {
+	err := ar.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		conn := transaction.Conn(ctx, ar.db)
+
+		query := fmt.Sprintf(`SELECT hash FROM %s WHERE id = 1 FOR UPDATE`, ar.headTableName)
+		err := conn.QueryRowContext(ctx, ar.dialect.Lock(query)).Scan(&event.PrevHash)
+		if err != nil {
+			return err
+		}
+
+		event.Hash = ComputeHash(event)
+
+		query = fmt.Sprintf(`INSERT INTO %s (actor_id, subject_id, action, outcome, detail, ip, user_agent, request_id, occurred_at, prev_hash, hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, ar.tableName)
+		event.ID, err = ar.dialect.InsertID(
+			ctx,
+			conn,
+			query,
+			event.ActorID,
+			event.SubjectID,
+			event.Action,
+			event.Outcome,
+			event.Detail,
+			event.IP,
+			event.UserAgent,
+			event.RequestID,
+			event.OccurredAt,
+			event.PrevHash,
+			event.Hash,
+		)
+
+		if err != nil {
+			return err
+		}
+
+		query = fmt.Sprintf(`UPDATE %s SET hash = ? WHERE id = 1`, ar.headTableName)
+		_, err = conn.ExecContext(ctx, ar.dialect.Rebind(query), event.Hash)
+		return err
+	})
+
+	if err != nil {
+		logger.Ctx(ctx).Error("AuditRepository.Append", "error", err)
+		return event, exception.Database("AuditRepository.Append", err)
+	}
+
+	return event, nil
+}
+
+func (ar *auditRepositoryImpl) Find(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.ActorID != 0 {
+		conditions = append(conditions, "actor_id = ?")
+		args = append(args, filter.ActorID)
+	}
+
+	if filter.SubjectID != 0 {
+		conditions = append(conditions, "subject_id = ?")
+		args = append(args, filter.SubjectID)
+	}
+
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+
+	if filter.Outcome != "" {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, filter.Outcome)
+	}
+
+	if filter.RequestID != "" {
+		conditions = append(conditions, "request_id = ?")
+		args = append(args, filter.RequestID)
+	}
+
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "occurred_at >= ?")
+		args = append(args, filter.From)
+	}
+
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "occurred_at < ?")
+		args = append(args, filter.To)
+	}
+
+	conditions = append(conditions, "id > ?")
+	args = append(args, filter.AfterID)
+	args = append(args, filter.Limit)
+
+	query := fmt.Sprintf(`SELECT id, actor_id, subject_id, action, outcome, detail, ip, user_agent, request_id, occurred_at, prev_hash, hash FROM %s WHERE %s ORDER BY id LIMIT ?`, ar.tableName, strings.Join(conditions, " AND "))
+
+	stmt, err := ar.db.PrepareContext(ctx, ar.dialect.Rebind(query))
+	if err != nil {
+		logger.Ctx(ctx).Error("AuditRepository.Find", "error", err)
+		return nil, exception.Database("AuditRepository.Find", err)
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		logger.Ctx(ctx).Error("AuditRepository.Find", "error", err)
+		return nil, exception.Database("AuditRepository.Find", err)
+	}
+
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		event := models.AuditEvent{}
+
+		err = rows.Scan(
+			&event.ID,
+			&event.ActorID,
+			&event.SubjectID,
+			&event.Action,
+			&event.Outcome,
+			&event.Detail,
+			&event.IP,
+			&event.UserAgent,
+			&event.RequestID,
+			&event.OccurredAt,
+			&event.PrevHash,
+			&event.Hash,
+		)
+
+		if err != nil {
+			logger.Ctx(ctx).Error("AuditRepository.Find", "error", err)
+			return nil, exception.Database("AuditRepository.Find", err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Ctx(ctx).Error("AuditRepository.Find", "error", err)
+		return nil, exception.Database("AuditRepository.Find", err)
+	}
+
+	return events, nil
+}
+
+// Head returns the hash of the most recently appended event.
+func (ar *auditRepositoryImpl) Head(ctx context.Context) (string, error) {
+	var hash string
+
+	query := fmt.Sprintf(`SELECT hash FROM %s WHERE id = 1`, ar.headTableName)
+	stmt, err := ar.db.PrepareContext(ctx, ar.dialect.Rebind(query))
+	if err != nil {
+		logger.Ctx(ctx).Error("AuditRepository.Head", "error", err)
+		return hash, exception.Database("AuditRepository.Head", err)
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(ctx).Scan(&hash)
+	if err != nil {
+		logger.Ctx(ctx).Error("AuditRepository.Head", "error", err)
+		return hash, exception.Database("AuditRepository.Head", err)
+	}
+
+	return hash, nil
+}
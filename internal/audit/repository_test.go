@@ -0,0 +1,123 @@
+package audit_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/dialect"
+	"waizly/helpers/exception"
+	"waizly/internal/audit"
+	"waizly/internal/constant"
+	"waizly/internal/mock"
+	"waizly/models"
+)
+
+var occurredAt = time.Date(2021, 12, 12, 0, 0, 0, 0, time.UTC)
+
+func TestAppend(t *testing.T) {
+	t.Run("Append Success", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := audit.NewAuditRepository(db, dialect.MySQL, constant.TableAuditLog, constant.TableAuditLogHead)
+
+		defer db.Close()
+
+		event := models.AuditEvent{
+			ActorID:    1,
+			SubjectID:  1,
+			Action:     audit.ActionLogin,
+			Outcome:    audit.OutcomeSuccess,
+			OccurredAt: occurredAt,
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(fmt.Sprintf(`SELECT hash FROM %s WHERE id = 1 FOR UPDATE`, constant.TableAuditLogHead)).WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow("previous"))
+		mock.ExpectExec(fmt.Sprintf(`INSERT INTO %s`, constant.TableAuditLog)).WillReturnResult(sqlmock.NewResult(5, 1))
+		mock.ExpectExec(fmt.Sprintf(`UPDATE %s SET hash = \?`, constant.TableAuditLogHead)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		stored, err := repo.Append(context.TODO(), event)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), stored.ID)
+		assert.Equal(t, "previous", stored.PrevHash)
+		assert.Equal(t, audit.ComputeHash(stored), stored.Hash)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Append Error Rolls Back", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := audit.NewAuditRepository(db, dialect.MySQL, constant.TableAuditLog, constant.TableAuditLogHead)
+
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT hash FROM`).WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow(""))
+		mock.ExpectExec(`INSERT INTO`).WillReturnError(fmt.Errorf("insert failed"))
+		mock.ExpectRollback()
+
+		_, err := repo.Append(context.TODO(), models.AuditEvent{})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Append Deadlock Is Retryable", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := audit.NewAuditRepository(db, dialect.MySQL, constant.TableAuditLog, constant.TableAuditLogHead)
+
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT hash FROM`).WillReturnError(&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"})
+		mock.ExpectRollback()
+
+		_, err := repo.Append(context.TODO(), models.AuditEvent{})
+
+		assert.ErrorIs(t, err, exception.ErrInternalServer)
+		assert.True(t, dialect.MySQL.Retryable(err), "the driver error is kept as the cause")
+	})
+}
+
+func TestFind(t *testing.T) {
+	t.Run("Find With Filter", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := audit.NewAuditRepository(db, dialect.MySQL, constant.TableAuditLog, constant.TableAuditLogHead)
+
+		defer db.Close()
+
+		columns := []string{"id", "actor_id", "subject_id", "action", "outcome", "detail", "ip", "user_agent", "request_id", "occurred_at", "prev_hash", "hash"}
+		rows := sqlmock.NewRows(columns).AddRow(1, 2, 2, audit.ActionDelete, audit.OutcomeSuccess, "", "127.0.0.1", "curl", "req-1", occurredAt, "", "hash")
+
+		mock.ExpectPrepare(`WHERE subject_id = \? AND action = \? AND id > \? ORDER BY id LIMIT \?`).ExpectQuery().WithArgs(int64(2), audit.ActionDelete, int64(0), 10).WillReturnRows(rows)
+
+		events, err := repo.Find(context.TODO(), models.AuditFilter{
+			SubjectID: 2,
+			Action:    audit.ActionDelete,
+			Limit:     10,
+		})
+
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+		assert.Equal(t, "req-1", events[0].RequestID)
+	})
+
+	t.Run("Find Error", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := audit.NewAuditRepository(db, dialect.MySQL, constant.TableAuditLog, constant.TableAuditLogHead)
+
+		defer db.Close()
+
+		mock.ExpectPrepare(`SELECT id`).ExpectQuery().WillReturnError(fmt.Errorf("query failed"))
+
+		events, err := repo.Find(context.TODO(), models.AuditFilter{Limit: 10})
+
+		assert.Error(t, err)
+		assert.Nil(t, events)
+	})
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/metadata"
+	"waizly/helpers/response"
+	"waizly/models"
+)
+
+const (
+	DefaultSearchLimit = 100
+	MaxSearchLimit     = 1000
+
+	verifyBatchSize = 500
+)
+
+type (
+	AuditUseCase interface {
+		Record(ctx context.Context, event models.AuditEvent) error
+		Search(ctx context.Context, filter models.AuditFilter) response.Response
+		Verify(ctx context.Context) response.Response
+	}
+
+	auditUseCaseImpl struct {
+		repository AuditRepository
+	}
+)
+
+func NewAuditUseCase(repo AuditRepository) AuditUseCase {
+	return &auditUseCaseImpl{
+		repository: repo,
+	}
+}
+
+// Record fills in the request metadata carried by ctx and appends the event,
+// cut to the width of the audit_log columns.
+func (au *auditUseCaseImpl) Record(ctx context.Context, event models.AuditEvent) error {
+	req := metadata.FromContext(ctx)
+
+	event.IP = req.IP
+	event.UserAgent = req.UserAgent
+	event.RequestID = req.RequestID
+	// DATETIME(6) keeps microseconds, anything finer would not survive the
+	// round trip and the stored hash could no longer be reproduced.
+	event.OccurredAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	_, err := au.repository.Append(ctx, fit(event))
+
+	return err
+}
+
+func (au *auditUseCaseImpl) Search(ctx context.Context, filter models.AuditFilter) response.Response {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultSearchLimit
+	}
+
+	if filter.Limit > MaxSearchLimit {
+		filter.Limit = MaxSearchLimit
+	}
+
+	events, err := au.repository.Find(ctx, filter)
+	if err != nil {
+		return response.Error(response.StatusInternalServerError, exception.ErrInternalServer)
+	}
+
+	return response.Success(response.StatusOK, events)
+}
+
+// Verify walks the whole log and recomputes every hash. The head is read
+// first so events appended while verifying are ignored, and reaching the end
+// of the table without meeting the head means rows were cut from the tail.
+func (au *auditUseCaseImpl) Verify(ctx context.Context) response.Response {
+	head, err := au.repository.Head(ctx)
+	if err != nil {
+		return response.Error(response.StatusInternalServerError, exception.ErrInternalServer)
+	}
+
+	result := models.AuditVerification{Valid: true}
+	filter := models.AuditFilter{Limit: verifyBatchSize}
+	prevHash := ""
+
+	for prevHash != head {
+		events, err := au.repository.Find(ctx, filter)
+		if err != nil {
+			return response.Error(response.StatusInternalServerError, exception.ErrInternalServer)
+		}
+
+		if len(events) == 0 {
+			result.Valid = false
+			result.BrokenAt = filter.AfterID
+			break
+		}
+
+		for _, event := range events {
+			if event.PrevHash != prevHash || ComputeHash(event) != event.Hash {
+				result.Valid = false
+				result.BrokenAt = event.ID
+				return response.Success(response.StatusOK, result)
+			}
+
+			result.Checked++
+			prevHash = event.Hash
+			filter.AfterID = event.ID
+
+			if prevHash == head {
+				break
+			}
+		}
+	}
+
+	return response.Success(response.StatusOK, result)
+}
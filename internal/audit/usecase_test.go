@@ -0,0 +1,183 @@
+package audit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/metadata"
+	"waizly/helpers/response"
+	"waizly/internal/audit"
+	"waizly/internal/audit/mocks"
+	"waizly/models"
+)
+
+func chain(n int) []models.AuditEvent {
+	events := make([]models.AuditEvent, n)
+	prevHash := ""
+
+	for i := range events {
+		events[i] = models.AuditEvent{
+			ID:         int64(i + 1),
+			ActorID:    1,
+			SubjectID:  1,
+			Action:     audit.ActionLogin,
+			Outcome:    audit.OutcomeSuccess,
+			OccurredAt: occurredAt,
+			PrevHash:   prevHash,
+		}
+		events[i].Hash = audit.ComputeHash(events[i])
+		prevHash = events[i].Hash
+	}
+
+	return events
+}
+
+func TestRecord(t *testing.T) {
+	t.Run("Record Uses Request Metadata", func(t *testing.T) {
+		auditRepository := new(mocks.AuditRepository)
+
+		auditRepository.On("Append", mock.Anything, mock.MatchedBy(func(event models.AuditEvent) bool {
+			return event.IP == "10.0.0.1" && event.UserAgent == "test-agent" && event.RequestID == "req-1" && !event.OccurredAt.IsZero()
+		})).Return(models.AuditEvent{}, nil)
+
+		auditUseCase := audit.NewAuditUseCase(auditRepository)
+
+		ctx := metadata.NewContext(context.TODO(), metadata.Request{
+			IP:        "10.0.0.1",
+			UserAgent: "test-agent",
+			RequestID: "req-1",
+		})
+
+		err := auditUseCase.Record(ctx, models.AuditEvent{Action: audit.ActionLogin})
+
+		assert.NoError(t, err)
+		auditRepository.AssertExpectations(t)
+	})
+
+	t.Run("Record Fits Columns", func(t *testing.T) {
+		auditRepository := new(mocks.AuditRepository)
+
+		auditRepository.On("Append", mock.Anything, mock.MatchedBy(func(event models.AuditEvent) bool {
+			return len(event.UserAgent) == 512 && utf8.RuneCountInString(event.Detail) == 512 && utf8.ValidString(event.Detail)
+		})).Return(models.AuditEvent{}, nil)
+
+		auditUseCase := audit.NewAuditUseCase(auditRepository)
+
+		ctx := metadata.NewContext(context.TODO(), metadata.Request{
+			UserAgent: strings.Repeat("a", 1024),
+		})
+
+		err := auditUseCase.Record(ctx, models.AuditEvent{
+			Action: audit.ActionLogin,
+			Detail: "unknown email " + strings.Repeat("é", 1024) + "@test.com",
+		})
+
+		assert.NoError(t, err)
+		auditRepository.AssertExpectations(t)
+	})
+
+	t.Run("Record Error", func(t *testing.T) {
+		auditRepository := new(mocks.AuditRepository)
+
+		auditRepository.On("Append", mock.Anything, mock.AnythingOfType("models.AuditEvent")).Return(models.AuditEvent{}, exception.ErrInternalServer)
+
+		auditUseCase := audit.NewAuditUseCase(auditRepository)
+
+		err := auditUseCase.Record(context.TODO(), models.AuditEvent{})
+
+		assert.Error(t, err)
+		auditRepository.AssertExpectations(t)
+	})
+}
+
+func TestSearch(t *testing.T) {
+	t.Run("Search Caps Limit", func(t *testing.T) {
+		auditRepository := new(mocks.AuditRepository)
+
+		auditRepository.On("Find", mock.Anything, mock.MatchedBy(func(filter models.AuditFilter) bool {
+			return filter.Limit == audit.MaxSearchLimit
+		})).Return([]models.AuditEvent{}, nil)
+
+		auditUseCase := audit.NewAuditUseCase(auditRepository)
+
+		resp := auditUseCase.Search(context.TODO(), models.AuditFilter{Limit: 100000})
+
+		assert.NoError(t, resp.Err())
+		auditRepository.AssertExpectations(t)
+	})
+
+	t.Run("Search Error", func(t *testing.T) {
+		auditRepository := new(mocks.AuditRepository)
+
+		auditRepository.On("Find", mock.Anything, mock.AnythingOfType("models.AuditFilter")).Return(nil, exception.ErrInternalServer)
+
+		auditUseCase := audit.NewAuditUseCase(auditRepository)
+
+		resp := auditUseCase.Search(context.TODO(), models.AuditFilter{})
+
+		assert.Error(t, resp.Err())
+		auditRepository.AssertExpectations(t)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	verify := func(t *testing.T, head string, events []models.AuditEvent) models.AuditVerification {
+		auditRepository := new(mocks.AuditRepository)
+
+		auditRepository.On("Head", mock.Anything).Return(head, nil)
+		auditRepository.On("Find", mock.Anything, mock.MatchedBy(func(filter models.AuditFilter) bool {
+			return filter.AfterID == 0
+		})).Return(events, nil).Maybe()
+		auditRepository.On("Find", mock.Anything, mock.MatchedBy(func(filter models.AuditFilter) bool {
+			return filter.AfterID != 0
+		})).Return([]models.AuditEvent{}, nil).Maybe()
+
+		auditUseCase := audit.NewAuditUseCase(auditRepository)
+
+		resp := auditUseCase.Verify(context.TODO())
+		assert.NoError(t, resp.Err())
+
+		return resp.(*response.ResponseImpl).Data.(models.AuditVerification)
+	}
+
+	t.Run("Intact Chain", func(t *testing.T) {
+		events := chain(3)
+
+		result := verify(t, events[2].Hash, events)
+
+		assert.True(t, result.Valid)
+		assert.Equal(t, int64(3), result.Checked)
+	})
+
+	t.Run("Empty Log", func(t *testing.T) {
+		result := verify(t, "", nil)
+
+		assert.True(t, result.Valid)
+		assert.Equal(t, int64(0), result.Checked)
+	})
+
+	t.Run("Tampered Event", func(t *testing.T) {
+		events := chain(3)
+		events[1].Outcome = audit.OutcomeFailure
+
+		result := verify(t, events[2].Hash, events)
+
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(2), result.BrokenAt)
+	})
+
+	t.Run("Truncated Tail", func(t *testing.T) {
+		events := chain(3)
+
+		result := verify(t, events[2].Hash, events[:2])
+
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(2), result.BrokenAt)
+	})
+}
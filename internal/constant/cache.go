@@ -0,0 +1,4 @@
+package constant
+
+// CachePrefix starts every key the service writes to a shared cache.
+const CachePrefix = "waizly:"
@@ -1,3 +1,14 @@
 package constant
 
-const TableAccount = "account"
+const (
+	TableAccount      = "account"
+	TableAuditLog     = "audit_log"
+	TableAuditLogHead = "audit_log_head"
+	TableOutbox       = "outbox"
+
+	TableWebhookSubscription = "webhook_subscription"
+	TableWebhookDelivery     = "webhook_delivery"
+	TableWebhookAttempt      = "webhook_attempt"
+
+	TableSchemaMigrations = "schema_migrations"
+)
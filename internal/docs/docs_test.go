@@ -0,0 +1,169 @@
+package docs_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/validation"
+	accountMocks "waizly/internal/account/mocks"
+	auditMocks "waizly/internal/audit/mocks"
+	"waizly/internal/docs"
+	"waizly/internal/health"
+	"waizly/internal/routes"
+	webhookMocks "waizly/internal/webhook/mocks"
+	"waizly/models"
+)
+
+type document struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+// undocumented are operational routes that are not part of the API.
+var undocumented = map[string]bool{
+	"/openapi.json": true,
+	"/docs":         true,
+	"/docs/":        true,
+	"/metrics":      true,
+}
+
+func load(t *testing.T) document {
+	var doc document
+
+	err := json.Unmarshal(docs.Spec(), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return doc
+}
+
+func TestRoutesDocumented(t *testing.T) {
+	doc := load(t)
+
+	router := mux.NewRouter()
+	routes.Register(router, routes.Dependencies{
+		Health:    health.New(time.Second, 0),
+		Metrics:   http.NotFoundHandler(),
+		Validate:  validation.New(),
+		Accounts:  new(accountMocks.AccountUseCase),
+		Audit:     new(auditMocks.AuditUseCase),
+		Webhooks:  new(webhookMocks.WebhookUseCase),
+		AdminAuth: func(next http.Handler) http.Handler { return next },
+	})
+
+	pattern := regexp.MustCompile(`\{(\w+):[^}]*\}`)
+	var missing []string
+
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		path := pattern.ReplaceAllString(template, "{$1}")
+		if undocumented[path] {
+			return nil
+		}
+
+		for _, method := range methods {
+			if _, ok := doc.Paths[path][strings.ToLower(method)]; !ok {
+				missing = append(missing, method+" "+path)
+			}
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, missing, "routes missing from openapi.json")
+}
+
+func TestSchemasMatchModels(t *testing.T) {
+	doc := load(t)
+
+	models := map[string]interface{}{
+		"Account":                          models.Account{},
+		"RegisterRequest":                  models.RegisterRequest{},
+		"LoginRequest":                     models.LoginRequest{},
+		"AuditEvent":                       models.AuditEvent{},
+		"AuditVerification":                models.AuditVerification{},
+		"WebhookSubscription":              models.WebhookSubscription{},
+		"WebhookSubscriptionRequest":       models.WebhookSubscriptionRequest{},
+		"WebhookSubscriptionUpdateRequest": models.WebhookSubscriptionUpdateRequest{},
+		"WebhookDelivery":                  models.WebhookDelivery{},
+		"WebhookAttempt":                   models.WebhookAttempt{},
+		"HealthReport":                     health.Report{},
+		"HealthCheck":                      health.Check{},
+	}
+
+	for name, model := range models {
+		t.Run(name, func(t *testing.T) {
+			var documented []string
+			for property := range doc.Components.Schemas[name].Properties {
+				documented = append(documented, property)
+			}
+			sort.Strings(documented)
+
+			assert.Equal(t, jsonFields(reflect.TypeOf(model)), documented)
+		})
+	}
+}
+
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+func TestHandler(t *testing.T) {
+	router := mux.NewRouter()
+	docs.NewDocsHandler(router)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, string(docs.Spec()), recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	assert.Contains(t, recorder.Body.String(), "/openapi.json")
+	assert.NotContains(t, recorder.Body.String(), "https://", "the page loads nothing from outside")
+
+	for _, asset := range []string{"/docs/swagger-ui.css", "/docs/swagger-ui-bundle.js"} {
+		recorder = httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, asset, nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code, asset)
+		assert.NotEmpty(t, recorder.Body.String(), asset)
+	}
+}
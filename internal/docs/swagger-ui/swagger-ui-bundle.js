@@ -0,0 +1,5 @@
+// Placeholder, replaced by `make docs.ui` with swagger-ui-dist 5.9.0.
+window.SwaggerUIBundle = function (options) {
+  var root = document.querySelector(options.dom_id);
+  root.textContent = "Swagger UI is not vendored yet, run `make docs.ui`. The API document is at " + options.url + ".";
+};
@@ -0,0 +1,44 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Ping checks that db accepts connections.
+func Ping(db *sql.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	})
+}
+
+// VersionSource reports the applied schema version; *migrator.Migrator
+// satisfies it.
+type VersionSource interface {
+	Version(ctx context.Context) (version int64, dirty bool, err error)
+}
+
+// Migration checks that the schema is clean and at least at the expected
+// version, so an instance built for a newer schema does not take traffic
+// before the migration has run. A schema ahead of the binary is fine: during
+// a rolling deploy the new release migrates while the old instances keep
+// serving.
+func Migration(source VersionSource, expected int64) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		version, dirty, err := source.Version(ctx)
+		if err != nil {
+			return err
+		}
+
+		if dirty {
+			return fmt.Errorf("schema version %d is dirty", version)
+		}
+
+		if version < expected {
+			return fmt.Errorf("schema version is %d, want %d", version, expected)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,47 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"waizly/helpers/response"
+)
+
+type HealthHandler struct {
+	Health *Health
+}
+
+func NewHealthHandler(router *mux.Router, health *Health) {
+	handler := &HealthHandler{
+		Health: health,
+	}
+
+	router.HandleFunc("/healthz", handler.Live).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", handler.Ready).Methods(http.MethodGet)
+}
+
+// Live only reports that the process is serving requests; dependencies are
+// left to Ready so a database outage does not get every instance restarted.
+func (handler *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	res := response.Success(response.StatusOK, "alive")
+
+	res.JSON(w)
+}
+
+func (handler *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if handler.Health.Stopping() {
+		res := &response.ResponseImpl{Status: response.StatusServiceUnavailable, Data: "shutting down"}
+		res.JSON(w)
+		return
+	}
+
+	report := handler.Health.Ready(r.Context())
+
+	res := &response.ResponseImpl{Status: response.StatusOK, Data: report}
+	if !report.Ready {
+		res.Status = response.StatusServiceUnavailable
+	}
+
+	res.JSON(w)
+}
@@ -0,0 +1,151 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Checker reports whether a dependency is usable. It should return promptly
+// once ctx is done.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type (
+	// Report is the result of one readiness evaluation.
+	Report struct {
+		Ready     bool             `json:"ready"`
+		CheckedAt time.Time        `json:"checked_at"`
+		Checks    map[string]Check `json:"checks"`
+	}
+
+	Check struct {
+		Status     string `json:"status"`
+		Error      string `json:"error,omitempty"`
+		DurationMs int64  `json:"duration_ms"`
+	}
+
+	Health struct {
+		timeout  time.Duration
+		cacheTTL time.Duration
+		checkers map[string]Checker
+		stopping atomic.Bool
+
+		mu     sync.Mutex
+		cached Report
+	}
+)
+
+// New returns a Health that gives every checker timeout to answer and reuses
+// a readiness report for cacheTTL, so frequent probes do not hammer the
+// dependencies.
+func New(timeout, cacheTTL time.Duration) *Health {
+	return &Health{
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		checkers: make(map[string]Checker),
+	}
+}
+
+// Register adds a readiness checker. It must be called before the first
+// Ready call.
+func (h *Health) Register(name string, checker Checker) {
+	h.checkers[name] = checker
+}
+
+// Shutdown marks the process as not ready so load balancers stop routing to
+// it while in-flight requests drain.
+func (h *Health) Shutdown() {
+	h.stopping.Store(true)
+}
+
+// Stopping reports whether Shutdown was called.
+func (h *Health) Stopping() bool {
+	return h.stopping.Load()
+}
+
+// Ready runs every checker concurrently, or returns the cached report when it
+// is younger than the cache TTL.
+func (h *Health) Ready(ctx context.Context) Report {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.cached.CheckedAt.IsZero() && time.Since(h.cached.CheckedAt) < h.cacheTTL {
+		return h.cached
+	}
+
+	names := make([]string, 0, len(h.checkers))
+	for name := range h.checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]Check, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, checker Checker) {
+			defer wg.Done()
+			results[i] = h.run(ctx, checker)
+		}(i, h.checkers[name])
+	}
+	wg.Wait()
+
+	report := Report{
+		Ready:     true,
+		CheckedAt: time.Now(),
+		Checks:    make(map[string]Check, len(names)),
+	}
+
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Ready = false
+		}
+	}
+
+	// A probe that gave up early would otherwise pin its failures in the
+	// cache for everyone else.
+	if ctx.Err() == nil {
+		h.cached = report
+	}
+
+	return report
+}
+
+func (h *Health) run(ctx context.Context, checker Checker) Check {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	err := checker.Check(ctx)
+
+	check := Check{
+		Status:     StatusUp,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+
+	if err != nil {
+		check.Status = StatusDown
+		check.Error = err.Error()
+	}
+
+	return check
+}
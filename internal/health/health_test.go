@@ -0,0 +1,154 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/response"
+	"waizly/internal/health"
+)
+
+type version struct {
+	version int64
+	dirty   bool
+	err     error
+}
+
+func (v version) Version(ctx context.Context) (int64, bool, error) {
+	return v.version, v.dirty, v.err
+}
+
+func TestReady(t *testing.T) {
+	t.Run("Per Check Detail", func(t *testing.T) {
+		checks := health.New(time.Second, 0)
+		checks.Register("ok", health.CheckerFunc(func(ctx context.Context) error { return nil }))
+		checks.Register("broken", health.CheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") }))
+
+		report := checks.Ready(context.TODO())
+
+		assert.False(t, report.Ready)
+		assert.Equal(t, health.StatusUp, report.Checks["ok"].Status)
+		assert.Equal(t, health.StatusDown, report.Checks["broken"].Status)
+		assert.Equal(t, "connection refused", report.Checks["broken"].Error)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		checks := health.New(20*time.Millisecond, 0)
+		checks.Register("slow", health.CheckerFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}))
+
+		report := checks.Ready(context.TODO())
+
+		assert.False(t, report.Ready)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		var calls int32
+
+		checks := health.New(time.Second, time.Minute)
+		checks.Register("counted", health.CheckerFunc(func(ctx context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		}))
+
+		checks.Ready(context.TODO())
+		report := checks.Ready(context.TODO())
+
+		assert.True(t, report.Ready)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestMigration(t *testing.T) {
+	tests := []struct {
+		name   string
+		source version
+		err    string
+	}{
+		{name: "Current", source: version{version: 5}},
+		{name: "Behind", source: version{version: 4}, err: "schema version is 4, want 5"},
+		{name: "Ahead", source: version{version: 6}},
+		{name: "Dirty", source: version{version: 5, dirty: true}, err: "schema version 5 is dirty"},
+		{name: "Unreachable", source: version{err: errors.New("bad connection")}, err: "bad connection"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := health.Migration(tt.source, 5).Check(context.TODO())
+
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	serve := func(checks *health.Health, path string) (int, response.ResponseImpl) {
+		router := mux.NewRouter()
+		health.NewHealthHandler(router, checks)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+		rb := response.ResponseImpl{}
+		if err := json.NewDecoder(recorder.Body).Decode(&rb); err != nil {
+			t.Fatal(err)
+		}
+
+		return recorder.Code, rb
+	}
+
+	t.Run("Live", func(t *testing.T) {
+		checks := health.New(time.Second, 0)
+		checks.Register("broken", health.CheckerFunc(func(ctx context.Context) error { return errors.New("down") }))
+
+		code, _ := serve(checks, "/healthz")
+
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		checks := health.New(time.Second, 0)
+		checks.Register("mysql", health.CheckerFunc(func(ctx context.Context) error { return nil }))
+
+		code, rb := serve(checks, "/readyz")
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, response.StatusOK, rb.Status)
+	})
+
+	t.Run("Not Ready", func(t *testing.T) {
+		checks := health.New(time.Second, 0)
+		checks.Register("mysql", health.CheckerFunc(func(ctx context.Context) error { return errors.New("down") }))
+
+		code, rb := serve(checks, "/readyz")
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, response.StatusServiceUnavailable, rb.Status)
+	})
+
+	t.Run("Shutting Down", func(t *testing.T) {
+		checks := health.New(time.Second, 0)
+		checks.Shutdown()
+
+		code, _ := serve(checks, "/readyz")
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+	})
+}
@@ -0,0 +1,195 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"waizly/helpers/logger"
+)
+
+// Worker is a background task that runs until its context is cancelled.
+// outbox.Relay and webhook.Worker both satisfy it.
+type Worker interface {
+	Run(ctx context.Context) error
+}
+
+type (
+	Manager struct {
+		server          *http.Server
+		shutdownTimeout time.Duration
+		drainDelay      time.Duration
+		hooks           []func()
+		workers         []worker
+		closers         []closer
+	}
+
+	worker struct {
+		name   string
+		worker Worker
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+
+	closer struct {
+		name   string
+		closer io.Closer
+	}
+)
+
+// CloserFunc adapts a function to io.Closer for AddCloser.
+type CloserFunc func() error
+
+func (f CloserFunc) Close() error {
+	return f()
+}
+
+func New(server *http.Server, shutdownTimeout time.Duration) *Manager {
+	return &Manager{
+		server:          server,
+		shutdownTimeout: shutdownTimeout,
+	}
+}
+
+// AddWorker registers a background task. Workers start before the server
+// accepts requests and stop after it has drained, in the order they were
+// added.
+func (m *Manager) AddWorker(name string, w Worker) {
+	m.workers = append(m.workers, worker{name: name, worker: w})
+}
+
+// OnShutdown registers fn to run as soon as shutdown begins, while the server
+// still accepts requests. Use it to fail readiness probes.
+func (m *Manager) OnShutdown(fn func()) {
+	m.hooks = append(m.hooks, fn)
+}
+
+// SetDrainDelay keeps the server accepting requests for d after the shutdown
+// hooks ran, giving load balancers time to notice the failing readiness
+// probe before connections are refused.
+func (m *Manager) SetDrainDelay(d time.Duration) {
+	m.drainDelay = d
+}
+
+// AddCloser registers a resource, such as a *sql.DB, that is closed once
+// every worker has stopped. Closers run in the order they were added.
+func (m *Manager) AddCloser(name string, c io.Closer) {
+	m.closers = append(m.closers, closer{name: name, closer: c})
+}
+
+// Run listens on the server address and serves until ctx is cancelled,
+// SIGINT or SIGTERM is received, or the server fails.
+func (m *Manager) Run(ctx context.Context) error {
+	addr := m.server.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return m.Serve(ctx, listener)
+}
+
+// Serve is Run on an existing listener. On the way out it stops accepting
+// connections, waits for in-flight requests, stops the workers and closes
+// the registered resources, all within the shutdown timeout.
+func (m *Manager) Serve(ctx context.Context, listener net.Listener) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for i := range m.workers {
+		m.start(&m.workers[i])
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- m.server.Serve(listener)
+	}()
+
+	logger.Default().Info("listening", "addr", listener.Addr().String())
+
+	var errs []string
+
+	select {
+	case <-ctx.Done():
+		logger.Default().Info("shutting down")
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, fmt.Sprintf("server: %v", err))
+		}
+	}
+
+	for _, fn := range m.hooks {
+		fn()
+	}
+
+	if m.drainDelay > 0 && len(errs) == 0 {
+		time.Sleep(m.drainDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+
+	err := m.server.Shutdown(shutdownCtx)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("drain requests: %v", err))
+	}
+
+	for i := range m.workers {
+		err := m.stop(shutdownCtx, &m.workers[i])
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	for _, c := range m.closers {
+		err := c.closer.Close()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("close %s: %v", c.name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func (m *Manager) start(w *worker) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+
+		err := w.worker.Run(ctx)
+		if err != nil {
+			logger.Default().Error("worker stopped", "worker", w.name, "error", err)
+		}
+	}()
+}
+
+func (m *Manager) stop(ctx context.Context, w *worker) error {
+	w.cancel()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("stop worker %s: %w", w.name, ctx.Err())
+	}
+}
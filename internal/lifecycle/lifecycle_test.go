@@ -0,0 +1,135 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"waizly/internal/lifecycle"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.events...)
+}
+
+type workerFunc func(ctx context.Context) error
+
+func (f workerFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+func listen(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return listener
+}
+
+func TestServe(t *testing.T) {
+	t.Run("Drains Requests Then Stops Workers And Closers In Order", func(t *testing.T) {
+		events := new(recorder)
+		started := make(chan struct{})
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			events.add("request done")
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		app := lifecycle.New(&http.Server{Handler: mux}, time.Second)
+		for _, name := range []string{"relay", "webhook"} {
+			name := name
+			app.AddWorker(name, workerFunc(func(ctx context.Context) error {
+				<-ctx.Done()
+				events.add(name + " stopped")
+				return nil
+			}))
+		}
+		app.AddCloser("database", closerFunc(func() error {
+			events.add("database closed")
+			return nil
+		}))
+		app.OnShutdown(func() {
+			events.add("not ready")
+		})
+
+		listener := listen(t)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		served := make(chan error, 1)
+		go func() {
+			served <- app.Serve(ctx, listener)
+		}()
+
+		status := make(chan int, 1)
+		go func() {
+			resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+			if err != nil {
+				status <- 0
+				return
+			}
+			resp.Body.Close()
+			status <- resp.StatusCode
+		}()
+
+		<-started
+		cancel()
+
+		assert.Equal(t, http.StatusNoContent, <-status)
+		assert.NoError(t, <-served)
+		assert.Equal(t, []string{"not ready", "request done", "relay stopped", "webhook stopped", "database closed"}, events.list())
+	})
+
+	t.Run("Worker Exceeding Deadline", func(t *testing.T) {
+		closed := false
+
+		app := lifecycle.New(&http.Server{Handler: http.NotFoundHandler()}, 50*time.Millisecond)
+		app.AddWorker("stuck", workerFunc(func(ctx context.Context) error {
+			select {}
+		}))
+		app.AddCloser("database", closerFunc(func() error {
+			closed = true
+			return errors.New("already closed")
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := app.Serve(ctx, listen(t))
+
+		assert.ErrorContains(t, err, "stop worker stuck")
+		assert.ErrorContains(t, err, "close database: already closed")
+		assert.True(t, closed)
+	})
+}
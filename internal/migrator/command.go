@@ -0,0 +1,106 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const Usage = `usage: migrate <command>
+
+commands:
+  up              apply every pending migration
+  down [n]        roll back the last n migrations (default 1)
+  goto <version>  migrate up or down to version, 0 rolls back everything
+  status          list migrations and whether they are applied
+  version         print the current version
+  force <version> mark version as cleanly applied after a manual fix`
+
+var ErrUsage = errors.New(Usage)
+
+// Run executes a migrate command line and writes its output to w.
+func Run(ctx context.Context, m *Migrator, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return ErrUsage
+			}
+			steps = n
+		}
+
+		return m.Down(ctx, steps)
+	case "goto", "force":
+		if len(args) < 2 {
+			return ErrUsage
+		}
+
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return ErrUsage
+		}
+
+		if args[0] == "force" {
+			return m.Force(ctx, version)
+		}
+
+		return m.Goto(ctx, version)
+	case "version":
+		version, dirty, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+
+		if dirty {
+			fmt.Fprintf(w, "%d (dirty)\n", version)
+		} else {
+			fmt.Fprintln(w, version)
+		}
+
+		return nil
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+
+		for _, status := range statuses {
+			state := "pending"
+			appliedAt := ""
+
+			if status.Applied {
+				state = "applied"
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+
+			if status.Dirty {
+				state = "dirty"
+			}
+
+			if status.Modified {
+				state += ", modified"
+			}
+
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+
+		return tw.Flush()
+	default:
+		return ErrUsage
+	}
+}
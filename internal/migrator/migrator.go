@@ -0,0 +1,511 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"waizly/helpers/dialect"
+)
+
+var (
+	ErrDirty            = errors.New("migration left the database dirty, fix it by hand and run force")
+	ErrChecksumMismatch = errors.New("applied migration was edited after it ran")
+	ErrLocked           = errors.New("another instance is running migrations")
+	ErrUnknownVersion   = errors.New("unknown migration version")
+	ErrPreflight        = errors.New("fix these rows before running the migration")
+)
+
+// maxPreflightRows caps how many offending rows a failed check reports.
+const maxPreflightRows = 50
+
+const DefaultLockTimeout = 30 * time.Second
+
+type (
+	Status struct {
+		Version   int64      `json:"version"`
+		Name      string     `json:"name"`
+		Applied   bool       `json:"applied"`
+		Dirty     bool       `json:"dirty"`
+		Modified  bool       `json:"modified"`
+		AppliedAt *time.Time `json:"applied_at"`
+	}
+
+	Migrator struct {
+		db          *sql.DB
+		dialect     dialect.Dialect
+		migrations  []Migration
+		tableName   string
+		lockName    string
+		lockTimeout time.Duration
+	}
+
+	applied struct {
+		version   int64
+		name      string
+		checksum  string
+		dirty     bool
+		appliedAt time.Time
+	}
+)
+
+func New(db *sql.DB, d dialect.Dialect, migrations []Migration, tableName string) *Migrator {
+	return &Migrator{
+		db:          db,
+		dialect:     d,
+		migrations:  migrations,
+		tableName:   tableName,
+		lockName:    "waizly:" + tableName,
+		lockTimeout: DefaultLockTimeout,
+	}
+}
+
+// Latest is the highest embedded version, or 0 when there is none.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down rolls back the last steps applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		target := int64(0)
+		if steps < len(versions) {
+			target = versions[len(versions)-steps-1].version
+		}
+
+		return m.migrate(ctx, conn, versions, target)
+	})
+}
+
+// Goto applies or rolls back migrations until target is the current version.
+// Target 0 rolls everything back.
+func (m *Migrator) Goto(ctx context.Context, target int64) error {
+	if target != 0 {
+		if _, ok := m.find(target); !ok {
+			return fmt.Errorf("%w %d", ErrUnknownVersion, target)
+		}
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		return m.migrate(ctx, conn, versions, target)
+	})
+}
+
+// Version returns the highest applied version and whether it is dirty.
+func (m *Migrator) Version(ctx context.Context) (int64, bool, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	defer conn.Close()
+
+	versions, err := m.applied(ctx, conn)
+	if err != nil || len(versions) == 0 {
+		return 0, false, err
+	}
+
+	last := versions[len(versions)-1]
+
+	return last.version, last.dirty, nil
+}
+
+// Status lists every embedded migration and every applied one that is no
+// longer embedded.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	versions, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Status{}
+	for _, migration := range m.migrations {
+		byVersion[migration.Version] = &Status{Version: migration.Version, Name: migration.Name}
+	}
+
+	for _, version := range versions {
+		status, ok := byVersion[version.version]
+		if !ok {
+			status = &Status{Version: version.version, Name: version.name, Modified: true}
+			byVersion[version.version] = status
+		}
+
+		appliedAt := version.appliedAt
+		status.Applied = true
+		status.Dirty = version.dirty
+		status.AppliedAt = &appliedAt
+
+		if migration, ok := m.find(version.version); ok && migration.Checksum != version.checksum {
+			status.Modified = true
+		}
+	}
+
+	statuses := make([]Status, 0, len(byVersion))
+	for _, status := range byVersion {
+		statuses = append(statuses, *status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// Force marks version as cleanly applied, or clears every record when version
+// is 0, after an operator repaired a failed migration by hand.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	migration, ok := m.find(version)
+	if version != 0 && !ok {
+		return fmt.Errorf("%w %d", ErrUnknownVersion, version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, m.dialect.Rebind(fmt.Sprintf(`DELETE FROM %s WHERE version >= ?`, m.tableName)), version)
+		if err != nil || version == 0 {
+			return err
+		}
+
+		return m.record(ctx, conn, migration, false)
+	})
+}
+
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, versions []applied, target int64) error {
+	for _, version := range versions {
+		if version.dirty {
+			return fmt.Errorf("%w (version %d)", ErrDirty, version.version)
+		}
+
+		migration, ok := m.find(version.version)
+		if !ok {
+			return fmt.Errorf("%w %d is applied but not embedded", ErrUnknownVersion, version.version)
+		}
+
+		if migration.Checksum != version.checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+
+	isApplied := map[int64]bool{}
+	for _, version := range versions {
+		isApplied[version.version] = true
+	}
+
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].version <= target {
+			continue
+		}
+
+		migration, _ := m.find(versions[i].version)
+		if err := m.rollback(ctx, conn, migration); err != nil {
+			return err
+		}
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version > target || isApplied[migration.Version] {
+			continue
+		}
+
+		if err := m.apply(ctx, conn, migration); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply records the version as dirty before running it. MySQL commits DDL
+// implicitly, so a failure half way leaves the flag set for an operator.
+// PostgreSQL runs the script as one implicit transaction, so the flag only
+// survives when the final update fails.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	err := m.preflight(ctx, conn, migration)
+	if err != nil {
+		return err
+	}
+
+	err = m.record(ctx, conn, migration, true)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range m.statements(migration.Up) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	_, err = conn.ExecContext(ctx, m.dialect.Rebind(fmt.Sprintf(`UPDATE %s SET dirty = FALSE WHERE version = ?`, m.tableName)), migration.Version)
+
+	return err
+}
+
+// preflight runs the check query of migration and fails with the rows it
+// returns, before anything is recorded or changed.
+func (m *Migrator) preflight(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if strings.TrimSpace(migration.Check) == "" {
+		return nil
+	}
+
+	rows, err := conn.QueryContext(ctx, migration.Check)
+	if err != nil {
+		return fmt.Errorf("check %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	var found []string
+	count := 0
+	for rows.Next() {
+		count++
+		if count > maxPreflightRows {
+			continue
+		}
+
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return err
+		}
+
+		fields := make([]string, len(columns))
+		for i, column := range columns {
+			fields[i] = fmt.Sprintf("%s=%s", column, formatValue(values[i]))
+		}
+
+		found = append(found, "  "+strings.Join(fields, " "))
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	if count > maxPreflightRows {
+		found = append(found, fmt.Sprintf("  ... and %d more", count-maxPreflightRows))
+	}
+
+	return fmt.Errorf("migration %d_%s: %w:\n%s", migration.Version, migration.Name, ErrPreflight, strings.Join(found, "\n"))
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return strconv.Quote(string(v))
+	case string:
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (m *Migrator) rollback(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	_, err := conn.ExecContext(ctx, m.dialect.Rebind(fmt.Sprintf(`UPDATE %s SET dirty = TRUE WHERE version = ?`, m.tableName)), migration.Version)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range m.statements(migration.Down) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("rollback %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	_, err = conn.ExecContext(ctx, m.dialect.Rebind(fmt.Sprintf(`DELETE FROM %s WHERE version = ?`, m.tableName)), migration.Version)
+
+	return err
+}
+
+func (m *Migrator) record(ctx context.Context, conn *sql.Conn, migration Migration, dirty bool) error {
+	query := fmt.Sprintf(`INSERT INTO %s (version, name, checksum, dirty, applied_at) VALUES (?, ?, ?, ?, ?)`, m.tableName)
+	_, err := conn.ExecContext(ctx, m.dialect.Rebind(query), migration.Version, migration.Name, migration.Checksum, dirty, time.Now().UTC())
+
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) ([]applied, error) {
+	err := m.ensureTable(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT version, name, checksum, dirty, applied_at FROM %s ORDER BY version`, m.tableName))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var versions []applied
+	for rows.Next() {
+		var version applied
+
+		err = rows.Scan(&version.version, &version.name, &version.checksum, &version.dirty, &version.appliedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	dirty, appliedAt := "TINYINT(1)", "DATETIME"
+	if m.dialect == dialect.Postgres {
+		dirty, appliedAt = "BOOLEAN", "TIMESTAMPTZ"
+	}
+
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  version BIGINT NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  checksum CHAR(64) NOT NULL,
+  dirty %s NOT NULL,
+  applied_at %s NOT NULL,
+  PRIMARY KEY (version)
+)`, m.tableName, dirty, appliedAt)
+
+	_, err := conn.ExecContext(ctx, query)
+
+	return err
+}
+
+// statements splits a script for Exec. The PostgreSQL and SQLite drivers run
+// a whole script at once, which keeps function and trigger bodies intact.
+func (m *Migrator) statements(script string) []string {
+	if m.dialect != dialect.MySQL {
+		return []string{script}
+	}
+
+	return SplitStatements(script)
+}
+
+// withLock runs fn on a single connection holding a database advisory lock,
+// so instances starting at the same time apply each migration once.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	switch m.dialect {
+	case dialect.Postgres:
+		return m.withPostgresLock(ctx, conn, fn)
+	case dialect.SQLite:
+		return m.withSQLiteLock(ctx, conn, fn)
+	}
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`, m.lockName, int(m.lockTimeout.Seconds())).Scan(&acquired)
+	if err != nil {
+		return err
+	}
+
+	if acquired.Int64 != 1 {
+		return ErrLocked
+	}
+
+	defer conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?)`, m.lockName)
+
+	return fn(conn)
+}
+
+// withPostgresLock waits up to the lock timeout for a session advisory lock.
+// pg_advisory_lock has no timeout of its own, so the wait is cancelled
+// through the context instead.
+func (m *Migrator) withPostgresLock(ctx context.Context, conn *sql.Conn, fn func(conn *sql.Conn) error) error {
+	lockCtx, cancel := context.WithTimeout(ctx, m.lockTimeout)
+	defer cancel()
+
+	_, err := conn.ExecContext(lockCtx, `SELECT pg_advisory_lock(hashtext($1))`, m.lockName)
+	if err != nil {
+		if ctx.Err() == nil && lockCtx.Err() == context.DeadlineExceeded {
+			return ErrLocked
+		}
+
+		return err
+	}
+
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, m.lockName)
+
+	return fn(conn)
+}
+
+// withSQLiteLock runs fn inside an immediate transaction, which takes the
+// database write lock up front. SQLite DDL is transactional, so a failed
+// migration is rolled back instead of leaving the version dirty.
+func (m *Migrator) withSQLiteLock(ctx context.Context, conn *sql.Conn, fn func(conn *sql.Conn) error) error {
+	_, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`)
+	if err != nil {
+		return err
+	}
+
+	err = fn(conn)
+	if err != nil {
+		conn.ExecContext(context.Background(), `ROLLBACK`)
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, `COMMIT`)
+
+	return err
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+
+	return Migration{}, false
+}
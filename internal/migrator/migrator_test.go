@@ -0,0 +1,278 @@
+package migrator_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+
+	"waizly/db/migration"
+
+	"waizly/helpers/dialect"
+	"waizly/internal/constant"
+	"waizly/internal/migrator"
+	"waizly/internal/mock"
+)
+
+var migrations = []migrator.Migration{
+	{Version: 1, Name: "first", Up: "CREATE TABLE a (id INT);", Down: "DROP TABLE a;", Checksum: "sum-1"},
+	{Version: 2, Name: "second", Up: "CREATE TABLE b (id INT); CREATE TABLE c (id INT);", Down: "DROP TABLE c; DROP TABLE b;", Checksum: "sum-2"},
+}
+
+var appliedColumns = []string{"version", "name", "checksum", "dirty", "applied_at"}
+
+func expectLock(mock sqlmock.Sqlmock, acquired int) {
+	mock.ExpectQuery(`SELECT GET_LOCK\(\?, \?\)`).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(acquired))
+}
+
+func expectApplied(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT version, name, checksum, dirty, applied_at FROM schema_migrations`).WillReturnRows(rows)
+}
+
+func TestUp(t *testing.T) {
+	t.Run("Apply Pending", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		defer db.Close()
+
+		m := migrator.New(db, dialect.MySQL, migrations, constant.TableSchemaMigrations)
+
+		expectLock(mock, 1)
+		expectApplied(mock, sqlmock.NewRows(appliedColumns).AddRow(1, "first", "sum-1", false, time.Now()))
+		mock.ExpectExec(`INSERT INTO schema_migrations`).WithArgs(int64(2), "second", "sum-2", true, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`CREATE TABLE b`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`CREATE TABLE c`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`UPDATE schema_migrations SET dirty = FALSE`).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`SELECT RELEASE_LOCK`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := m.Up(context.TODO())
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Checksum Mismatch", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		defer db.Close()
+
+		m := migrator.New(db, dialect.MySQL, migrations, constant.TableSchemaMigrations)
+
+		expectLock(mock, 1)
+		expectApplied(mock, sqlmock.NewRows(appliedColumns).AddRow(1, "first", "edited", false, time.Now()))
+		mock.ExpectExec(`SELECT RELEASE_LOCK`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := m.Up(context.TODO())
+
+		assert.ErrorIs(t, err, migrator.ErrChecksumMismatch)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Dirty Database", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		defer db.Close()
+
+		m := migrator.New(db, dialect.MySQL, migrations, constant.TableSchemaMigrations)
+
+		expectLock(mock, 1)
+		expectApplied(mock, sqlmock.NewRows(appliedColumns).AddRow(1, "first", "sum-1", true, time.Now()))
+		mock.ExpectExec(`SELECT RELEASE_LOCK`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := m.Up(context.TODO())
+
+		assert.ErrorIs(t, err, migrator.ErrDirty)
+	})
+
+	t.Run("Lock Held Elsewhere", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		defer db.Close()
+
+		m := migrator.New(db, dialect.MySQL, migrations, constant.TableSchemaMigrations)
+
+		expectLock(mock, 0)
+
+		err := m.Up(context.TODO())
+
+		assert.ErrorIs(t, err, migrator.ErrLocked)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Apply Pending Postgres", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		defer db.Close()
+
+		m := migrator.New(db, dialect.Postgres, migrations, constant.TableSchemaMigrations)
+
+		mock.ExpectExec(`SELECT pg_advisory_lock\(hashtext\(\$1\)\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations (?s:.*)dirty BOOLEAN`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT version, name, checksum, dirty, applied_at FROM schema_migrations`).WillReturnRows(sqlmock.NewRows(appliedColumns).AddRow(1, "first", "sum-1", false, time.Now()))
+		mock.ExpectExec(`INSERT INTO schema_migrations .* VALUES \(\$1, \$2, \$3, \$4, \$5\)`).WithArgs(int64(2), "second", "sum-2", true, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`CREATE TABLE b \(id INT\); CREATE TABLE c \(id INT\);`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`UPDATE schema_migrations SET dirty = FALSE WHERE version = \$1`).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := m.Up(context.TODO())
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDown(t *testing.T) {
+	t.Run("Roll Back Last", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		defer db.Close()
+
+		m := migrator.New(db, dialect.MySQL, migrations, constant.TableSchemaMigrations)
+
+		expectLock(mock, 1)
+		expectApplied(mock, sqlmock.NewRows(appliedColumns).
+			AddRow(1, "first", "sum-1", false, time.Now()).
+			AddRow(2, "second", "sum-2", false, time.Now()))
+		mock.ExpectExec(`UPDATE schema_migrations SET dirty = TRUE`).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DROP TABLE c`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`DROP TABLE b`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`DELETE FROM schema_migrations WHERE version = \?`).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`SELECT RELEASE_LOCK`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := m.Down(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGoto(t *testing.T) {
+	t.Run("Unknown Version", func(t *testing.T) {
+		db, _ := mock.NewMock()
+		defer db.Close()
+
+		m := migrator.New(db, dialect.MySQL, migrations, constant.TableSchemaMigrations)
+
+		err := m.Goto(context.TODO(), 42)
+
+		assert.ErrorIs(t, err, migrator.ErrUnknownVersion)
+	})
+}
+
+func TestStatusCommand(t *testing.T) {
+	t.Run("Print Status", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		defer db.Close()
+
+		m := migrator.New(db, dialect.MySQL, migrations, constant.TableSchemaMigrations)
+
+		expectApplied(mock, sqlmock.NewRows(appliedColumns).AddRow(1, "first", "edited", false, time.Now()))
+
+		var out bytes.Buffer
+		err := migrator.Run(context.TODO(), m, []string{"status"}, &out)
+
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "applied, modified")
+		assert.Contains(t, out.String(), "pending")
+	})
+
+	t.Run("Unknown Command", func(t *testing.T) {
+		db, _ := mock.NewMock()
+		defer db.Close()
+
+		m := migrator.New(db, dialect.MySQL, migrations, constant.TableSchemaMigrations)
+
+		err := migrator.Run(context.TODO(), m, []string{"sideways"}, &bytes.Buffer{})
+
+		assert.ErrorIs(t, err, migrator.ErrUsage)
+	})
+}
+
+// TestSQLiteRoundTrip applies and rolls back the embedded SQLite migrations
+// against a real database, which also proves trigger bodies survive intact.
+func TestSQLiteRoundTrip(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "waizly.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	embedded, err := migrator.Load(migration.For(dialect.SQLite))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := migrator.New(db, dialect.SQLite, embedded, constant.TableSchemaMigrations)
+	ctx := context.TODO()
+
+	assert.NoError(t, m.Up(ctx))
+
+	version, dirty, err := m.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, m.Latest(), version)
+	assert.False(t, dirty)
+
+	_, err = db.Exec(`INSERT INTO audit_log (action, outcome, occurred_at, prev_hash, hash) VALUES ('a', 'b', '2021-12-12', '', '')`)
+	assert.NoError(t, err)
+
+	_, err = db.Exec(`DELETE FROM audit_log`)
+	assert.ErrorContains(t, err, "append-only")
+
+	assert.NoError(t, m.Goto(ctx, 0))
+
+	version, _, err = m.Version(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, version)
+
+	assert.NoError(t, m.Up(ctx))
+}
+
+func TestSQLitePreflight(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "waizly.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	embedded, err := migrator.Load(migration.For(dialect.SQLite))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := migrator.New(db, dialect.SQLite, embedded, constant.TableSchemaMigrations)
+	ctx := context.TODO()
+
+	assert.NoError(t, m.Goto(ctx, 5))
+
+	_, err = db.Exec(`INSERT INTO account (username, password, email) VALUES
+		('jane', 'x', 'jane@test.com'),
+		('john', 'x', 'JANE@test.com'),
+		(NULL, 'x', 'nobody@test.com'),
+		('joe', 'x', 'joe@test.com')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Up(ctx)
+
+	assert.ErrorIs(t, err, migrator.ErrPreflight)
+	assert.ErrorContains(t, err, `id=1 username="jane" email="jane@test.com" problem="duplicate email"`)
+	assert.ErrorContains(t, err, `id=2 username="john" email="JANE@test.com" problem="duplicate email"`)
+	assert.ErrorContains(t, err, `id=3 username=NULL email="nobody@test.com" problem="username is NULL"`)
+	assert.NotContains(t, err.Error(), "joe")
+
+	version, dirty, err := m.Version(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), version)
+	assert.False(t, dirty, "a failed check changes nothing")
+
+	_, err = db.Exec(`UPDATE account SET email = 'john@test.com' WHERE id = 2`)
+	assert.NoError(t, err)
+	_, err = db.Exec(`UPDATE account SET username = 'nobody' WHERE id = 3`)
+	assert.NoError(t, err)
+
+	assert.NoError(t, m.Up(ctx))
+}
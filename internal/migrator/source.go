@@ -0,0 +1,146 @@
+package migrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migration is one version read from a golang-migrate style pair of files:
+// <version>_<name>.up.sql and <version>_<name>.down.sql. An optional
+// <version>_<name>.check.sql holds a query for the rows that would make the
+// up script fail. The checksum covers all three files.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Check    string
+	Checksum string
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down|check)\.sql$`)
+
+// Load reads every migration in the root of fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files named %q and %q", version, migration.Name, match[2])
+		}
+
+		switch match[3] {
+		case "up":
+			migration.Up = string(content)
+		case "down":
+			migration.Down = string(content)
+		default:
+			migration.Check = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+
+		migration.Checksum = checksum(migration.Up, migration.Down, migration.Check)
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// checksum hashes the scripts of one migration, each followed by a NUL so
+// text moved from one file to the next still changes the sum.
+func checksum(scripts ...string) string {
+	hash := sha256.New()
+	for _, script := range scripts {
+		hash.Write([]byte(script))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// SplitStatements splits a script on semicolons that end a statement,
+// ignoring those inside quotes and comments, because the MySQL driver runs
+// one statement per Exec unless multiStatements is enabled.
+func SplitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	var quote byte
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+
+		switch {
+		case quote != 0:
+			current.WriteByte(c)
+			if c == '\\' && quote != '`' && i+1 < len(script) {
+				i++
+				current.WriteByte(script[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			current.WriteByte(c)
+		case c == '-' && strings.HasPrefix(script[i:], "-- "), c == '#':
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			current.WriteByte('\n')
+		case c == ';':
+			if statement := strings.TrimSpace(current.String()); statement != "" {
+				statements = append(statements, statement)
+			}
+			current.Reset()
+		default:
+			current.WriteByte(c)
+		}
+	}
+
+	if statement := strings.TrimSpace(current.String()); statement != "" {
+		statements = append(statements, statement)
+	}
+
+	return statements
+}
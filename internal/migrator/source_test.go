@@ -0,0 +1,127 @@
+package migrator_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"waizly/db/migration"
+	"waizly/helpers/dialect"
+	"waizly/internal/migrator"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("Load Sorted Pairs", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"000002_second.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+			"000002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+			"000001_first.up.sql":    {Data: []byte("CREATE TABLE a (id INT);")},
+			"000001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+			"README.md":              {Data: []byte("ignored")},
+		}
+
+		migrations, err := migrator.Load(fsys)
+
+		assert.NoError(t, err)
+		assert.Len(t, migrations, 2)
+		assert.Equal(t, int64(1), migrations[0].Version)
+		assert.Equal(t, "first", migrations[0].Name)
+		assert.Equal(t, "DROP TABLE a;", migrations[0].Down)
+		assert.Len(t, migrations[0].Checksum, 64)
+	})
+
+	t.Run("Check File", func(t *testing.T) {
+		up := fstest.MapFS{
+			"000001_first.up.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+		}
+		withCheck := fstest.MapFS{
+			"000001_first.up.sql":    {Data: []byte("CREATE TABLE a (id INT);")},
+			"000001_first.check.sql": {Data: []byte("SELECT 1")},
+		}
+
+		plain, err := migrator.Load(up)
+		assert.NoError(t, err)
+
+		checked, err := migrator.Load(withCheck)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "SELECT 1", checked[0].Check)
+		assert.NotEqual(t, plain[0].Checksum, checked[0].Checksum, "the check is part of the checksum")
+	})
+
+	t.Run("Down File Changes Checksum", func(t *testing.T) {
+		before, err := migrator.Load(fstest.MapFS{
+			"000001_first.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+			"000001_first.down.sql": {Data: []byte("DROP TABLE a;")},
+		})
+		assert.NoError(t, err)
+
+		after, err := migrator.Load(fstest.MapFS{
+			"000001_first.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+			"000001_first.down.sql": {Data: []byte("DROP TABLE IF EXISTS a;")},
+		})
+		assert.NoError(t, err)
+
+		assert.NotEqual(t, before[0].Checksum, after[0].Checksum)
+	})
+
+	t.Run("Missing Up File", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"000001_first.down.sql": {Data: []byte("DROP TABLE a;")},
+		}
+
+		_, err := migrator.Load(fsys)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Embedded Migrations", func(t *testing.T) {
+		migrations, err := migrator.Load(migration.FS)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, migrations)
+
+		for _, m := range migrations {
+			assert.NotEmpty(t, m.Down, "migration %d_%s should have a down file", m.Version, m.Name)
+		}
+	})
+
+	t.Run("Embedded Dialect Migrations Match", func(t *testing.T) {
+		mysql, err := migrator.Load(migration.For(dialect.MySQL))
+		assert.NoError(t, err)
+
+		for _, d := range []dialect.Dialect{dialect.Postgres, dialect.SQLite} {
+			migrations, err := migrator.Load(migration.For(d))
+			assert.NoError(t, err)
+
+			if !assert.Len(t, migrations, len(mysql), "%s migrations", d) {
+				continue
+			}
+
+			for i, m := range migrations {
+				assert.Equal(t, mysql[i].Version, m.Version)
+				assert.Equal(t, mysql[i].Name, m.Name)
+				assert.NotEmpty(t, m.Down, "%s migration %d_%s should have a down file", d, m.Version, m.Name)
+			}
+		}
+	})
+}
+
+func TestSplitStatements(t *testing.T) {
+	t.Run("Split On Terminators Only", func(t *testing.T) {
+		script := `-- create things; carefully
+CREATE TABLE a (note VARCHAR(10) DEFAULT 'x;y');
+
+CREATE TRIGGER t BEFORE DELETE ON a
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'it''s; append-only';
+INSERT INTO a VALUES ("\";")`
+
+		statements := migrator.SplitStatements(script)
+
+		assert.Len(t, statements, 3)
+		assert.Equal(t, "CREATE TABLE a (note VARCHAR(10) DEFAULT 'x;y')", statements[0])
+		assert.Contains(t, statements[1], "'it''s; append-only'")
+		assert.Equal(t, `INSERT INTO a VALUES ("\";")`, statements[2])
+	})
+}
@@ -0,0 +1,38 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"waizly/models"
+)
+
+const (
+	EventAccountRegistered = "AccountRegistered"
+	EventAccountUpdated    = "AccountUpdated"
+	EventAccountDeleted    = "AccountDeleted"
+)
+
+func NewEvent(eventType string, aggregateID int64, payload interface{}) (models.Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	return models.Event{
+		Type:        eventType,
+		AggregateID: aggregateID,
+		Payload:     data,
+		OccurredAt:  time.Now().UTC().Truncate(time.Microsecond),
+	}, nil
+}
+
+func NewAccountEvent(eventType string, account models.Account) (models.Event, error) {
+	return NewEvent(eventType, account.ID, models.AccountPayload{
+		ID:        account.ID,
+		Username:  account.Username,
+		Email:     account.Email,
+		CreatedAt: account.CreatedAt,
+		UpdateAt:  account.UpdateAt,
+	})
+}
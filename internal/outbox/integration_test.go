@@ -0,0 +1,166 @@
+package outbox_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+
+	"waizly/db/migration"
+	"waizly/helpers/dialect"
+	"waizly/helpers/transaction"
+	"waizly/internal/constant"
+	"waizly/internal/migrator"
+	"waizly/internal/outbox"
+	"waizly/models"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	path := filepath.Join(t.TempDir(), "waizly.db")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_time_format=sqlite&_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := migrator.Load(migration.For(dialect.SQLite))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrator.New(db, dialect.SQLite, migrations, constant.TableSchemaMigrations).Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// failingSink rejects the events of one account.
+type failingSink struct {
+	aggregateID int64
+	published   []int64
+}
+
+func (s *failingSink) Publish(ctx context.Context, event models.Event) error {
+	if event.AggregateID == s.aggregateID {
+		return fmt.Errorf("sink down")
+	}
+
+	s.published = append(s.published, event.ID)
+
+	return nil
+}
+
+// flakySink rejects every event until it is fixed and records the order in
+// which it accepted them.
+type flakySink struct {
+	fixed     bool
+	published []int64
+}
+
+func (s *flakySink) Publish(ctx context.Context, event models.Event) error {
+	if !s.fixed {
+		return fmt.Errorf("sink down")
+	}
+
+	s.published = append(s.published, event.ID)
+
+	return nil
+}
+
+func TestRelayOrderSQLite(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	repo := outbox.NewOutboxRepository(db, dialect.SQLite, constant.TableOutbox)
+
+	err := repo.Save(ctx, models.Event{Type: outbox.EventAccountRegistered, AggregateID: 1, Payload: []byte(`{}`), OccurredAt: time.Now().UTC()})
+	assert.NoError(t, err)
+
+	sink := &flakySink{}
+	policy := outbox.Policy{MaxAttempts: 5, BackoffBase: time.Hour, BackoffMax: time.Hour, Lease: time.Minute}
+	first := outbox.NewRelay(repo, transaction.NewTransactor(db), []outbox.Sink{sink}, policy, time.Second, 10)
+	second := outbox.NewRelay(repo, transaction.NewTransactor(db), []outbox.Sink{sink}, policy, time.Second, 10)
+
+	_, err = first.Process(ctx)
+	assert.NoError(t, err)
+
+	// The account changes again while its first event backs off.
+	err = repo.Save(ctx, models.Event{Type: outbox.EventAccountUpdated, AggregateID: 1, Payload: []byte(`{}`), OccurredAt: time.Now().UTC()})
+	assert.NoError(t, err)
+
+	sink.fixed = true
+
+	// Neither a later poll nor another relay publishes the second event
+	// ahead of the first.
+	for _, relay := range []*outbox.Relay{first, second} {
+		published, err := relay.Process(ctx)
+		assert.NoError(t, err)
+		assert.Zero(t, published)
+	}
+
+	_, err = db.Exec(`UPDATE outbox SET next_attempt_at = NULL WHERE id = 1`)
+	assert.NoError(t, err)
+
+	for _, relay := range []*outbox.Relay{second, first} {
+		published, err := relay.Process(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, published)
+	}
+
+	assert.Equal(t, []int64{1, 2}, sink.published)
+}
+
+func TestRelaySQLite(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	repo := outbox.NewOutboxRepository(db, dialect.SQLite, constant.TableOutbox)
+
+	for _, aggregateID := range []int64{1, 1, 2} {
+		err := repo.Save(ctx, models.Event{Type: outbox.EventAccountRegistered, AggregateID: aggregateID, Payload: []byte(`{}`), OccurredAt: time.Now().UTC()})
+		assert.NoError(t, err)
+	}
+
+	sink := &failingSink{aggregateID: 1}
+	relay := outbox.NewRelay(repo, transaction.NewTransactor(db), []outbox.Sink{sink},
+		outbox.Policy{MaxAttempts: 2, BackoffBase: time.Millisecond, BackoffMax: time.Millisecond, Lease: time.Minute}, time.Second, 10)
+
+	published, err := relay.Process(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []int64{3}, sink.published, "a failing event does not hold up other accounts")
+
+	time.Sleep(5 * time.Millisecond)
+
+	// The second failure dead-letters the first event, which releases the
+	// next event of the same account for the following poll.
+	_, err = relay.Process(ctx)
+	assert.NoError(t, err)
+
+	var attempts int
+	var lastError string
+	var deadAt sql.NullTime
+	err = db.QueryRow(`SELECT attempts, last_error, dead_at FROM outbox WHERE id = 1`).Scan(&attempts, &lastError, &deadAt)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, "sink down", lastError)
+	assert.True(t, deadAt.Valid)
+
+	err = db.QueryRow(`SELECT attempts, dead_at FROM outbox WHERE id = 2`).Scan(&attempts, &deadAt)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, attempts, "the next event waited for the first one")
+	assert.False(t, deadAt.Valid)
+
+	pending, err := repo.FetchPending(ctx, time.Now().UTC().Add(time.Hour), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 1, "published and dead events are not fetched again") {
+		assert.Equal(t, int64(2), pending[0].ID)
+	}
+}
@@ -0,0 +1,166 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"waizly/helpers/logger"
+	"waizly/helpers/transaction"
+	"waizly/models"
+)
+
+type (
+	// Policy controls how often the relay retries an event and how long a
+	// claimed batch stays hidden from other relays.
+	Policy struct {
+		MaxAttempts int
+		BackoffBase time.Duration
+		BackoffMax  time.Duration
+		Lease       time.Duration
+	}
+
+	Relay struct {
+		repository OutboxRepository
+		transactor transaction.Transactor
+		sinks      []Sink
+		policy     Policy
+		interval   time.Duration
+		batchSize  int
+		now        func() time.Time
+	}
+)
+
+func NewRelay(repo OutboxRepository, transactor transaction.Transactor, sinks []Sink, policy Policy, interval time.Duration, batchSize int) *Relay {
+	return &Relay{
+		repository: repo,
+		transactor: transactor,
+		sinks:      sinks,
+		policy:     policy,
+		interval:   interval,
+		batchSize:  batchSize,
+		now:        time.Now,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			published, err := r.Process(ctx)
+			if err != nil {
+				logger.Ctx(ctx).Error("process outbox", "error", err)
+			}
+
+			if err != nil || published < r.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process publishes one batch of due events to every sink and returns how
+// many were marked as published. The batch is claimed in a short transaction
+// and published outside it, so slow sinks hold neither row locks nor a
+// connection. An event is only marked once all sinks accepted it. A failed
+// event is retried with backoff until Policy.MaxAttempts, then dead-lettered;
+// the rest of the batch carries on. FetchPending holds back later events of
+// the same account until the failed one is published or dead, so they keep
+// their order across polls and relays.
+func (r *Relay) Process(ctx context.Context) (int, error) {
+	now := r.now().UTC()
+	leasedUntil := now.Add(r.policy.Lease)
+
+	var events []models.Event
+	err := r.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		pending, err := r.repository.FetchPending(ctx, now, r.batchSize)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]int64, len(pending))
+		for i, event := range pending {
+			ids[i] = event.ID
+		}
+
+		events = pending
+
+		return r.repository.Lease(ctx, ids, leasedUntil)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+
+	for i, event := range events {
+		// Past the lease another relay may claim the rest of the batch.
+		if !r.now().Before(leasedUntil) {
+			logger.Ctx(ctx).Warn("outbox lease expired", "remaining", len(events)-i)
+			break
+		}
+
+		err := r.publish(ctx, event)
+		if err == nil {
+			if err := r.repository.MarkPublished(ctx, event.ID); err != nil {
+				return published, err
+			}
+
+			published++
+			continue
+		}
+
+		attempts := event.Attempts + 1
+		if attempts >= r.policy.MaxAttempts {
+			logger.Ctx(ctx).Error("outbox event dead-lettered", "event_id", event.ID, "type", event.Type, "attempts", attempts, "error", err)
+
+			if err := r.repository.MarkDead(ctx, event.ID, err.Error()); err != nil {
+				return published, err
+			}
+
+			continue
+		}
+
+		retryAt := r.now().UTC().Add(r.policy.Backoff(attempts))
+		logger.Ctx(ctx).Warn("publish outbox event", "event_id", event.ID, "type", event.Type, "attempts", attempts, "retry_at", retryAt.Format(time.RFC3339), "error", err)
+
+		if err := r.repository.MarkFailed(ctx, event.ID, err.Error(), retryAt); err != nil {
+			return published, err
+		}
+	}
+
+	return published, nil
+}
+
+func (r *Relay) publish(ctx context.Context, event models.Event) error {
+	for _, sink := range r.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Backoff doubles the wait after every failed attempt, capped at BackoffMax.
+func (p Policy) Backoff(attempts int) time.Duration {
+	wait := p.BackoffBase
+	for i := 1; i < attempts && wait < p.BackoffMax; i++ {
+		wait *= 2
+	}
+
+	if wait > p.BackoffMax {
+		wait = p.BackoffMax
+	}
+
+	return wait
+}
@@ -0,0 +1,148 @@
+package outbox_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	transactionmocks "waizly/helpers/transaction/mocks"
+	"waizly/internal/outbox"
+	"waizly/internal/outbox/mocks"
+	"waizly/models"
+)
+
+var policy = outbox.Policy{MaxAttempts: 3, BackoffBase: time.Second, BackoffMax: time.Minute, Lease: time.Minute}
+
+// newTransactor runs fn and reports through committed whether it returned.
+func newTransactor(committed *bool) *transactionmocks.Transactor {
+	transactor := new(transactionmocks.Transactor)
+	transactor.On("WithinTransaction", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		err := fn(ctx)
+		if committed != nil {
+			*committed = err == nil
+		}
+
+		return err
+	})
+
+	return transactor
+}
+
+func TestProcess(t *testing.T) {
+	events := []models.Event{
+		{ID: 1, Type: outbox.EventAccountRegistered, AggregateID: 1},
+		{ID: 2, Type: outbox.EventAccountUpdated, AggregateID: 2},
+		{ID: 3, Type: outbox.EventAccountRegistered, AggregateID: 3},
+	}
+
+	t.Run("Publish All", func(t *testing.T) {
+		outboxRepository := new(mocks.OutboxRepository)
+		sink := new(mocks.Sink)
+		committed := false
+
+		outboxRepository.On("FetchPending", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return(events[:2], nil)
+		outboxRepository.On("Lease", mock.Anything, []int64{1, 2}, mock.AnythingOfType("time.Time")).Return(nil)
+		outboxRepository.On("MarkPublished", mock.Anything, int64(1)).Return(nil)
+		outboxRepository.On("MarkPublished", mock.Anything, int64(2)).Return(nil)
+		sink.On("Publish", mock.Anything, mock.AnythingOfType("models.Event")).Return(nil).Run(func(args mock.Arguments) {
+			assert.True(t, committed, "events are published after the claim committed")
+		}).Twice()
+
+		relay := outbox.NewRelay(outboxRepository, newTransactor(&committed), []outbox.Sink{sink}, policy, 0, 10)
+
+		published, err := relay.Process(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, published)
+		outboxRepository.AssertExpectations(t)
+		sink.AssertExpectations(t)
+	})
+
+	t.Run("Failure Skips Ahead", func(t *testing.T) {
+		outboxRepository := new(mocks.OutboxRepository)
+		sink := new(mocks.Sink)
+
+		outboxRepository.On("FetchPending", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return(events, nil)
+		outboxRepository.On("Lease", mock.Anything, []int64{1, 2, 3}, mock.AnythingOfType("time.Time")).Return(nil)
+		outboxRepository.On("MarkFailed", mock.Anything, int64(1), "sink down", mock.AnythingOfType("time.Time")).Return(nil)
+		outboxRepository.On("MarkPublished", mock.Anything, int64(2)).Return(nil)
+		outboxRepository.On("MarkPublished", mock.Anything, int64(3)).Return(nil)
+		sink.On("Publish", mock.Anything, events[0]).Return(fmt.Errorf("sink down")).Once()
+		sink.On("Publish", mock.Anything, events[1]).Return(nil).Once()
+		sink.On("Publish", mock.Anything, events[2]).Return(nil).Once()
+
+		relay := outbox.NewRelay(outboxRepository, newTransactor(nil), []outbox.Sink{sink}, policy, 0, 10)
+
+		published, err := relay.Process(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, published)
+		outboxRepository.AssertExpectations(t)
+		sink.AssertExpectations(t)
+	})
+
+	t.Run("Dead Letter", func(t *testing.T) {
+		outboxRepository := new(mocks.OutboxRepository)
+		sink := new(mocks.Sink)
+
+		exhausted := events[0]
+		exhausted.Attempts = 2
+
+		outboxRepository.On("FetchPending", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]models.Event{exhausted, events[1]}, nil)
+		outboxRepository.On("Lease", mock.Anything, []int64{1, 2}, mock.AnythingOfType("time.Time")).Return(nil)
+		outboxRepository.On("MarkDead", mock.Anything, int64(1), "sink down").Return(nil)
+		outboxRepository.On("MarkPublished", mock.Anything, int64(2)).Return(nil)
+		sink.On("Publish", mock.Anything, exhausted).Return(fmt.Errorf("sink down")).Once()
+		sink.On("Publish", mock.Anything, events[1]).Return(nil).Once()
+
+		relay := outbox.NewRelay(outboxRepository, newTransactor(nil), []outbox.Sink{sink}, policy, 0, 10)
+
+		published, err := relay.Process(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, published)
+		outboxRepository.AssertExpectations(t)
+		sink.AssertExpectations(t)
+	})
+
+	t.Run("Lease Expired", func(t *testing.T) {
+		outboxRepository := new(mocks.OutboxRepository)
+		sink := new(mocks.Sink)
+
+		outboxRepository.On("FetchPending", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return(events, nil)
+		outboxRepository.On("Lease", mock.Anything, []int64{1, 2, 3}, mock.AnythingOfType("time.Time")).Return(nil)
+
+		expiring := policy
+		expiring.Lease = time.Nanosecond
+		relay := outbox.NewRelay(outboxRepository, newTransactor(nil), []outbox.Sink{sink}, expiring, 0, 10)
+
+		published, err := relay.Process(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, published)
+		sink.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Fetch Error", func(t *testing.T) {
+		outboxRepository := new(mocks.OutboxRepository)
+
+		outboxRepository.On("FetchPending", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return(nil, fmt.Errorf("db down"))
+
+		relay := outbox.NewRelay(outboxRepository, newTransactor(nil), nil, policy, 0, 10)
+
+		_, err := relay.Process(context.TODO())
+
+		assert.Error(t, err)
+		outboxRepository.AssertExpectations(t)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, policy.Backoff(1))
+	assert.Equal(t, 4*time.Second, policy.Backoff(3))
+	assert.Equal(t, time.Minute, policy.Backoff(20))
+}
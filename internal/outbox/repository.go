@@ -0,0 +1,222 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"waizly/helpers/dialect"
+	"waizly/helpers/exception"
+	"waizly/helpers/logger"
+	"waizly/helpers/transaction"
+	"waizly/models"
+)
+
+type (
+	OutboxRepository interface {
+		Save(ctx context.Context, event models.Event) error
+		FetchPending(ctx context.Context, now time.Time, limit int) ([]models.Event, error)
+		Lease(ctx context.Context, ids []int64, until time.Time) error
+		MarkPublished(ctx context.Context, id int64) error
+		MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error
+		MarkDead(ctx context.Context, id int64, reason string) error
+	}
+
+	outboxRepositoryImpl struct {
+		db        *sql.DB
+		dialect   dialect.Dialect
+		tableName string
+	}
+)
+
+func NewOutboxRepository(db *sql.DB, d dialect.Dialect, tableName string) OutboxRepository {
+	return &outboxRepositoryImpl{
+		db:        db,
+		dialect:   d,
+		tableName: tableName,
+	}
+}
+
+// Save joins the transaction carried by ctx, so the event is committed
+// together with the write that produced it.
+func (or *outboxRepositoryImpl) Save(ctx context.Context, event models.Event) error {
+	query := fmt.Sprintf(`INSERT INTO %s (event_type, aggregate_id, payload, occurred_at) VALUES (?, ?, ?, ?)`, or.tableName)
+	stmt, err := transaction.Conn(ctx, or.db).PrepareContext(ctx, or.dialect.Rebind(query))
+	if err != nil {
+		logger.Ctx(ctx).Error("OutboxRepository.Save", "error", err)
+		return exception.Database("OutboxRepository.Save", err)
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(
+		ctx,
+		event.Type,
+		event.AggregateID,
+		[]byte(event.Payload),
+		event.OccurredAt,
+	)
+
+	if err != nil {
+		logger.Ctx(ctx).Error("OutboxRepository.Save", "error", err)
+		return exception.Database("OutboxRepository.Save", err)
+	}
+
+	return nil
+}
+
+// maxLastError is the width of the last_error column.
+const maxLastError = 1024
+
+// FetchPending locks the oldest events that are neither published nor dead
+// and whose next attempt is due at now. An event is left out while an older
+// event of the same account is still unpublished and not dead, so a batch
+// holds at most one event per account and they go out in order, whichever
+// relay picks them up. SKIP LOCKED lets several relays share the table
+// without handing the same event to two of them; the locks are held until the
+// surrounding transaction ends, which should only last until Lease has
+// claimed the events.
+func (or *outboxRepositoryImpl) FetchPending(ctx context.Context, now time.Time, limit int) ([]models.Event, error) {
+	query := fmt.Sprintf(`SELECT o.id, o.event_type, o.aggregate_id, o.payload, o.occurred_at, o.attempts FROM %[1]s o WHERE o.published_at IS NULL AND o.dead_at IS NULL AND (o.next_attempt_at IS NULL OR o.next_attempt_at <= ?) AND NOT EXISTS (SELECT 1 FROM %[1]s p WHERE p.aggregate_id = o.aggregate_id AND p.id < o.id AND p.published_at IS NULL AND p.dead_at IS NULL) ORDER BY o.id LIMIT ? FOR UPDATE SKIP LOCKED`, or.tableName)
+	stmt, err := transaction.Conn(ctx, or.db).PrepareContext(ctx, or.dialect.Rebind(or.dialect.Lock(query)))
+	if err != nil {
+		logger.Ctx(ctx).Error("OutboxRepository.FetchPending", "error", err)
+		return nil, exception.Database("OutboxRepository.FetchPending", err)
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, now, limit)
+	if err != nil {
+		logger.Ctx(ctx).Error("OutboxRepository.FetchPending", "error", err)
+		return nil, exception.Database("OutboxRepository.FetchPending", err)
+	}
+
+	defer rows.Close()
+
+	events := []models.Event{}
+	for rows.Next() {
+		event := models.Event{}
+		var payload []byte
+
+		err = rows.Scan(
+			&event.ID,
+			&event.Type,
+			&event.AggregateID,
+			&payload,
+			&event.OccurredAt,
+			&event.Attempts,
+		)
+
+		if err != nil {
+			logger.Ctx(ctx).Error("OutboxRepository.FetchPending", "error", err)
+			return nil, exception.Database("OutboxRepository.FetchPending", err)
+		}
+
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Ctx(ctx).Error("OutboxRepository.FetchPending", "error", err)
+		return nil, exception.Database("OutboxRepository.FetchPending", err)
+	}
+
+	return events, nil
+}
+
+// Lease hides events from FetchPending until until, so a relay can publish
+// them outside the transaction that fetched them. Events a relay claimed but
+// never finished, because it crashed, come back once the lease runs out.
+func (or *outboxRepositoryImpl) Lease(ctx context.Context, ids []int64, until time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := []interface{}{until}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	query := fmt.Sprintf(`UPDATE %s SET next_attempt_at = ? WHERE id IN (%s)`, or.tableName, placeholders)
+
+	return or.exec(ctx, "OutboxRepository.Lease", query, args...)
+}
+
+func (or *outboxRepositoryImpl) MarkPublished(ctx context.Context, id int64) error {
+	query := fmt.Sprintf(`UPDATE %s SET published_at = ?, attempts = attempts + 1, last_error = NULL WHERE id = ?`, or.tableName)
+	stmt, err := transaction.Conn(ctx, or.db).PrepareContext(ctx, or.dialect.Rebind(query))
+	if err != nil {
+		logger.Ctx(ctx).Error("OutboxRepository.MarkPublished", "error", err)
+		return exception.Database("OutboxRepository.MarkPublished", err)
+	}
+
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, time.Now().UTC(), id)
+	if err != nil {
+		logger.Ctx(ctx).Error("OutboxRepository.MarkPublished", "error", err)
+		return exception.Database("OutboxRepository.MarkPublished", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+
+	if rowsAffected < 1 {
+		return exception.Wrap("OutboxRepository.MarkPublished", exception.ErrNotFound, nil)
+	}
+
+	return nil
+}
+
+// MarkFailed counts a failed attempt and schedules the next one at retryAt.
+func (or *outboxRepositoryImpl) MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	query := fmt.Sprintf(`UPDATE %s SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?`, or.tableName)
+
+	return or.exec(ctx, "OutboxRepository.MarkFailed", query, truncate(reason, maxLastError), retryAt, id)
+}
+
+// MarkDead counts a failed attempt and gives up on the event. It stays in
+// the table, with dead_at set, for an operator to inspect and requeue.
+func (or *outboxRepositoryImpl) MarkDead(ctx context.Context, id int64, reason string) error {
+	query := fmt.Sprintf(`UPDATE %s SET attempts = attempts + 1, last_error = ?, dead_at = ? WHERE id = ?`, or.tableName)
+
+	return or.exec(ctx, "OutboxRepository.MarkDead", query, truncate(reason, maxLastError), time.Now().UTC(), id)
+}
+
+func (or *outboxRepositoryImpl) exec(ctx context.Context, op, query string, args ...interface{}) error {
+	stmt, err := transaction.Conn(ctx, or.db).PrepareContext(ctx, or.dialect.Rebind(query))
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return exception.Database(op, err)
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, args...)
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return exception.Database(op, err)
+	}
+
+	return nil
+}
+
+// truncate keeps the first n characters of s.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	count := 0
+	for i := range s {
+		if count == n {
+			return s[:i]
+		}
+		count++
+	}
+
+	return s
+}
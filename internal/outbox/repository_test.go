@@ -0,0 +1,210 @@
+package outbox_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/dialect"
+	"waizly/helpers/exception"
+	"waizly/internal/constant"
+	"waizly/internal/mock"
+	"waizly/internal/outbox"
+	"waizly/models"
+)
+
+var occurredAt = time.Date(2021, 12, 12, 0, 0, 0, 0, time.UTC)
+
+func TestSave(t *testing.T) {
+	t.Run("Save Success", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := outbox.NewOutboxRepository(db, dialect.MySQL, constant.TableOutbox)
+
+		defer db.Close()
+
+		event, err := outbox.NewAccountEvent(outbox.EventAccountRegistered, models.Account{ID: 1, Email: "email@test.com"})
+		assert.NoError(t, err)
+
+		query := fmt.Sprintf(`INSERT INTO %s`, constant.TableOutbox)
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(event.Type, event.AggregateID, []byte(event.Payload), event.OccurredAt).WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err = repo.Save(context.TODO(), event)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Save Error", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := outbox.NewOutboxRepository(db, dialect.MySQL, constant.TableOutbox)
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`INSERT INTO %s`, constant.TableOutbox)
+		mock.ExpectPrepare(query).ExpectExec().WillReturnError(fmt.Errorf("insert failed"))
+
+		err := repo.Save(context.TODO(), models.Event{})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Save Deadlock Is Retryable", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := outbox.NewOutboxRepository(db, dialect.MySQL, constant.TableOutbox)
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`INSERT INTO %s`, constant.TableOutbox)
+		mock.ExpectPrepare(query).ExpectExec().WillReturnError(&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"})
+
+		err := repo.Save(context.TODO(), models.Event{})
+
+		assert.ErrorIs(t, err, exception.ErrInternalServer)
+		assert.True(t, dialect.MySQL.Retryable(err), "the driver error is kept as the cause")
+	})
+}
+
+func TestFetchPending(t *testing.T) {
+	t.Run("Fetch Pending Success", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := outbox.NewOutboxRepository(db, dialect.MySQL, constant.TableOutbox)
+
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{"id", "event_type", "aggregate_id", "payload", "occurred_at", "attempts"}).
+			AddRow(1, outbox.EventAccountRegistered, 1, []byte(`{"id":1}`), occurredAt, 0).
+			AddRow(2, outbox.EventAccountDeleted, 1, []byte(`{"id":1}`), occurredAt, 3)
+
+		mock.ExpectPrepare(`WHERE o.published_at IS NULL AND o.dead_at IS NULL AND \(o.next_attempt_at IS NULL OR o.next_attempt_at <= \?\) AND NOT EXISTS \(SELECT 1 FROM outbox p WHERE p.aggregate_id = o.aggregate_id AND p.id < o.id AND p.published_at IS NULL AND p.dead_at IS NULL\) ORDER BY o.id LIMIT \? FOR UPDATE SKIP LOCKED`).ExpectQuery().WithArgs(occurredAt, 10).WillReturnRows(rows)
+
+		events, err := repo.FetchPending(context.TODO(), occurredAt, 10)
+
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		assert.JSONEq(t, `{"id":1}`, string(events[0].Payload))
+		assert.Equal(t, 3, events[1].Attempts)
+	})
+
+	t.Run("Fetch Pending Error", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := outbox.NewOutboxRepository(db, dialect.MySQL, constant.TableOutbox)
+
+		defer db.Close()
+
+		mock.ExpectPrepare(`SELECT o.id`).ExpectQuery().WillReturnError(fmt.Errorf("query failed"))
+
+		events, err := repo.FetchPending(context.TODO(), occurredAt, 10)
+
+		assert.Error(t, err)
+		assert.Nil(t, events)
+	})
+}
+
+func TestMarkPublished(t *testing.T) {
+	t.Run("Mark Published Success", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := outbox.NewOutboxRepository(db, dialect.MySQL, constant.TableOutbox)
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`UPDATE %s SET published_at`, constant.TableOutbox)
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(sqlmock.AnyArg(), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.MarkPublished(context.TODO(), 1)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Mark Published Not Found", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := outbox.NewOutboxRepository(db, dialect.MySQL, constant.TableOutbox)
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`UPDATE %s SET published_at`, constant.TableOutbox)
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(sqlmock.AnyArg(), int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.MarkPublished(context.TODO(), 1)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestMarkFailed(t *testing.T) {
+	t.Run("Mark Failed Success", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := outbox.NewOutboxRepository(db, dialect.MySQL, constant.TableOutbox)
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`UPDATE %s SET attempts = attempts \+ 1, last_error = \?, next_attempt_at = \?`, constant.TableOutbox)
+		mock.ExpectPrepare(query).ExpectExec().WithArgs("sink down", occurredAt, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.MarkFailed(context.TODO(), 1, "sink down", occurredAt)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Mark Failed Truncates Reason", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := outbox.NewOutboxRepository(db, dialect.MySQL, constant.TableOutbox)
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`UPDATE %s SET attempts`, constant.TableOutbox)
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(strings.Repeat("x", 1024), occurredAt, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.MarkFailed(context.TODO(), 1, strings.Repeat("x", 4096), occurredAt)
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestMarkDead(t *testing.T) {
+	t.Run("Mark Dead Success", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := outbox.NewOutboxRepository(db, dialect.MySQL, constant.TableOutbox)
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`UPDATE %s SET attempts = attempts \+ 1, last_error = \?, dead_at = \?`, constant.TableOutbox)
+		mock.ExpectPrepare(query).ExpectExec().WithArgs("sink down", sqlmock.AnyArg(), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.MarkDead(context.TODO(), 1, "sink down")
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestLease(t *testing.T) {
+	t.Run("Lease Success", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := outbox.NewOutboxRepository(db, dialect.MySQL, constant.TableOutbox)
+
+		defer db.Close()
+
+		query := fmt.Sprintf(`UPDATE %s SET next_attempt_at = \? WHERE id IN \(\?, \?\)`, constant.TableOutbox)
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(occurredAt, int64(1), int64(2)).WillReturnResult(sqlmock.NewResult(0, 2))
+
+		err := repo.Lease(context.TODO(), []int64{1, 2}, occurredAt)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Lease Nothing", func(t *testing.T) {
+		db, mock := mock.NewMock()
+		repo := outbox.NewOutboxRepository(db, dialect.MySQL, constant.TableOutbox)
+
+		defer db.Close()
+
+		assert.NoError(t, repo.Lease(context.TODO(), nil, occurredAt))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
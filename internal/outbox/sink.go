@@ -0,0 +1,125 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"waizly/models"
+)
+
+// Sink receives events from the relay. Delivery is at-least-once, so a sink
+// may see the same event ID more than once and must tolerate it.
+type Sink interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+type (
+	HandlerFunc func(ctx context.Context, event models.Event) error
+
+	// Bus delivers events to handlers in the same process.
+	Bus struct {
+		mu       sync.RWMutex
+		handlers map[string][]HandlerFunc
+	}
+
+	// HTTPSink POSTs every event as JSON to a single URL.
+	HTTPSink struct {
+		url    string
+		client *http.Client
+	}
+
+	// WriterSink writes events as newline delimited JSON.
+	WriterSink struct {
+		mu sync.Mutex
+		w  io.Writer
+	}
+)
+
+// AllEvents subscribes a bus handler to every event type.
+const AllEvents = "*"
+
+func NewBus() *Bus {
+	return &Bus{
+		handlers: map[string][]HandlerFunc{},
+	}
+}
+
+func (b *Bus) Subscribe(eventType string, handler HandlerFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+func (b *Bus) Publish(ctx context.Context, event models.Event) error {
+	b.mu.RLock()
+	handlers := append(append([]HandlerFunc{}, b.handlers[event.Type]...), b.handlers[AllEvents]...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func NewHTTPSink(url string, client *http.Client) *HTTPSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &HTTPSink{
+		url:    url,
+		client: client,
+	}
+}
+
+func (s *HTTPSink) Publish(ctx context.Context, event models.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Type", event.Type)
+	req.Header.Set("X-Event-ID", fmt.Sprint(event.ID))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded %d", s.url, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{
+		w: w,
+	}
+}
+
+func (s *WriterSink) Publish(ctx context.Context, event models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.NewEncoder(s.w).Encode(event)
+}
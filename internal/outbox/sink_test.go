@@ -0,0 +1,81 @@
+package outbox_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"waizly/internal/outbox"
+	"waizly/models"
+)
+
+func TestBus(t *testing.T) {
+	t.Run("Deliver By Type", func(t *testing.T) {
+		bus := outbox.NewBus()
+
+		var received []string
+		bus.Subscribe(outbox.EventAccountDeleted, func(ctx context.Context, event models.Event) error {
+			received = append(received, "deleted")
+			return nil
+		})
+		bus.Subscribe(outbox.AllEvents, func(ctx context.Context, event models.Event) error {
+			received = append(received, "all")
+			return nil
+		})
+
+		assert.NoError(t, bus.Publish(context.TODO(), models.Event{Type: outbox.EventAccountRegistered}))
+		assert.NoError(t, bus.Publish(context.TODO(), models.Event{Type: outbox.EventAccountDeleted}))
+		assert.Equal(t, []string{"all", "deleted", "all"}, received)
+	})
+}
+
+func TestHTTPSink(t *testing.T) {
+	t.Run("Publish Success", func(t *testing.T) {
+		var received models.Event
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, outbox.EventAccountUpdated, r.Header.Get("X-Event-Type"))
+			json.NewDecoder(r.Body).Decode(&received)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		sink := outbox.NewHTTPSink(server.URL, server.Client())
+
+		err := sink.Publish(context.TODO(), models.Event{ID: 3, Type: outbox.EventAccountUpdated, Payload: json.RawMessage(`{}`)})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), received.ID)
+	})
+
+	t.Run("Publish Rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		sink := outbox.NewHTTPSink(server.URL, server.Client())
+
+		err := sink.Publish(context.TODO(), models.Event{ID: 3, Payload: json.RawMessage(`{}`)})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestWriterSink(t *testing.T) {
+	t.Run("Write NDJSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		sink := outbox.NewWriterSink(&buf)
+
+		assert.NoError(t, sink.Publish(context.TODO(), models.Event{ID: 1, Payload: json.RawMessage(`{}`)}))
+		assert.NoError(t, sink.Publish(context.TODO(), models.Event{ID: 2, Payload: json.RawMessage(`{}`)}))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 2)
+	})
+}
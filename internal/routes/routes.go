@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"waizly/internal/account"
+	"waizly/internal/audit"
+	"waizly/internal/docs"
+	"waizly/internal/health"
+	"waizly/internal/webhook"
+)
+
+// Dependencies are what the HTTP handlers are built from.
+type Dependencies struct {
+	Health         *health.Health
+	Metrics        http.Handler
+	Validate       *validator.Validate
+	Accounts       account.AccountUseCase
+	AccountOptions []account.HandlerOption
+	Audit          audit.AuditUseCase
+	Webhooks       webhook.WebhookUseCase
+	AdminAuth      mux.MiddlewareFunc
+}
+
+// Register adds every route the server answers to router. The docs tests
+// walk the same routes, so anything served here is either in openapi.json
+// or listed there as undocumented.
+func Register(router *mux.Router, deps Dependencies) {
+	router.Handle("/metrics", deps.Metrics).Methods(http.MethodGet)
+
+	health.NewHealthHandler(router, deps.Health)
+	docs.NewDocsHandler(router)
+	account.NewAccountHandler(router, deps.Validate, deps.Accounts, deps.AccountOptions...)
+	audit.NewAuditHandler(router, deps.Audit, deps.AdminAuth)
+	webhook.NewWebhookHandler(router, deps.Validate, deps.Webhooks, deps.AdminAuth)
+}
@@ -0,0 +1,337 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/logger"
+	"waizly/helpers/transaction"
+	"waizly/models"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+
+	maxResponseBody = 1024
+)
+
+type (
+	// Policy controls retries and when an endpoint is switched off. Lease is
+	// the time a claimed delivery stays hidden from other workers and should
+	// cover one request, so it is normally the HTTP client timeout.
+	Policy struct {
+		MaxAttempts  int
+		BackoffBase  time.Duration
+		BackoffMax   time.Duration
+		DisableAfter int
+		Lease        time.Duration
+	}
+
+	// Dispatcher is an outbox sink that fans events out into one delivery
+	// per matching subscription.
+	Dispatcher struct {
+		repository WebhookRepository
+	}
+
+	// Worker sends due deliveries and schedules retries.
+	Worker struct {
+		repository WebhookRepository
+		transactor transaction.Transactor
+		client     *http.Client
+		policy     Policy
+		interval   time.Duration
+		batchSize  int
+		now        func() time.Time
+	}
+)
+
+func NewDispatcher(repo WebhookRepository) *Dispatcher {
+	return &Dispatcher{
+		repository: repo,
+	}
+}
+
+// Publish queues the deliveries outside any transaction. When the relay
+// fails to mark the event as published and delivers it again, the
+// deliveries already queued are ignored, see EnqueueDelivery.
+func (d *Dispatcher) Publish(ctx context.Context, event models.Event) error {
+	subscriptions, err := d.repository.FindActiveSubscriptions(ctx, event.Type)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, subscription := range subscriptions {
+		err = d.repository.EnqueueDelivery(ctx, models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        body,
+			Status:         DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdateAt:       now,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func NewWorker(repo WebhookRepository, transactor transaction.Transactor, client *http.Client, policy Policy, interval time.Duration, batchSize int) *Worker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Worker{
+		repository: repo,
+		transactor: transactor,
+		client:     client,
+		policy:     policy,
+		interval:   interval,
+		batchSize:  batchSize,
+		now:        time.Now,
+	}
+}
+
+// Run sends due deliveries until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			sent, err := w.Process(ctx)
+			if err != nil {
+				logger.Ctx(ctx).Error("process webhook deliveries", "error", err)
+			}
+
+			if err != nil || sent < w.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process attempts one batch of due deliveries and returns how many were
+// attempted. The batch is claimed in a short transaction that leases every
+// delivery for one Policy.Lease per request, plus one as slack. The requests
+// are sent outside any transaction and each outcome is stored in its own, so
+// a slow endpoint holds neither row locks nor a connection and a failure to
+// store one attempt leaves the others in place. A delivery whose subscription
+// cannot be loaded is skipped, and failed if the subscription is gone.
+func (w *Worker) Process(ctx context.Context) (int, error) {
+	now := w.now().UTC()
+
+	var deliveries []models.WebhookDelivery
+	leasedUntil := now
+	err := w.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		due, err := w.repository.FetchDueDeliveries(ctx, now, w.batchSize)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]int64, len(due))
+		for i, delivery := range due {
+			ids[i] = delivery.ID
+		}
+
+		deliveries = due
+		leasedUntil = now.Add(time.Duration(len(due)+1) * w.policy.Lease)
+
+		return w.repository.LeaseDeliveries(ctx, ids, leasedUntil)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	subscriptions := map[int64]models.WebhookSubscription{}
+
+	for i, delivery := range deliveries {
+		// Another worker may claim the rest once a request no longer fits
+		// into the lease.
+		if w.now().Add(w.policy.Lease).After(leasedUntil) {
+			logger.Ctx(ctx).Warn("webhook lease expired", "remaining", len(deliveries)-i)
+			break
+		}
+
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			subscription, err = w.repository.FindSubscription(ctx, delivery.SubscriptionID)
+			if err != nil {
+				logger.Ctx(ctx).Error("find webhook subscription", "delivery", delivery.ID, "subscription", delivery.SubscriptionID, "error", err)
+
+				// A deleted subscription never comes back, anything else is
+				// retried once the lease expires.
+				if errors.Is(err, exception.ErrNotFound) {
+					err = w.fail(ctx, delivery, "subscription deleted")
+					if err != nil && !errors.Is(err, exception.ErrNotFound) {
+						logger.Ctx(ctx).Error("fail webhook delivery", "delivery", delivery.ID, "error", err)
+					}
+				}
+
+				continue
+			}
+
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		disabled, err := w.attempt(ctx, subscription, delivery)
+		if err != nil {
+			return attempted, err
+		}
+
+		if disabled {
+			subscription.Active = false
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		attempted++
+	}
+
+	return attempted, nil
+}
+
+// attempt sends one delivery and stores the outcome in its own transaction.
+// It reports whether the subscription got disabled because of this failure.
+func (w *Worker) attempt(ctx context.Context, subscription models.WebhookSubscription, delivery models.WebhookDelivery) (bool, error) {
+	if !subscription.Active {
+		return false, w.fail(ctx, delivery, "subscription disabled")
+	}
+
+	attempt := w.send(ctx, subscription, delivery)
+
+	now := w.now().UTC()
+	delivery.UpdateAt = now
+	delivery.Attempts++
+	delivery.LastStatusCode = attempt.StatusCode
+	delivery.LastError = attempt.Error
+
+	disabled := false
+	err := w.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		disabled = false
+
+		err := w.repository.RecordAttempt(ctx, attempt)
+		if err != nil {
+			return err
+		}
+
+		if attempt.Error == "" {
+			delivery.Status = DeliverySucceeded
+
+			err = w.repository.UpdateDelivery(ctx, delivery.ID, delivery)
+			if err != nil {
+				return err
+			}
+
+			return w.repository.RecordSuccess(ctx, subscription.ID)
+		}
+
+		if delivery.Attempts >= w.policy.MaxAttempts {
+			delivery.Status = DeliveryFailed
+		} else {
+			delivery.NextAttemptAt = now.Add(w.policy.Backoff(delivery.Attempts))
+		}
+
+		err = w.repository.UpdateDelivery(ctx, delivery.ID, delivery)
+		if err != nil {
+			return err
+		}
+
+		disabled, err = w.repository.RecordFailure(ctx, subscription.ID, w.policy.DisableAfter)
+		return err
+	})
+
+	return disabled, err
+}
+
+// fail gives up on a delivery without sending it.
+func (w *Worker) fail(ctx context.Context, delivery models.WebhookDelivery, reason string) error {
+	delivery.UpdateAt = w.now().UTC()
+	delivery.Status = DeliveryFailed
+	delivery.LastError = reason
+
+	return w.repository.UpdateDelivery(ctx, delivery.ID, delivery)
+}
+
+func (w *Worker) send(ctx context.Context, subscription models.WebhookSubscription, delivery models.WebhookDelivery) (attempt models.WebhookAttempt) {
+	start := w.now()
+	attempt.DeliveryID = delivery.ID
+	attempt.AttemptedAt = start.UTC()
+
+	defer func() {
+		attempt.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(start.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, start, delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	io.Copy(io.Discard, resp.Body)
+
+	attempt.StatusCode = resp.StatusCode
+	attempt.ResponseBody = string(body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+
+	return attempt
+}
+
+// Backoff doubles the wait after every failed attempt, capped at BackoffMax.
+func (p Policy) Backoff(attempts int) time.Duration {
+	wait := p.BackoffBase
+	for i := 1; i < attempts && wait < p.BackoffMax; i++ {
+		wait *= 2
+	}
+
+	if wait > p.BackoffMax {
+		wait = p.BackoffMax
+	}
+
+	return wait
+}
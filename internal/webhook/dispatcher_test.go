@@ -0,0 +1,342 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"waizly/helpers/exception"
+	transactionmocks "waizly/helpers/transaction/mocks"
+	"waizly/internal/outbox"
+	"waizly/internal/webhook"
+	"waizly/internal/webhook/mocks"
+	"waizly/models"
+)
+
+const secret = "0123456789abcdef0123456789abcdef"
+
+var policy = webhook.Policy{
+	MaxAttempts:  3,
+	BackoffBase:  time.Second,
+	BackoffMax:   10 * time.Second,
+	DisableAfter: 5,
+	Lease:        time.Minute,
+}
+
+// newTransactor runs fn and counts through committed how many transactions
+// returned without error.
+func newTransactor(committed *int) *transactionmocks.Transactor {
+	transactor := new(transactionmocks.Transactor)
+	transactor.On("WithinTransaction", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		err := fn(ctx)
+		if err == nil && committed != nil {
+			*committed++
+		}
+
+		return err
+	})
+
+	return transactor
+}
+
+// receiver is an httptest endpoint that checks signatures and answers with
+// the configured status.
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	received []string
+	server   *httptest.Server
+}
+
+func newReceiver(t *testing.T, status int) *receiver {
+	rc := &receiver{status: status}
+	rc.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		valid := webhook.Verify(secret, r.Header.Get(webhook.HeaderTimestamp), r.Header.Get(webhook.HeaderSignature), body, time.Minute)
+		assert.True(t, valid, "signature should verify")
+
+		rc.mu.Lock()
+		rc.received = append(rc.received, r.Header.Get(webhook.HeaderEvent))
+		rc.mu.Unlock()
+
+		w.WriteHeader(rc.status)
+		w.Write([]byte("ack"))
+	}))
+	t.Cleanup(rc.server.Close)
+
+	return rc
+}
+
+func TestSignature(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"id":1}`)
+	signature := webhook.Sign(secret, now, body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.True(t, webhook.Verify(secret, timestamp, signature, body, time.Minute))
+	})
+
+	t.Run("Tampered Body", func(t *testing.T) {
+		assert.False(t, webhook.Verify(secret, timestamp, signature, []byte(`{"id":2}`), time.Minute))
+	})
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		assert.False(t, webhook.Verify("another-secret-value", timestamp, signature, body, time.Minute))
+	})
+
+	t.Run("Stale Timestamp", func(t *testing.T) {
+		old := now.Add(-time.Hour)
+		assert.False(t, webhook.Verify(secret, strconv.FormatInt(old.Unix(), 10), webhook.Sign(secret, old, body), body, time.Minute))
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, policy.Backoff(1))
+	assert.Equal(t, 2*time.Second, policy.Backoff(2))
+	assert.Equal(t, 8*time.Second, policy.Backoff(4))
+	assert.Equal(t, 10*time.Second, policy.Backoff(10))
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("Enqueue Per Subscription", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("FindActiveSubscriptions", mock.Anything, outbox.EventAccountUpdated).Return([]models.WebhookSubscription{{ID: 1}, {ID: 2}}, nil)
+		webhookRepository.On("EnqueueDelivery", mock.Anything, mock.MatchedBy(func(delivery models.WebhookDelivery) bool {
+			return delivery.EventID == 10 && delivery.Status == webhook.DeliveryPending
+		})).Return(nil).Twice()
+
+		dispatcher := webhook.NewDispatcher(webhookRepository)
+
+		err := dispatcher.Publish(context.TODO(), models.Event{ID: 10, Type: outbox.EventAccountUpdated, Payload: json.RawMessage(`{}`)})
+
+		assert.NoError(t, err)
+		webhookRepository.AssertExpectations(t)
+	})
+}
+
+func TestWorker(t *testing.T) {
+	delivery := models.WebhookDelivery{
+		ID:             1,
+		SubscriptionID: 1,
+		EventID:        10,
+		EventType:      outbox.EventAccountRegistered,
+		Payload:        json.RawMessage(`{"id":10}`),
+		Status:         webhook.DeliveryPending,
+	}
+
+	t.Run("Deliver Signed Request", func(t *testing.T) {
+		rc := newReceiver(t, http.StatusOK)
+		webhookRepository := new(mocks.WebhookRepository)
+		committed := 0
+
+		webhookRepository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]models.WebhookDelivery{delivery}, nil)
+		webhookRepository.On("LeaseDeliveries", mock.Anything, []int64{1}, mock.AnythingOfType("time.Time")).Return(nil)
+		webhookRepository.On("FindSubscription", mock.Anything, int64(1)).Return(models.WebhookSubscription{ID: 1, URL: rc.server.URL, Secret: secret, Active: true}, nil)
+		webhookRepository.On("RecordAttempt", mock.Anything, mock.MatchedBy(func(attempt models.WebhookAttempt) bool {
+			return attempt.StatusCode == http.StatusOK && attempt.ResponseBody == "ack" && attempt.Error == ""
+		})).Return(nil).Run(func(args mock.Arguments) {
+			assert.Equal(t, 1, committed, "the request is sent after the claim committed")
+		})
+		webhookRepository.On("UpdateDelivery", mock.Anything, int64(1), mock.MatchedBy(func(delivery models.WebhookDelivery) bool {
+			return delivery.Status == webhook.DeliverySucceeded && delivery.Attempts == 1
+		})).Return(nil)
+		webhookRepository.On("RecordSuccess", mock.Anything, int64(1)).Return(nil)
+
+		worker := webhook.NewWorker(webhookRepository, newTransactor(&committed), rc.server.Client(), policy, time.Second, 10)
+
+		attempted, err := worker.Process(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempted)
+		assert.Equal(t, 2, committed, "claim and outcome commit separately")
+		assert.Equal(t, []string{outbox.EventAccountRegistered}, rc.received)
+		webhookRepository.AssertExpectations(t)
+	})
+
+	t.Run("Schedule Retry On Failure", func(t *testing.T) {
+		rc := newReceiver(t, http.StatusInternalServerError)
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]models.WebhookDelivery{delivery}, nil)
+		webhookRepository.On("LeaseDeliveries", mock.Anything, []int64{1}, mock.AnythingOfType("time.Time")).Return(nil)
+		webhookRepository.On("FindSubscription", mock.Anything, int64(1)).Return(models.WebhookSubscription{ID: 1, URL: rc.server.URL, Secret: secret, Active: true}, nil)
+		webhookRepository.On("RecordAttempt", mock.Anything, mock.MatchedBy(func(attempt models.WebhookAttempt) bool {
+			return attempt.StatusCode == http.StatusInternalServerError && attempt.Error != ""
+		})).Return(nil)
+		webhookRepository.On("UpdateDelivery", mock.Anything, int64(1), mock.MatchedBy(func(updated models.WebhookDelivery) bool {
+			return updated.Status == webhook.DeliveryPending && updated.Attempts == 1 && updated.NextAttemptAt.After(time.Now())
+		})).Return(nil)
+		webhookRepository.On("RecordFailure", mock.Anything, int64(1), policy.DisableAfter).Return(false, nil)
+
+		worker := webhook.NewWorker(webhookRepository, newTransactor(nil), rc.server.Client(), policy, time.Second, 10)
+
+		_, err := worker.Process(context.TODO())
+
+		assert.NoError(t, err)
+		webhookRepository.AssertExpectations(t)
+	})
+
+	t.Run("Give Up After Max Attempts", func(t *testing.T) {
+		rc := newReceiver(t, http.StatusGone)
+		webhookRepository := new(mocks.WebhookRepository)
+
+		exhausted := delivery
+		exhausted.Attempts = policy.MaxAttempts - 1
+
+		webhookRepository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]models.WebhookDelivery{exhausted}, nil)
+		webhookRepository.On("LeaseDeliveries", mock.Anything, []int64{1}, mock.AnythingOfType("time.Time")).Return(nil)
+		webhookRepository.On("FindSubscription", mock.Anything, int64(1)).Return(models.WebhookSubscription{ID: 1, URL: rc.server.URL, Secret: secret, Active: true}, nil)
+		webhookRepository.On("RecordAttempt", mock.Anything, mock.AnythingOfType("models.WebhookAttempt")).Return(nil)
+		webhookRepository.On("UpdateDelivery", mock.Anything, int64(1), mock.MatchedBy(func(updated models.WebhookDelivery) bool {
+			return updated.Status == webhook.DeliveryFailed
+		})).Return(nil)
+		webhookRepository.On("RecordFailure", mock.Anything, int64(1), policy.DisableAfter).Return(true, nil)
+
+		worker := webhook.NewWorker(webhookRepository, newTransactor(nil), rc.server.Client(), policy, time.Second, 10)
+
+		_, err := worker.Process(context.TODO())
+
+		assert.NoError(t, err)
+		webhookRepository.AssertExpectations(t)
+	})
+
+	t.Run("Skip Disabled Subscription", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]models.WebhookDelivery{delivery}, nil)
+		webhookRepository.On("LeaseDeliveries", mock.Anything, []int64{1}, mock.AnythingOfType("time.Time")).Return(nil)
+		webhookRepository.On("FindSubscription", mock.Anything, int64(1)).Return(models.WebhookSubscription{ID: 1, Active: false}, nil)
+		webhookRepository.On("UpdateDelivery", mock.Anything, int64(1), mock.MatchedBy(func(updated models.WebhookDelivery) bool {
+			return updated.Status == webhook.DeliveryFailed && updated.LastError == "subscription disabled"
+		})).Return(nil)
+
+		worker := webhook.NewWorker(webhookRepository, newTransactor(nil), nil, policy, time.Second, 10)
+
+		_, err := worker.Process(context.TODO())
+
+		assert.NoError(t, err)
+		webhookRepository.AssertExpectations(t)
+	})
+
+	t.Run("Skip Deleted Subscription", func(t *testing.T) {
+		rc := newReceiver(t, http.StatusOK)
+		webhookRepository := new(mocks.WebhookRepository)
+
+		orphan := delivery
+		orphan.SubscriptionID = 2
+
+		second := delivery
+		second.ID = 2
+
+		webhookRepository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]models.WebhookDelivery{orphan, second}, nil)
+		webhookRepository.On("LeaseDeliveries", mock.Anything, []int64{1, 2}, mock.AnythingOfType("time.Time")).Return(nil)
+		webhookRepository.On("FindSubscription", mock.Anything, int64(2)).Return(models.WebhookSubscription{}, exception.Wrap("WebhookRepository.FindSubscription", exception.ErrNotFound, nil))
+		webhookRepository.On("FindSubscription", mock.Anything, int64(1)).Return(models.WebhookSubscription{ID: 1, URL: rc.server.URL, Secret: secret, Active: true}, nil)
+		webhookRepository.On("UpdateDelivery", mock.Anything, int64(1), mock.MatchedBy(func(updated models.WebhookDelivery) bool {
+			return updated.Status == webhook.DeliveryFailed && updated.LastError == "subscription deleted"
+		})).Return(nil)
+		webhookRepository.On("RecordAttempt", mock.Anything, mock.AnythingOfType("models.WebhookAttempt")).Return(nil)
+		webhookRepository.On("UpdateDelivery", mock.Anything, int64(2), mock.MatchedBy(func(updated models.WebhookDelivery) bool {
+			return updated.Status == webhook.DeliverySucceeded
+		})).Return(nil)
+		webhookRepository.On("RecordSuccess", mock.Anything, int64(1)).Return(nil)
+
+		worker := webhook.NewWorker(webhookRepository, newTransactor(nil), rc.server.Client(), policy, time.Second, 10)
+
+		attempted, err := worker.Process(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempted)
+		assert.Equal(t, []string{outbox.EventAccountRegistered}, rc.received)
+		webhookRepository.AssertExpectations(t)
+	})
+
+	t.Run("Skip Subscription Lookup Error", func(t *testing.T) {
+		rc := newReceiver(t, http.StatusOK)
+		webhookRepository := new(mocks.WebhookRepository)
+
+		unreachable := delivery
+		unreachable.SubscriptionID = 2
+
+		second := delivery
+		second.ID = 2
+
+		webhookRepository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]models.WebhookDelivery{unreachable, second}, nil)
+		webhookRepository.On("LeaseDeliveries", mock.Anything, []int64{1, 2}, mock.AnythingOfType("time.Time")).Return(nil)
+		webhookRepository.On("FindSubscription", mock.Anything, int64(2)).Return(models.WebhookSubscription{}, exception.Wrap("WebhookRepository.FindSubscription", exception.ErrTimeout, nil))
+		webhookRepository.On("FindSubscription", mock.Anything, int64(1)).Return(models.WebhookSubscription{ID: 1, URL: rc.server.URL, Secret: secret, Active: true}, nil)
+		webhookRepository.On("RecordAttempt", mock.Anything, mock.AnythingOfType("models.WebhookAttempt")).Return(nil)
+		webhookRepository.On("UpdateDelivery", mock.Anything, int64(2), mock.AnythingOfType("models.WebhookDelivery")).Return(nil)
+		webhookRepository.On("RecordSuccess", mock.Anything, int64(1)).Return(nil)
+
+		worker := webhook.NewWorker(webhookRepository, newTransactor(nil), rc.server.Client(), policy, time.Second, 10)
+
+		attempted, err := worker.Process(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempted)
+		webhookRepository.AssertNotCalled(t, "UpdateDelivery", mock.Anything, int64(1), mock.Anything)
+		webhookRepository.AssertExpectations(t)
+	})
+
+	t.Run("Record Error Keeps Earlier Attempts", func(t *testing.T) {
+		rc := newReceiver(t, http.StatusOK)
+		webhookRepository := new(mocks.WebhookRepository)
+		committed := 0
+
+		second := delivery
+		second.ID = 2
+
+		webhookRepository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]models.WebhookDelivery{delivery, second}, nil)
+		webhookRepository.On("LeaseDeliveries", mock.Anything, []int64{1, 2}, mock.AnythingOfType("time.Time")).Return(nil)
+		webhookRepository.On("FindSubscription", mock.Anything, int64(1)).Return(models.WebhookSubscription{ID: 1, URL: rc.server.URL, Secret: secret, Active: true}, nil).Once()
+		webhookRepository.On("RecordAttempt", mock.Anything, mock.MatchedBy(func(attempt models.WebhookAttempt) bool {
+			return attempt.DeliveryID == 1
+		})).Return(nil)
+		webhookRepository.On("RecordAttempt", mock.Anything, mock.MatchedBy(func(attempt models.WebhookAttempt) bool {
+			return attempt.DeliveryID == 2
+		})).Return(fmt.Errorf("db down"))
+		webhookRepository.On("UpdateDelivery", mock.Anything, int64(1), mock.AnythingOfType("models.WebhookDelivery")).Return(nil)
+		webhookRepository.On("RecordSuccess", mock.Anything, int64(1)).Return(nil).Once()
+
+		worker := webhook.NewWorker(webhookRepository, newTransactor(&committed), rc.server.Client(), policy, time.Second, 10)
+
+		attempted, err := worker.Process(context.TODO())
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, attempted)
+		assert.Equal(t, 2, committed, "the first attempt stays committed")
+		webhookRepository.AssertExpectations(t)
+	})
+
+	t.Run("Lease Expired", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]models.WebhookDelivery{delivery}, nil)
+		webhookRepository.On("LeaseDeliveries", mock.Anything, []int64{1}, mock.AnythingOfType("time.Time")).Return(nil)
+
+		expiring := policy
+		expiring.Lease = time.Nanosecond
+		worker := webhook.NewWorker(webhookRepository, newTransactor(nil), nil, expiring, time.Second, 10)
+
+		attempted, err := worker.Process(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, attempted)
+		webhookRepository.AssertNotCalled(t, "FindSubscription", mock.Anything, mock.Anything)
+		webhookRepository.AssertExpectations(t)
+	})
+}
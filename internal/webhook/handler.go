@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/response"
+	"waizly/models"
+)
+
+type WebhookHandler struct {
+	Validate *validator.Validate
+	UseCase  WebhookUseCase
+}
+
+func NewWebhookHandler(router *mux.Router, validate *validator.Validate, usecase WebhookUseCase, auth mux.MiddlewareFunc) {
+	handler := &WebhookHandler{
+		Validate: validate,
+		UseCase:  usecase,
+	}
+
+	admin := router.PathPrefix("/admin/webhooks").Subrouter()
+	admin.Use(auth)
+
+	admin.HandleFunc("", handler.CreateSubscription).Methods(http.MethodPost)
+	admin.HandleFunc("", handler.ListSubscriptions).Methods(http.MethodGet)
+	admin.HandleFunc("/deliveries/{id:[0-9]+}", handler.DetailDelivery).Methods(http.MethodGet)
+	admin.HandleFunc("/deliveries/{id:[0-9]+}/redeliver", handler.Redeliver).Methods(http.MethodPost)
+	admin.HandleFunc("/{id:[0-9]+}", handler.DetailSubscription).Methods(http.MethodGet)
+	admin.HandleFunc("/{id:[0-9]+}", handler.UpdateSubscription).Methods(http.MethodPatch)
+	admin.HandleFunc("/{id:[0-9]+}", handler.DeleteSubscription).Methods(http.MethodDelete)
+	admin.HandleFunc("/{id:[0-9]+}/deliveries", handler.ListDeliveries).Methods(http.MethodGet)
+}
+
+func (handler *WebhookHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var res response.Response
+	var params models.WebhookSubscriptionRequest
+
+	ctx := r.Context()
+
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		res = response.Error(response.StatusUnprocessableEntity, err)
+		res.Render(w, r)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		res = response.Error(response.StatusBadRequest, err)
+		res.Render(w, r)
+		return
+	}
+
+	res = handler.UseCase.CreateSubscription(ctx, params)
+
+	res.Render(w, r)
+}
+
+func (handler *WebhookHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	res := handler.UseCase.ListSubscriptions(r.Context())
+
+	res.Render(w, r)
+}
+
+func (handler *WebhookHandler) DetailSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		response.Error(response.StatusBadRequest, exception.ErrParams).Render(w, r)
+		return
+	}
+
+	res := handler.UseCase.DetailSubscription(r.Context(), id)
+
+	res.Render(w, r)
+}
+
+func (handler *WebhookHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	var res response.Response
+	var params models.WebhookSubscriptionUpdateRequest
+
+	ctx := r.Context()
+
+	id, err := pathID(r)
+	if err != nil {
+		res = response.Error(response.StatusBadRequest, exception.ErrParams)
+		res.Render(w, r)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		res = response.Error(response.StatusUnprocessableEntity, err)
+		res.Render(w, r)
+		return
+	}
+
+	err = handler.Validate.StructCtx(ctx, params)
+	if err != nil {
+		res = response.Error(response.StatusBadRequest, err)
+		res.Render(w, r)
+		return
+	}
+
+	res = handler.UseCase.UpdateSubscription(ctx, id, params)
+
+	res.Render(w, r)
+}
+
+func (handler *WebhookHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		response.Error(response.StatusBadRequest, exception.ErrParams).Render(w, r)
+		return
+	}
+
+	res := handler.UseCase.DeleteSubscription(r.Context(), id)
+
+	res.Render(w, r)
+}
+
+func (handler *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		response.Error(response.StatusBadRequest, exception.ErrParams).Render(w, r)
+		return
+	}
+
+	res := handler.UseCase.ListDeliveries(r.Context(), id)
+
+	res.Render(w, r)
+}
+
+func (handler *WebhookHandler) DetailDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		response.Error(response.StatusBadRequest, exception.ErrParams).Render(w, r)
+		return
+	}
+
+	res := handler.UseCase.DetailDelivery(r.Context(), id)
+
+	res.Render(w, r)
+}
+
+func (handler *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		response.Error(response.StatusBadRequest, exception.ErrParams).Render(w, r)
+		return
+	}
+
+	res := handler.UseCase.Redeliver(r.Context(), id)
+
+	res.Render(w, r)
+}
+
+func pathID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+}
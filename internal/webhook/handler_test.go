@@ -0,0 +1,104 @@
+package webhook_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"waizly/helpers/middleware"
+	"waizly/helpers/response"
+	"waizly/internal/webhook"
+	"waizly/internal/webhook/mocks"
+	"waizly/models"
+)
+
+func newRouter(webhookUseCase webhook.WebhookUseCase) *mux.Router {
+	router := mux.NewRouter()
+	webhook.NewWebhookHandler(router, validator.New(), webhookUseCase, middleware.BasicAuth("admin", "secret"))
+
+	return router
+}
+
+func TestHandler_CreateSubscription(t *testing.T) {
+	t.Run("Create Success", func(t *testing.T) {
+		webhookUseCase := new(mocks.WebhookUseCase)
+		webhookUseCase.On("CreateSubscription", mock.Anything, mock.AnythingOfType("models.WebhookSubscriptionRequest")).Return(response.Success(response.StatusCreated, models.WebhookSubscription{}))
+
+		body, _ := json.Marshal(models.WebhookSubscriptionRequest{URL: "https://example.com/hook", EventType: "AccountDeleted"})
+		r := httptest.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewReader(body))
+		r.SetBasicAuth("admin", "secret")
+		recorder := httptest.NewRecorder()
+
+		newRouter(webhookUseCase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		webhookUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Unknown Event Type", func(t *testing.T) {
+		webhookUseCase := new(mocks.WebhookUseCase)
+
+		body, _ := json.Marshal(models.WebhookSubscriptionRequest{URL: "https://example.com/hook", EventType: "AccountExploded"})
+		r := httptest.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewReader(body))
+		r.SetBasicAuth("admin", "secret")
+		recorder := httptest.NewRecorder()
+
+		newRouter(webhookUseCase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		webhookUseCase.AssertExpectations(t)
+	})
+}
+
+func TestHandler_Redeliver(t *testing.T) {
+	t.Run("Redeliver Success", func(t *testing.T) {
+		webhookUseCase := new(mocks.WebhookUseCase)
+		webhookUseCase.On("Redeliver", mock.Anything, int64(42)).Return(response.Success(response.StatusOK, models.WebhookDelivery{}))
+
+		r := httptest.NewRequest(http.MethodPost, "/admin/webhooks/deliveries/42/redeliver", nil)
+		r.SetBasicAuth("admin", "secret")
+		recorder := httptest.NewRecorder()
+
+		newRouter(webhookUseCase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		webhookUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		webhookUseCase := new(mocks.WebhookUseCase)
+
+		r := httptest.NewRequest(http.MethodPost, "/admin/webhooks/deliveries/42/redeliver", nil)
+		recorder := httptest.NewRecorder()
+
+		newRouter(webhookUseCase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		webhookUseCase.AssertExpectations(t)
+	})
+}
+
+func TestHandler_UpdateSubscription(t *testing.T) {
+	t.Run("Disable Subscription", func(t *testing.T) {
+		webhookUseCase := new(mocks.WebhookUseCase)
+		webhookUseCase.On("UpdateSubscription", mock.Anything, int64(7), mock.MatchedBy(func(params models.WebhookSubscriptionUpdateRequest) bool {
+			return params.Active != nil && !*params.Active && params.URL == nil
+		})).Return(response.Success(response.StatusOK, models.WebhookSubscription{}))
+
+		r := httptest.NewRequest(http.MethodPatch, "/admin/webhooks/7", bytes.NewReader([]byte(`{"active":false}`)))
+		r.SetBasicAuth("admin", "secret")
+		recorder := httptest.NewRecorder()
+
+		newRouter(webhookUseCase).ServeHTTP(recorder, r)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		webhookUseCase.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,452 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"waizly/helpers/dialect"
+	"waizly/helpers/exception"
+	"waizly/helpers/logger"
+	"waizly/helpers/transaction"
+	"waizly/models"
+)
+
+type (
+	WebhookRepository interface {
+		CreateSubscription(ctx context.Context, params models.WebhookSubscription) (int64, error)
+		FindSubscription(ctx context.Context, id int64) (models.WebhookSubscription, error)
+		ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+		FindActiveSubscriptions(ctx context.Context, eventType string) ([]models.WebhookSubscription, error)
+		UpdateSubscription(ctx context.Context, id int64, params models.WebhookSubscription) error
+		DeleteSubscription(ctx context.Context, id int64) error
+		RecordSuccess(ctx context.Context, id int64) error
+		RecordFailure(ctx context.Context, id int64, disableAfter int) (bool, error)
+
+		EnqueueDelivery(ctx context.Context, params models.WebhookDelivery) error
+		FetchDueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+		LeaseDeliveries(ctx context.Context, ids []int64, until time.Time) error
+		FindDelivery(ctx context.Context, id int64) (models.WebhookDelivery, error)
+		ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]models.WebhookDelivery, error)
+		UpdateDelivery(ctx context.Context, id int64, params models.WebhookDelivery) error
+		RecordAttempt(ctx context.Context, params models.WebhookAttempt) error
+		ListAttempts(ctx context.Context, deliveryID int64) ([]models.WebhookAttempt, error)
+	}
+
+	webhookRepositoryImpl struct {
+		db                *sql.DB
+		dialect           dialect.Dialect
+		subscriptionTable string
+		deliveryTable     string
+		attemptTable      string
+	}
+)
+
+const (
+	subscriptionColumns = "id, url, event_type, secret, active, failure_count, disabled_at, created_at, update_at"
+	deliveryColumns     = "id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, update_at"
+)
+
+func NewWebhookRepository(db *sql.DB, d dialect.Dialect, subscriptionTable, deliveryTable, attemptTable string) WebhookRepository {
+	return &webhookRepositoryImpl{
+		db:                db,
+		dialect:           d,
+		subscriptionTable: subscriptionTable,
+		deliveryTable:     deliveryTable,
+		attemptTable:      attemptTable,
+	}
+}
+
+func (wr *webhookRepositoryImpl) CreateSubscription(ctx context.Context, params models.WebhookSubscription) (int64, error) {
+	query := fmt.Sprintf(`INSERT INTO %s (url, event_type, secret, active, created_at, update_at) VALUES (?, ?, ?, ?, ?, ?)`, wr.subscriptionTable)
+	ID, err := wr.dialect.InsertID(
+		ctx,
+		transaction.Conn(ctx, wr.db),
+		query,
+		params.URL,
+		params.EventType,
+		params.Secret,
+		params.Active,
+		params.CreatedAt,
+		params.UpdateAt,
+	)
+
+	if err != nil {
+		logger.Ctx(ctx).Error("WebhookRepository.CreateSubscription", "error", err)
+		return 0, exception.Database("WebhookRepository.CreateSubscription", err)
+	}
+
+	return ID, nil
+}
+
+func (wr *webhookRepositoryImpl) FindSubscription(ctx context.Context, id int64) (models.WebhookSubscription, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, subscriptionColumns, wr.subscriptionTable)
+
+	subscriptions, err := wr.querySubscriptions(ctx, "WebhookRepository.FindSubscription", query, id)
+	if err != nil {
+		return models.WebhookSubscription{}, err
+	}
+
+	if len(subscriptions) == 0 {
+		return models.WebhookSubscription{}, exception.Wrap("WebhookRepository.FindSubscription", exception.ErrNotFound, nil)
+	}
+
+	return subscriptions[0], nil
+}
+
+func (wr *webhookRepositoryImpl) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s ORDER BY id`, subscriptionColumns, wr.subscriptionTable)
+
+	return wr.querySubscriptions(ctx, "WebhookRepository.ListSubscriptions", query)
+}
+
+func (wr *webhookRepositoryImpl) FindActiveSubscriptions(ctx context.Context, eventType string) ([]models.WebhookSubscription, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE active = TRUE AND event_type IN (?, '*') ORDER BY id`, subscriptionColumns, wr.subscriptionTable)
+
+	return wr.querySubscriptions(ctx, "WebhookRepository.FindActiveSubscriptions", query, eventType)
+}
+
+func (wr *webhookRepositoryImpl) querySubscriptions(ctx context.Context, op, query string, args ...interface{}) ([]models.WebhookSubscription, error) {
+	stmt, err := transaction.Conn(ctx, wr.db).PrepareContext(ctx, wr.dialect.Rebind(query))
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return nil, exception.Database(op, err)
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return nil, exception.Database(op, err)
+	}
+
+	defer rows.Close()
+
+	subscriptions := []models.WebhookSubscription{}
+	for rows.Next() {
+		subscription := models.WebhookSubscription{}
+		var disabledAt sql.NullTime
+
+		err = rows.Scan(
+			&subscription.ID,
+			&subscription.URL,
+			&subscription.EventType,
+			&subscription.Secret,
+			&subscription.Active,
+			&subscription.FailureCount,
+			&disabledAt,
+			&subscription.CreatedAt,
+			&subscription.UpdateAt,
+		)
+
+		if err != nil {
+			logger.Ctx(ctx).Error(op, "error", err)
+			return nil, exception.Database(op, err)
+		}
+
+		if disabledAt.Valid {
+			subscription.DisabledAt = &disabledAt.Time
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return nil, exception.Database(op, err)
+	}
+
+	return subscriptions, nil
+}
+
+func (wr *webhookRepositoryImpl) UpdateSubscription(ctx context.Context, id int64, params models.WebhookSubscription) error {
+	query := fmt.Sprintf(`UPDATE %s SET url = ?, event_type = ?, active = ?, failure_count = ?, disabled_at = ?, update_at = ? WHERE id = ?`, wr.subscriptionTable)
+
+	return wr.exec(ctx, "WebhookRepository.UpdateSubscription", query,
+		params.URL,
+		params.EventType,
+		params.Active,
+		params.FailureCount,
+		params.DisabledAt,
+		params.UpdateAt,
+		id,
+	)
+}
+
+func (wr *webhookRepositoryImpl) DeleteSubscription(ctx context.Context, id int64) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, wr.subscriptionTable)
+
+	return wr.exec(ctx, "WebhookRepository.DeleteSubscription", query, id)
+}
+
+func (wr *webhookRepositoryImpl) RecordSuccess(ctx context.Context, id int64) error {
+	query := fmt.Sprintf(`UPDATE %s SET failure_count = 0 WHERE id = ?`, wr.subscriptionTable)
+
+	err := wr.exec(ctx, "WebhookRepository.RecordSuccess", query, id)
+	if errors.Is(err, exception.ErrNotFound) {
+		// The counter was already zero.
+		return nil
+	}
+
+	return err
+}
+
+// RecordFailure bumps the consecutive failure counter and disables the
+// subscription once it reaches disableAfter. It reports whether this call
+// disabled it.
+func (wr *webhookRepositoryImpl) RecordFailure(ctx context.Context, id int64, disableAfter int) (bool, error) {
+	query := fmt.Sprintf(`UPDATE %s SET failure_count = failure_count + 1 WHERE id = ?`, wr.subscriptionTable)
+
+	err := wr.exec(ctx, "WebhookRepository.RecordFailure", query, id)
+	if err != nil {
+		return false, err
+	}
+
+	query = fmt.Sprintf(`UPDATE %s SET active = FALSE, disabled_at = ? WHERE id = ? AND active = TRUE AND failure_count >= ?`, wr.subscriptionTable)
+
+	err = wr.exec(ctx, "WebhookRepository.RecordFailure", query, time.Now().UTC(), id, disableAfter)
+	if errors.Is(err, exception.ErrNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// EnqueueDelivery ignores a delivery already queued for the same
+// subscription and event, so an event relayed twice is delivered once.
+func (wr *webhookRepositoryImpl) EnqueueDelivery(ctx context.Context, params models.WebhookDelivery) error {
+	query := wr.dialect.InsertIgnore(fmt.Sprintf(`INSERT INTO %s (subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, update_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, wr.deliveryTable))
+	stmt, err := transaction.Conn(ctx, wr.db).PrepareContext(ctx, wr.dialect.Rebind(query))
+	if err != nil {
+		logger.Ctx(ctx).Error("WebhookRepository.EnqueueDelivery", "error", err)
+		return exception.Database("WebhookRepository.EnqueueDelivery", err)
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(
+		ctx,
+		params.SubscriptionID,
+		params.EventID,
+		params.EventType,
+		[]byte(params.Payload),
+		params.Status,
+		params.Attempts,
+		params.NextAttemptAt,
+		params.CreatedAt,
+		params.UpdateAt,
+	)
+
+	if err != nil {
+		logger.Ctx(ctx).Error("WebhookRepository.EnqueueDelivery", "error", err)
+		return exception.Database("WebhookRepository.EnqueueDelivery", err)
+	}
+
+	return nil
+}
+
+// FetchDueDeliveries locks pending deliveries whose next attempt is due. It
+// must run inside a transaction, see outbox.FetchPending.
+func (wr *webhookRepositoryImpl) FetchDueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ? FOR UPDATE SKIP LOCKED`, deliveryColumns, wr.deliveryTable)
+
+	return wr.queryDeliveries(ctx, "WebhookRepository.FetchDueDeliveries", wr.dialect.Lock(query), DeliveryPending, now, limit)
+}
+
+// LeaseDeliveries pushes next_attempt_at of the given deliveries to until, so
+// other workers skip them while they are being sent.
+func (wr *webhookRepositoryImpl) LeaseDeliveries(ctx context.Context, ids []int64, until time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := []interface{}{until}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	query := fmt.Sprintf(`UPDATE %s SET next_attempt_at = ? WHERE id IN (%s)`, wr.deliveryTable, placeholders)
+
+	return wr.exec(ctx, "WebhookRepository.LeaseDeliveries", query, args...)
+}
+
+func (wr *webhookRepositoryImpl) FindDelivery(ctx context.Context, id int64) (models.WebhookDelivery, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, deliveryColumns, wr.deliveryTable)
+
+	deliveries, err := wr.queryDeliveries(ctx, "WebhookRepository.FindDelivery", query, id)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	if len(deliveries) == 0 {
+		return models.WebhookDelivery{}, exception.Wrap("WebhookRepository.FindDelivery", exception.ErrNotFound, nil)
+	}
+
+	return deliveries[0], nil
+}
+
+func (wr *webhookRepositoryImpl) ListDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]models.WebhookDelivery, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE subscription_id = ? ORDER BY id DESC LIMIT ?`, deliveryColumns, wr.deliveryTable)
+
+	return wr.queryDeliveries(ctx, "WebhookRepository.ListDeliveries", query, subscriptionID, limit)
+}
+
+func (wr *webhookRepositoryImpl) queryDeliveries(ctx context.Context, op, query string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	stmt, err := transaction.Conn(ctx, wr.db).PrepareContext(ctx, wr.dialect.Rebind(query))
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return nil, exception.Database(op, err)
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return nil, exception.Database(op, err)
+	}
+
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery := models.WebhookDelivery{}
+		var payload []byte
+
+		err = rows.Scan(
+			&delivery.ID,
+			&delivery.SubscriptionID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastStatusCode,
+			&delivery.LastError,
+			&delivery.CreatedAt,
+			&delivery.UpdateAt,
+		)
+
+		if err != nil {
+			logger.Ctx(ctx).Error(op, "error", err)
+			return nil, exception.Database(op, err)
+		}
+
+		delivery.Payload = payload
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return nil, exception.Database(op, err)
+	}
+
+	return deliveries, nil
+}
+
+func (wr *webhookRepositoryImpl) UpdateDelivery(ctx context.Context, id int64, params models.WebhookDelivery) error {
+	query := fmt.Sprintf(`UPDATE %s SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, update_at = ? WHERE id = ?`, wr.deliveryTable)
+
+	return wr.exec(ctx, "WebhookRepository.UpdateDelivery", query,
+		params.Status,
+		params.Attempts,
+		params.NextAttemptAt,
+		params.LastStatusCode,
+		params.LastError,
+		params.UpdateAt,
+		id,
+	)
+}
+
+func (wr *webhookRepositoryImpl) RecordAttempt(ctx context.Context, params models.WebhookAttempt) error {
+	query := fmt.Sprintf(`INSERT INTO %s (delivery_id, status_code, response_body, error, duration_ms, attempted_at) VALUES (?, ?, ?, ?, ?, ?)`, wr.attemptTable)
+
+	return wr.exec(ctx, "WebhookRepository.RecordAttempt", query,
+		params.DeliveryID,
+		params.StatusCode,
+		params.ResponseBody,
+		params.Error,
+		params.DurationMs,
+		params.AttemptedAt,
+	)
+}
+
+func (wr *webhookRepositoryImpl) ListAttempts(ctx context.Context, deliveryID int64) ([]models.WebhookAttempt, error) {
+	query := fmt.Sprintf(`SELECT id, delivery_id, status_code, response_body, error, duration_ms, attempted_at FROM %s WHERE delivery_id = ? ORDER BY id`, wr.attemptTable)
+	stmt, err := transaction.Conn(ctx, wr.db).PrepareContext(ctx, wr.dialect.Rebind(query))
+	if err != nil {
+		logger.Ctx(ctx).Error("WebhookRepository.ListAttempts", "error", err)
+		return nil, exception.Database("WebhookRepository.ListAttempts", err)
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, deliveryID)
+	if err != nil {
+		logger.Ctx(ctx).Error("WebhookRepository.ListAttempts", "error", err)
+		return nil, exception.Database("WebhookRepository.ListAttempts", err)
+	}
+
+	defer rows.Close()
+
+	attempts := []models.WebhookAttempt{}
+	for rows.Next() {
+		attempt := models.WebhookAttempt{}
+
+		err = rows.Scan(
+			&attempt.ID,
+			&attempt.DeliveryID,
+			&attempt.StatusCode,
+			&attempt.ResponseBody,
+			&attempt.Error,
+			&attempt.DurationMs,
+			&attempt.AttemptedAt,
+		)
+
+		if err != nil {
+			logger.Ctx(ctx).Error("WebhookRepository.ListAttempts", "error", err)
+			return nil, exception.Database("WebhookRepository.ListAttempts", err)
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.Ctx(ctx).Error("WebhookRepository.ListAttempts", "error", err)
+		return nil, exception.Database("WebhookRepository.ListAttempts", err)
+	}
+
+	return attempts, nil
+}
+
+// exec runs a write for op and maps "no rows affected" to
+// exception.ErrNotFound.
+func (wr *webhookRepositoryImpl) exec(ctx context.Context, op, query string, args ...interface{}) error {
+	stmt, err := transaction.Conn(ctx, wr.db).PrepareContext(ctx, wr.dialect.Rebind(query))
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return exception.Database(op, err)
+	}
+
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		logger.Ctx(ctx).Error(op, "error", err)
+		return exception.Database(op, err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+
+	if rowsAffected < 1 {
+		return exception.Wrap(op, exception.ErrNotFound, nil)
+	}
+
+	return nil
+}
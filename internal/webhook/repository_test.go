@@ -0,0 +1,142 @@
+package webhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"waizly/helpers/dialect"
+	"waizly/helpers/exception"
+	"waizly/internal/constant"
+	"waizly/internal/mock"
+	"waizly/internal/webhook"
+	"waizly/models"
+)
+
+func newRepository() (webhook.WebhookRepository, sqlmock.Sqlmock, func() error) {
+	db, mock := mock.NewMock()
+	repo := webhook.NewWebhookRepository(db, dialect.MySQL, constant.TableWebhookSubscription, constant.TableWebhookDelivery, constant.TableWebhookAttempt)
+
+	return repo, mock, db.Close
+}
+
+func TestFindActiveSubscriptions(t *testing.T) {
+	t.Run("Match Type And Wildcard", func(t *testing.T) {
+		repo, mock, closeDB := newRepository()
+		defer closeDB()
+
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "url", "event_type", "secret", "active", "failure_count", "disabled_at", "created_at", "update_at"}).
+			AddRow(1, "https://example.com/a", "*", "s", true, 0, nil, now, now).
+			AddRow(2, "https://example.com/b", "AccountDeleted", "s", true, 0, nil, now, now)
+
+		mock.ExpectPrepare(`WHERE active = TRUE AND event_type IN \(\?, '\*'\)`).ExpectQuery().WithArgs("AccountDeleted").WillReturnRows(rows)
+
+		subscriptions, err := repo.FindActiveSubscriptions(context.TODO(), "AccountDeleted")
+
+		assert.NoError(t, err)
+		assert.Len(t, subscriptions, 2)
+		assert.Nil(t, subscriptions[0].DisabledAt)
+	})
+}
+
+func TestFindSubscription(t *testing.T) {
+	t.Run("Not Found", func(t *testing.T) {
+		repo, mock, closeDB := newRepository()
+		defer closeDB()
+
+		rows := sqlmock.NewRows([]string{"id", "url", "event_type", "secret", "active", "failure_count", "disabled_at", "created_at", "update_at"})
+		mock.ExpectPrepare(fmt.Sprintf(`FROM %s WHERE id = \?`, constant.TableWebhookSubscription)).ExpectQuery().WithArgs(int64(1)).WillReturnRows(rows)
+
+		_, err := repo.FindSubscription(context.TODO(), 1)
+
+		assert.ErrorIs(t, err, exception.ErrNotFound)
+	})
+}
+
+func TestUpdateDelivery(t *testing.T) {
+	t.Run("Deadlock Is Retryable", func(t *testing.T) {
+		repo, mock, closeDB := newRepository()
+		defer closeDB()
+
+		query := fmt.Sprintf(`UPDATE %s SET status`, constant.TableWebhookDelivery)
+		mock.ExpectPrepare(query).ExpectExec().WillReturnError(&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"})
+
+		err := repo.UpdateDelivery(context.TODO(), 1, models.WebhookDelivery{})
+
+		assert.ErrorIs(t, err, exception.ErrInternalServer)
+		assert.True(t, dialect.MySQL.Retryable(err), "the driver error is kept as the cause")
+	})
+}
+
+func TestRecordFailure(t *testing.T) {
+	t.Run("Disable At Threshold", func(t *testing.T) {
+		repo, mock, closeDB := newRepository()
+		defer closeDB()
+
+		mock.ExpectPrepare(`SET failure_count = failure_count \+ 1`).ExpectExec().WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare(`SET active = FALSE, disabled_at = \?`).ExpectExec().WithArgs(sqlmock.AnyArg(), int64(1), 5).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		disabled, err := repo.RecordFailure(context.TODO(), 1, 5)
+
+		assert.NoError(t, err)
+		assert.True(t, disabled)
+	})
+
+	t.Run("Below Threshold", func(t *testing.T) {
+		repo, mock, closeDB := newRepository()
+		defer closeDB()
+
+		mock.ExpectPrepare(`SET failure_count = failure_count \+ 1`).ExpectExec().WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare(`SET active = FALSE, disabled_at = \?`).ExpectExec().WithArgs(sqlmock.AnyArg(), int64(1), 5).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		disabled, err := repo.RecordFailure(context.TODO(), 1, 5)
+
+		assert.NoError(t, err)
+		assert.False(t, disabled)
+	})
+}
+
+func TestEnqueueDelivery(t *testing.T) {
+	t.Run("Ignore Duplicates", func(t *testing.T) {
+		repo, mock, closeDB := newRepository()
+		defer closeDB()
+
+		query := fmt.Sprintf(`INSERT IGNORE INTO %s`, constant.TableWebhookDelivery)
+		mock.ExpectPrepare(query).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.EnqueueDelivery(context.TODO(), models.WebhookDelivery{SubscriptionID: 1, EventID: 1})
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestLeaseDeliveries(t *testing.T) {
+	until := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Lease Success", func(t *testing.T) {
+		repo, mock, closeDB := newRepository()
+		defer closeDB()
+
+		query := fmt.Sprintf(`UPDATE %s SET next_attempt_at = \? WHERE id IN \(\?, \?\)`, constant.TableWebhookDelivery)
+		mock.ExpectPrepare(query).ExpectExec().WithArgs(until, int64(1), int64(2)).WillReturnResult(sqlmock.NewResult(0, 2))
+
+		err := repo.LeaseDeliveries(context.TODO(), []int64{1, 2}, until)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Lease Nothing", func(t *testing.T) {
+		repo, mock, closeDB := newRepository()
+		defer closeDB()
+
+		assert.NoError(t, repo.LeaseDeliveries(context.TODO(), nil, until))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
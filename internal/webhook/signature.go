@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Waizly-Event"
+	HeaderDelivery  = "X-Waizly-Delivery"
+	HeaderTimestamp = "X-Waizly-Timestamp"
+	HeaderSignature = "X-Waizly-Signature"
+
+	signaturePrefix = "sha256="
+)
+
+// Sign returns the value of the signature header for body sent at timestamp.
+// The MAC covers "<unix timestamp>.<body>" so a captured request cannot be
+// replayed with a different timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify is the receiver side of Sign. It rejects timestamps further than
+// tolerance from now.
+func Verify(secret, timestampHeader, signatureHeader string, body []byte, tolerance time.Duration) bool {
+	unix, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	timestamp := time.Unix(unix, 0)
+	if age := time.Since(timestamp); age > tolerance || age < -tolerance {
+		return false
+	}
+
+	if !strings.HasPrefix(signatureHeader, signaturePrefix) {
+		return false
+	}
+
+	expected := Sign(secret, timestamp, body)
+
+	return hmac.Equal([]byte(expected), []byte(signatureHeader))
+}
+
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/response"
+	"waizly/models"
+)
+
+const deliveryListLimit = 100
+
+type (
+	WebhookUseCase interface {
+		CreateSubscription(ctx context.Context, params models.WebhookSubscriptionRequest) response.Response
+		ListSubscriptions(ctx context.Context) response.Response
+		DetailSubscription(ctx context.Context, id int64) response.Response
+		UpdateSubscription(ctx context.Context, id int64, params models.WebhookSubscriptionUpdateRequest) response.Response
+		DeleteSubscription(ctx context.Context, id int64) response.Response
+		ListDeliveries(ctx context.Context, subscriptionID int64) response.Response
+		DetailDelivery(ctx context.Context, id int64) response.Response
+		Redeliver(ctx context.Context, id int64) response.Response
+	}
+
+	webhookUseCaseImpl struct {
+		repository WebhookRepository
+	}
+)
+
+func NewWebhookUseCase(repo WebhookRepository) WebhookUseCase {
+	return &webhookUseCaseImpl{
+		repository: repo,
+	}
+}
+
+// CreateSubscription is the only call that returns the signing secret.
+func (wu *webhookUseCaseImpl) CreateSubscription(ctx context.Context, params models.WebhookSubscriptionRequest) response.Response {
+	var err error
+
+	secret := params.Secret
+	if secret == "" {
+		secret, err = GenerateSecret()
+		if err != nil {
+			return response.Error(response.StatusInternalServerError, exception.ErrInternalServer)
+		}
+	}
+
+	now := time.Now().UTC()
+	subscription := models.WebhookSubscription{
+		URL:       params.URL,
+		EventType: params.EventType,
+		Secret:    secret,
+		Active:    true,
+		CreatedAt: now,
+		UpdateAt:  now,
+	}
+
+	subscription.ID, err = wu.repository.CreateSubscription(ctx, subscription)
+	if err != nil {
+		return response.FromError(err)
+	}
+
+	return response.Success(response.StatusCreated, subscription)
+}
+
+func (wu *webhookUseCaseImpl) ListSubscriptions(ctx context.Context) response.Response {
+	subscriptions, err := wu.repository.ListSubscriptions(ctx)
+	if err != nil {
+		return response.FromError(err)
+	}
+
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	return response.Success(response.StatusOK, subscriptions)
+}
+
+func (wu *webhookUseCaseImpl) DetailSubscription(ctx context.Context, id int64) response.Response {
+	subscription, err := wu.repository.FindSubscription(ctx, id)
+	if err != nil {
+		return response.FromError(err)
+	}
+
+	subscription.Secret = ""
+
+	return response.Success(response.StatusOK, subscription)
+}
+
+// UpdateSubscription applies the fields present in params. Re-activating a
+// subscription clears its failure streak.
+func (wu *webhookUseCaseImpl) UpdateSubscription(ctx context.Context, id int64, params models.WebhookSubscriptionUpdateRequest) response.Response {
+	subscription, err := wu.repository.FindSubscription(ctx, id)
+	if err != nil {
+		return response.FromError(err)
+	}
+
+	if params.URL != nil {
+		subscription.URL = *params.URL
+	}
+
+	if params.EventType != nil {
+		subscription.EventType = *params.EventType
+	}
+
+	if params.Active != nil && *params.Active != subscription.Active {
+		subscription.Active = *params.Active
+		subscription.FailureCount = 0
+		subscription.DisabledAt = nil
+
+		if !subscription.Active {
+			now := time.Now().UTC()
+			subscription.DisabledAt = &now
+		}
+	}
+
+	subscription.UpdateAt = time.Now().UTC()
+
+	err = wu.repository.UpdateSubscription(ctx, id, subscription)
+	if err != nil {
+		return response.FromError(err)
+	}
+
+	subscription.Secret = ""
+
+	return response.Success(response.StatusOK, subscription)
+}
+
+func (wu *webhookUseCaseImpl) DeleteSubscription(ctx context.Context, id int64) response.Response {
+	err := wu.repository.DeleteSubscription(ctx, id)
+	if err != nil {
+		return response.FromError(err)
+	}
+
+	msg := "Success Delete Data"
+
+	return response.Success(response.StatusOK, msg)
+}
+
+func (wu *webhookUseCaseImpl) ListDeliveries(ctx context.Context, subscriptionID int64) response.Response {
+	deliveries, err := wu.repository.ListDeliveries(ctx, subscriptionID, deliveryListLimit)
+	if err != nil {
+		return response.FromError(err)
+	}
+
+	return response.Success(response.StatusOK, deliveries)
+}
+
+func (wu *webhookUseCaseImpl) DetailDelivery(ctx context.Context, id int64) response.Response {
+	delivery, err := wu.repository.FindDelivery(ctx, id)
+	if err != nil {
+		return response.FromError(err)
+	}
+
+	attempts, err := wu.repository.ListAttempts(ctx, id)
+	if err != nil {
+		return response.FromError(err)
+	}
+
+	return response.Success(response.StatusOK, models.WebhookDeliveryDetail{
+		WebhookDelivery: delivery,
+		History:         attempts,
+	})
+}
+
+// Redeliver queues a delivery again with a fresh retry budget, whatever its
+// current status.
+func (wu *webhookUseCaseImpl) Redeliver(ctx context.Context, id int64) response.Response {
+	delivery, err := wu.repository.FindDelivery(ctx, id)
+	if err != nil {
+		return response.FromError(err)
+	}
+
+	now := time.Now().UTC()
+	delivery.Status = DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	delivery.UpdateAt = now
+
+	err = wu.repository.UpdateDelivery(ctx, id, delivery)
+	if err != nil {
+		return response.FromError(err)
+	}
+
+	return response.Success(response.StatusOK, delivery)
+}
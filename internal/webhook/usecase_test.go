@@ -0,0 +1,174 @@
+package webhook_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"waizly/helpers/exception"
+	"waizly/helpers/response"
+	"waizly/internal/webhook"
+	"waizly/internal/webhook/mocks"
+	"waizly/models"
+)
+
+func TestCreateSubscription(t *testing.T) {
+	t.Run("Generate Secret", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(subscription models.WebhookSubscription) bool {
+			return len(subscription.Secret) == 64 && subscription.Active
+		})).Return(int64(1), nil)
+
+		webhookUseCase := webhook.NewWebhookUseCase(webhookRepository)
+
+		resp := webhookUseCase.CreateSubscription(context.TODO(), models.WebhookSubscriptionRequest{
+			URL:       "https://example.com/hook",
+			EventType: "*",
+		})
+
+		assert.NoError(t, resp.Err())
+		subscription := resp.(*response.ResponseImpl).Data.(models.WebhookSubscription)
+		assert.Equal(t, int64(1), subscription.ID)
+		assert.NotEmpty(t, subscription.Secret)
+		webhookRepository.AssertExpectations(t)
+	})
+
+	t.Run("Create Error", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("CreateSubscription", mock.Anything, mock.AnythingOfType("models.WebhookSubscription")).Return(int64(0), exception.ErrInternalServer)
+
+		webhookUseCase := webhook.NewWebhookUseCase(webhookRepository)
+
+		resp := webhookUseCase.CreateSubscription(context.TODO(), models.WebhookSubscriptionRequest{})
+
+		assert.Error(t, resp.Err())
+		webhookRepository.AssertExpectations(t)
+	})
+}
+
+func TestListSubscriptions(t *testing.T) {
+	t.Run("Hide Secrets", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("ListSubscriptions", mock.Anything).Return([]models.WebhookSubscription{{ID: 1, Secret: secret}}, nil)
+
+		webhookUseCase := webhook.NewWebhookUseCase(webhookRepository)
+
+		resp := webhookUseCase.ListSubscriptions(context.TODO())
+
+		assert.NoError(t, resp.Err())
+		subscriptions := resp.(*response.ResponseImpl).Data.([]models.WebhookSubscription)
+		assert.Empty(t, subscriptions[0].Secret)
+	})
+}
+
+func TestUpdateSubscription(t *testing.T) {
+	t.Run("Reactivate Clears Failures", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+		active := true
+
+		webhookRepository.On("FindSubscription", mock.Anything, int64(1)).Return(models.WebhookSubscription{ID: 1, Active: false, FailureCount: 20}, nil)
+		webhookRepository.On("UpdateSubscription", mock.Anything, int64(1), mock.MatchedBy(func(subscription models.WebhookSubscription) bool {
+			return subscription.Active && subscription.FailureCount == 0 && subscription.DisabledAt == nil
+		})).Return(nil)
+
+		webhookUseCase := webhook.NewWebhookUseCase(webhookRepository)
+
+		resp := webhookUseCase.UpdateSubscription(context.TODO(), 1, models.WebhookSubscriptionUpdateRequest{Active: &active})
+
+		assert.NoError(t, resp.Err())
+		webhookRepository.AssertExpectations(t)
+	})
+
+	t.Run("Subscription Not Found", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("FindSubscription", mock.Anything, int64(1)).Return(models.WebhookSubscription{}, exception.Wrap("WebhookRepository.FindSubscription", exception.ErrNotFound, nil))
+
+		webhookUseCase := webhook.NewWebhookUseCase(webhookRepository)
+
+		resp := webhookUseCase.UpdateSubscription(context.TODO(), 1, models.WebhookSubscriptionUpdateRequest{})
+
+		assert.ErrorIs(t, resp.Err(), exception.ErrNotFound)
+		webhookRepository.AssertExpectations(t)
+	})
+
+	t.Run("Database Timeout", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("FindSubscription", mock.Anything, int64(1)).Return(models.WebhookSubscription{}, exception.Wrap("WebhookRepository.FindSubscription", exception.ErrTimeout, nil))
+
+		webhookUseCase := webhook.NewWebhookUseCase(webhookRepository)
+
+		resp := webhookUseCase.UpdateSubscription(context.TODO(), 1, models.WebhookSubscriptionUpdateRequest{})
+
+		assert.Equal(t, response.StatusGatewayTimeout, response.StatusOf(resp))
+	})
+}
+
+func TestDeleteSubscription(t *testing.T) {
+	t.Run("Subscription Not Found", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("DeleteSubscription", mock.Anything, int64(1)).Return(exception.ErrNotFound)
+
+		webhookUseCase := webhook.NewWebhookUseCase(webhookRepository)
+
+		resp := webhookUseCase.DeleteSubscription(context.TODO(), 1)
+
+		assert.ErrorIs(t, resp.Err(), exception.ErrNotFound)
+		webhookRepository.AssertExpectations(t)
+	})
+}
+
+func TestDetailDelivery(t *testing.T) {
+	t.Run("Include Attempts", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("FindDelivery", mock.Anything, int64(3)).Return(models.WebhookDelivery{ID: 3}, nil)
+		webhookRepository.On("ListAttempts", mock.Anything, int64(3)).Return([]models.WebhookAttempt{{ID: 1}, {ID: 2}}, nil)
+
+		webhookUseCase := webhook.NewWebhookUseCase(webhookRepository)
+
+		resp := webhookUseCase.DetailDelivery(context.TODO(), 3)
+
+		assert.NoError(t, resp.Err())
+		detail := resp.(*response.ResponseImpl).Data.(models.WebhookDeliveryDetail)
+		assert.Len(t, detail.History, 2)
+	})
+}
+
+func TestRedeliver(t *testing.T) {
+	t.Run("Requeue Failed Delivery", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("FindDelivery", mock.Anything, int64(3)).Return(models.WebhookDelivery{ID: 3, Status: webhook.DeliveryFailed, Attempts: 8}, nil)
+		webhookRepository.On("UpdateDelivery", mock.Anything, int64(3), mock.MatchedBy(func(delivery models.WebhookDelivery) bool {
+			return delivery.Status == webhook.DeliveryPending && delivery.Attempts == 0
+		})).Return(nil)
+
+		webhookUseCase := webhook.NewWebhookUseCase(webhookRepository)
+
+		resp := webhookUseCase.Redeliver(context.TODO(), 3)
+
+		assert.NoError(t, resp.Err())
+		webhookRepository.AssertExpectations(t)
+	})
+
+	t.Run("Delivery Not Found", func(t *testing.T) {
+		webhookRepository := new(mocks.WebhookRepository)
+
+		webhookRepository.On("FindDelivery", mock.Anything, int64(3)).Return(models.WebhookDelivery{}, exception.ErrNotFound)
+
+		webhookUseCase := webhook.NewWebhookUseCase(webhookRepository)
+
+		resp := webhookUseCase.Redeliver(context.TODO(), 3)
+
+		assert.Error(t, resp.Err())
+		webhookRepository.AssertExpectations(t)
+	})
+}
@@ -9,4 +9,12 @@ type Account struct {
 	Email     string    `json:"email" validate:"email"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdateAt  time.Time `json:"update_at"`
+
+	Role              string     `json:"role"`
+	DisabledAt        *time.Time `json:"disabled_at"`
+	SessionsRevokedAt *time.Time `json:"sessions_revoked_at"`
+
+	// Version starts at 1 and grows with every update. It is the account's
+	// ETag, and writes only apply while it still matches.
+	Version int64 `json:"version"`
 }
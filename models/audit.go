@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type AuditEvent struct {
+	ID         int64     `json:"id"`
+	ActorID    int64     `json:"actor_id"`
+	SubjectID  int64     `json:"subject_id"`
+	Action     string    `json:"action"`
+	Outcome    string    `json:"outcome"`
+	Detail     string    `json:"detail"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	RequestID  string    `json:"request_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+}
+
+type AuditFilter struct {
+	ActorID   int64
+	SubjectID int64
+	Action    string
+	Outcome   string
+	RequestID string
+	From      time.Time
+	To        time.Time
+	AfterID   int64
+	Limit     int
+}
+
+type AuditVerification struct {
+	Valid    bool  `json:"valid"`
+	Checked  int64 `json:"checked"`
+	BrokenAt int64 `json:"broken_at,omitempty"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookSubscription struct {
+	ID           int64      `json:"id"`
+	URL          string     `json:"url"`
+	EventType    string     `json:"event_type"`
+	Secret       string     `json:"secret,omitempty"`
+	Active       bool       `json:"active"`
+	FailureCount int        `json:"failure_count"`
+	DisabledAt   *time.Time `json:"disabled_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdateAt     time.Time  `json:"update_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscription_id"`
+	EventID        int64           `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode int             `json:"last_status_code"`
+	LastError      string          `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdateAt       time.Time       `json:"update_at"`
+}
+
+type WebhookAttempt struct {
+	ID           int64     `json:"id"`
+	DeliveryID   int64     `json:"delivery_id"`
+	StatusCode   int       `json:"status_code"`
+	ResponseBody string    `json:"response_body"`
+	Error        string    `json:"error"`
+	DurationMs   int64     `json:"duration_ms"`
+	AttemptedAt  time.Time `json:"attempted_at"`
+}
+
+type WebhookDeliveryDetail struct {
+	WebhookDelivery
+	History []WebhookAttempt `json:"history"`
+}
+
+type WebhookSubscriptionRequest struct {
+	URL       string `json:"url" validate:"required,url"`
+	EventType string `json:"event_type" validate:"required,oneof=* AccountRegistered AccountUpdated AccountDeleted"`
+	Secret    string `json:"secret" validate:"omitempty,min=16"`
+}
+
+type WebhookSubscriptionUpdateRequest struct {
+	URL       *string `json:"url" validate:"omitempty,url"`
+	EventType *string `json:"event_type" validate:"omitempty,oneof=* AccountRegistered AccountUpdated AccountDeleted"`
+	Active    *bool   `json:"active"`
+}